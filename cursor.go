@@ -0,0 +1,162 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+//
+// The file declares serialization of cursors into opaque tokens
+//
+
+package dynamo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/fogfish/curie"
+	"github.com/fogfish/faults"
+)
+
+const (
+	errInvalidToken = faults.Type("invalid cursor token")
+)
+
+// version of token layout
+const (
+	tokenV1 = byte(1)
+)
+
+// flags of token
+const (
+	tokenSigned = byte(1 << iota)
+)
+
+// TokenOption configures encoding of cursor tokens
+type TokenOption func(*tokenOptions)
+
+type tokenOptions struct {
+	secret []byte
+}
+
+// WithHMAC signs tokens with HMAC-SHA256 using the secret key. Decoder
+// rejects tokens that are not signed or signed with other key.
+func WithHMAC(secret []byte) TokenOption {
+	return func(opts *tokenOptions) {
+		opts.secret = secret
+	}
+}
+
+// position is a cursor decoded from token
+type position struct {
+	Hash  curie.IRI         `json:"h,omitempty"`
+	Sort  curie.IRI         `json:"s,omitempty"`
+	Attrs map[string]string `json:"a,omitempty"`
+}
+
+func (p *position) HashKey() curie.IRI            { return p.Hash }
+func (p *position) SortKey() curie.IRI            { return p.Sort }
+func (p *position) Attributes() map[string]string { return p.Attrs }
+
+// EncodeCursor serializes cursor, returned by Match, into opaque URL-safe
+// token. The token is empty if cursor is nil (end of sequence).
+func EncodeCursor[T Thing](cursor interface{ MatcherOpt(T) }, opts ...TokenOption) (string, error) {
+	if cursor == nil {
+		return "", nil
+	}
+
+	thing, ok := cursor.(Thing)
+	if !ok {
+		return "", errInvalidToken.New(errors.New("option is not a cursor"))
+	}
+
+	pos := position{Hash: thing.HashKey(), Sort: thing.SortKey()}
+	if attrs, ok := cursor.(interface{ Attributes() map[string]string }); ok {
+		pos.Attrs = attrs.Attributes()
+	}
+
+	payload, err := json.Marshal(pos)
+	if err != nil {
+		return "", errInvalidToken.New(err)
+	}
+
+	conf := tokenOptions{}
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	token := make([]byte, 0, 2+len(payload)+sha256.Size)
+	if conf.secret == nil {
+		token = append(token, tokenV1, 0)
+		token = append(token, payload...)
+	} else {
+		token = append(token, tokenV1, tokenSigned)
+		token = append(token, payload...)
+		token = append(token, signature(conf.secret, token)...)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// DecodeCursor deserializes the token, produced by EncodeCursor, into cursor.
+// The cursor is nil if token is empty.
+func DecodeCursor[T Thing](token string, opts ...TokenOption) (interface{ MatcherOpt(T) }, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	conf := tokenOptions{}
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalidToken.New(err)
+	}
+
+	if len(raw) < 2 || raw[0] != tokenV1 {
+		return nil, errInvalidToken.New(errors.New("unsupported version"))
+	}
+
+	payload := raw[2:]
+	signed := raw[1]&tokenSigned != 0
+
+	switch {
+	case conf.secret != nil && !signed:
+		return nil, errInvalidToken.New(errors.New("token is not signed"))
+	case conf.secret == nil && signed:
+		return nil, errInvalidToken.New(errors.New("secret key is required"))
+	case signed:
+		if len(payload) < sha256.Size {
+			return nil, errInvalidToken.New(errors.New("malformed signature"))
+		}
+		at := len(raw) - sha256.Size
+		if !hmac.Equal(raw[at:], signature(conf.secret, raw[:at])) {
+			return nil, errInvalidToken.New(errors.New("signature mismatch"))
+		}
+		payload = raw[2:at]
+	}
+
+	var pos position
+	if err := json.Unmarshal(payload, &pos); err != nil {
+		return nil, errInvalidToken.New(err)
+	}
+
+	if pos.Hash == "" {
+		return nil, errInvalidToken.New(errors.New("hash key is empty"))
+	}
+
+	return Cursor[T](&pos), nil
+}
+
+func signature(secret, data []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,103 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package dynamo_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/fogfish/curie"
+	"github.com/fogfish/dynamo/v3"
+	"github.com/fogfish/it"
+)
+
+type thing struct{ hashKey, sortKey curie.IRI }
+
+func (t thing) HashKey() curie.IRI { return t.hashKey }
+func (t thing) SortKey() curie.IRI { return t.sortKey }
+
+type position struct {
+	thing
+	attrs map[string]string
+}
+
+func (p position) Attributes() map[string]string { return p.attrs }
+
+func TestCursorToken(t *testing.T) {
+	cursor := dynamo.Cursor[thing](position{
+		thing: thing{"a", "b"},
+		attrs: map[string]string{"year": "N:2020"},
+	})
+
+	t.Run("Plain", func(t *testing.T) {
+		token, err := dynamo.EncodeCursor[thing](cursor)
+		it.Ok(t).If(err).Should().Equal(nil)
+
+		val, err := dynamo.DecodeCursor[thing](token)
+		it.Ok(t).If(err).Should().Equal(nil)
+
+		key := val.(dynamo.Thing)
+		attrs := val.(interface{ Attributes() map[string]string })
+		it.Ok(t).
+			If(key.HashKey()).Should().Equal(curie.IRI("a")).
+			If(key.SortKey()).Should().Equal(curie.IRI("b")).
+			If(attrs.Attributes()).Should().Equal(map[string]string{"year": "N:2020"})
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		token, err := dynamo.EncodeCursor[thing](nil)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(token).Should().Equal("")
+
+		val, err := dynamo.DecodeCursor[thing]("")
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(nil)
+	})
+
+	t.Run("HMAC", func(t *testing.T) {
+		token, err := dynamo.EncodeCursor[thing](cursor, dynamo.WithHMAC([]byte("secret")))
+		it.Ok(t).If(err).Should().Equal(nil)
+
+		val, err := dynamo.DecodeCursor[thing](token, dynamo.WithHMAC([]byte("secret")))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val.(dynamo.Thing).HashKey()).Should().Equal(curie.IRI("a"))
+
+		_, err = dynamo.DecodeCursor[thing](token, dynamo.WithHMAC([]byte("other")))
+		it.Ok(t).If(err).ShouldNot().Equal(nil)
+
+		_, err = dynamo.DecodeCursor[thing](token)
+		it.Ok(t).If(err).ShouldNot().Equal(nil)
+	})
+
+	t.Run("Unsigned", func(t *testing.T) {
+		token, _ := dynamo.EncodeCursor[thing](cursor)
+
+		_, err := dynamo.DecodeCursor[thing](token, dynamo.WithHMAC([]byte("secret")))
+		it.Ok(t).If(err).ShouldNot().Equal(nil)
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		token, _ := dynamo.EncodeCursor[thing](cursor, dynamo.WithHMAC([]byte("secret")))
+		raw, _ := base64.RawURLEncoding.DecodeString(token)
+		raw[5] ^= 0xff
+
+		_, err := dynamo.DecodeCursor[thing](base64.RawURLEncoding.EncodeToString(raw), dynamo.WithHMAC([]byte("secret")))
+		it.Ok(t).If(err).ShouldNot().Equal(nil)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		for _, token := range []string{"!", "AA", base64.RawURLEncoding.EncodeToString([]byte{2, 0, '{', '}'})} {
+			_, err := dynamo.DecodeCursor[thing](token)
+			it.Ok(t).If(err).ShouldNot().Equal(nil)
+		}
+	})
+}
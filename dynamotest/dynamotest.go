@@ -0,0 +1,455 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+/*
+Package dynamotest declares conformance test suite for implementations of
+dynamo.KeyVal interface. Any storage, or a wrapper around storage (e.g.
+caching, tracing), is expected to pass the suite.
+
+The suite is generic over type T, the application supplies the factory of
+empty storage and the fixture that builds instances of T.
+
+	func TestKeyVal(t *testing.T) {
+		dynamotest.TestKeyVal(t,
+			func(t *testing.T) dynamo.KeyVal[Person] { return mem.New[Person]() },
+			func(hashKey, sortKey curie.IRI, seq int) Person {
+				return Person{
+					Org:  hashKey,
+					ID:   sortKey,
+					Name: fmt.Sprintf("Name %d", seq),
+					Age:  seq,
+				}
+			},
+		)
+	}
+*/
+package dynamotest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fogfish/curie"
+	"github.com/fogfish/dynamo/v3"
+	"github.com/fogfish/it"
+)
+
+// Factory creates an empty instance of the storage
+type Factory[T dynamo.Thing] func(*testing.T) dynamo.KeyVal[T]
+
+// Fixture creates an instance of T with given keys. The seq parameter
+// distinguishes the content, instances built with different seq MUST have
+// different values. All attributes of the instance MUST be defined (non-zero),
+// so that the update of the entity replaces each of them.
+type Fixture[T dynamo.Thing] func(hashKey, sortKey curie.IRI, seq int) T
+
+// key is a generic implementation of dynamo.Thing used by MatchKey
+type key struct{ hashKey, sortKey curie.IRI }
+
+func (k key) HashKey() curie.IRI { return k.hashKey }
+func (k key) SortKey() curie.IRI { return k.sortKey }
+
+const (
+	hashKey  = curie.IRI("dynamotest")
+	otherKey = curie.IRI("other")
+)
+
+// sort keys used by the test suite, the order is lexicographical
+var sortKeys = []curie.IRI{"a1", "a2", "a3", "b1", "b2"}
+
+// TestKeyVal runs complete test suite except conditional expressions,
+// see TestConditions
+func TestKeyVal[T dynamo.Thing](t *testing.T, factory Factory[T], fixture Fixture[T]) {
+	t.Helper()
+
+	TestGet(t, factory, fixture)
+	TestPut(t, factory, fixture)
+	TestRemove(t, factory, fixture)
+	TestUpdate(t, factory, fixture)
+	TestMatch(t, factory, fixture)
+}
+
+// TestGet validates Get
+func TestGet[T dynamo.Thing](t *testing.T, factory Factory[T], fixture Fixture[T]) {
+	t.Helper()
+
+	t.Run("GetSuccess", func(t *testing.T) {
+		db := factory(t)
+		expect := fixture(hashKey, "a1", 1)
+		it.Ok(t).If(db.Put(context.Background(), expect)).Should().Equal(nil)
+
+		val, err := db.Get(context.Background(), fixture(hashKey, "a1", 0))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(expect)
+	})
+
+	t.Run("GetNotFound", func(t *testing.T) {
+		db := factory(t)
+
+		val, err := db.Get(context.Background(), fixture(hashKey, "a1", 0))
+		it.Ok(t).
+			If(isNotFound(err)).Should().Equal(true).
+			If(val).Should().Equal(*new(T))
+	})
+}
+
+// TestPut validates Put
+func TestPut[T dynamo.Thing](t *testing.T, factory Factory[T], fixture Fixture[T]) {
+	t.Helper()
+
+	t.Run("PutCreate", func(t *testing.T) {
+		db := factory(t)
+		expect := fixture(hashKey, "a1", 1)
+
+		err := db.Put(context.Background(), expect)
+		it.Ok(t).If(err).Should().Equal(nil)
+
+		val, err := db.Get(context.Background(), expect)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(expect)
+	})
+
+	t.Run("PutReplace", func(t *testing.T) {
+		db := factory(t)
+		expect := fixture(hashKey, "a1", 2)
+
+		it.Ok(t).
+			If(db.Put(context.Background(), fixture(hashKey, "a1", 1))).Should().Equal(nil).
+			If(db.Put(context.Background(), expect)).Should().Equal(nil)
+
+		val, err := db.Get(context.Background(), expect)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(expect)
+	})
+}
+
+// TestRemove validates Remove
+func TestRemove[T dynamo.Thing](t *testing.T, factory Factory[T], fixture Fixture[T]) {
+	t.Helper()
+
+	t.Run("RemoveSuccess", func(t *testing.T) {
+		db := factory(t)
+		expect := fixture(hashKey, "a1", 1)
+		it.Ok(t).If(db.Put(context.Background(), expect)).Should().Equal(nil)
+
+		val, err := db.Remove(context.Background(), fixture(hashKey, "a1", 0))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(expect)
+
+		_, err = db.Get(context.Background(), expect)
+		it.Ok(t).If(isNotFound(err)).Should().Equal(true)
+	})
+
+	// The error of removing absent entity is storage specific, the suite only
+	// requires that nothing is returned or created.
+	t.Run("RemoveNotFound", func(t *testing.T) {
+		db := factory(t)
+
+		val, _ := db.Remove(context.Background(), fixture(hashKey, "a1", 0))
+		it.Ok(t).If(val).Should().Equal(*new(T))
+
+		_, err := db.Get(context.Background(), fixture(hashKey, "a1", 0))
+		it.Ok(t).If(isNotFound(err)).Should().Equal(true)
+	})
+}
+
+// TestUpdate validates Update
+func TestUpdate[T dynamo.Thing](t *testing.T, factory Factory[T], fixture Fixture[T]) {
+	t.Helper()
+
+	t.Run("UpdateCreate", func(t *testing.T) {
+		db := factory(t)
+		expect := fixture(hashKey, "a1", 1)
+
+		val, err := db.Update(context.Background(), expect)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(expect)
+	})
+
+	t.Run("UpdateReplace", func(t *testing.T) {
+		db := factory(t)
+		expect := fixture(hashKey, "a1", 2)
+		it.Ok(t).If(db.Put(context.Background(), fixture(hashKey, "a1", 1))).Should().Equal(nil)
+
+		val, err := db.Update(context.Background(), expect)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(expect)
+
+		val, err = db.Get(context.Background(), expect)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(expect)
+	})
+}
+
+// TestMatch validates Match, MatchKey, pagination and iterator
+func TestMatch[T dynamo.Thing](t *testing.T, factory Factory[T], fixture Fixture[T]) {
+	t.Helper()
+
+	setup := func(t *testing.T) dynamo.KeyVal[T] {
+		db := factory(t)
+		for i, sortKey := range sortKeys {
+			it.Ok(t).
+				If(db.Put(context.Background(), fixture(hashKey, sortKey, i+1))).Should().Equal(nil).
+				If(db.Put(context.Background(), fixture(otherKey, sortKey, i+1))).Should().Equal(nil)
+		}
+		return db
+	}
+
+	expect := func(seq ...int) []T {
+		vals := make([]T, 0, len(seq))
+		for _, i := range seq {
+			vals = append(vals, fixture(hashKey, sortKeys[i-1], i))
+		}
+		return vals
+	}
+
+	t.Run("MatchNone", func(t *testing.T) {
+		db := factory(t)
+
+		seq, cur, err := db.Match(context.Background(), fixture(hashKey, "", 0))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(len(seq)).Should().Equal(0).
+			If(cur).Should().Equal(nil)
+	})
+
+	t.Run("MatchHashKey", func(t *testing.T) {
+		db := setup(t)
+
+		seq, cur, err := db.Match(context.Background(), fixture(hashKey, "", 0))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(seq).Should().Equal(expect(1, 2, 3, 4, 5)).
+			If(cur).Should().Equal(nil)
+	})
+
+	t.Run("MatchSortKeyPrefix", func(t *testing.T) {
+		db := setup(t)
+
+		seq, _, err := db.Match(context.Background(), fixture(hashKey, "a", 0))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(seq).Should().Equal(expect(1, 2, 3))
+	})
+
+	t.Run("MatchKey", func(t *testing.T) {
+		db := setup(t)
+
+		seq, _, err := db.MatchKey(context.Background(), key{hashKey: hashKey, sortKey: "b"})
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(seq).Should().Equal(expect(4, 5))
+	})
+
+	t.Run("MatchDescending", func(t *testing.T) {
+		db := setup(t)
+
+		seq, _, err := db.Match(context.Background(), fixture(hashKey, "", 0), dynamo.Descending[T]())
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(seq).Should().Equal(expect(5, 4, 3, 2, 1))
+	})
+
+	t.Run("MatchWithCursor", func(t *testing.T) {
+		db := setup(t)
+
+		seq := paginate(t, db, fixture(hashKey, "", 0), 2)
+		it.Ok(t).If(seq).Should().Equal(expect(1, 2, 3, 4, 5))
+	})
+
+	t.Run("MatchWithCursorDescending", func(t *testing.T) {
+		db := setup(t)
+
+		seq := paginate(t, db, fixture(hashKey, "", 0), 2, dynamo.Descending[T]())
+		it.Ok(t).If(seq).Should().Equal(expect(5, 4, 3, 2, 1))
+	})
+
+	t.Run("Iterate", func(t *testing.T) {
+		db := setup(t)
+
+		seq, err := collect(dynamo.Iterate[T](context.Background(), db, fixture(hashKey, "", 0), dynamo.Limit[T](2)))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(seq).Should().Equal(expect(1, 2, 3, 4, 5))
+	})
+
+	t.Run("IterateMaxItems", func(t *testing.T) {
+		db := setup(t)
+
+		seq, err := collect(dynamo.Iterate[T](context.Background(), db, fixture(hashKey, "", 0),
+			dynamo.Limit[T](2),
+			dynamo.MaxItems[T](3),
+			dynamo.Descending[T](),
+		))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(seq).Should().Equal(expect(5, 4, 3))
+	})
+
+	t.Run("IterateCancel", func(t *testing.T) {
+		db := setup(t)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		n := 0
+		var err error
+		dynamo.Iterate[T](ctx, db, fixture(hashKey, "", 0), dynamo.Limit[T](2))(
+			func(_ T, e error) bool {
+				if e != nil {
+					err = e
+					return false
+				}
+				n++
+				cancel()
+				return true
+			},
+		)
+		it.Ok(t).
+			If(n).Should().Equal(1).
+			If(err).Should().Equal(context.Canceled)
+	})
+}
+
+// collect reads all elements of the sequence
+func collect[T dynamo.Thing](seq dynamo.Seq[T]) ([]T, error) {
+	vals := make([]T, 0)
+	var err error
+	seq(func(val T, e error) bool {
+		if e != nil {
+			err = e
+			return false
+		}
+		vals = append(vals, val)
+		return true
+	})
+	return vals, err
+}
+
+// paginate reads all pages using cursor. Storages might return an empty
+// last page, the function tolerates it.
+func paginate[T dynamo.Thing](t *testing.T, db dynamo.KeyVal[T], key T, limit int32, opts ...interface{ MatcherOpt(T) }) []T {
+	t.Helper()
+
+	seq := make([]T, 0)
+	opts = append(opts, dynamo.Limit[T](limit))
+
+	req := opts
+	for i := 0; i < 100; i++ {
+		page, cur, err := db.Match(context.Background(), key, req...)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(len(page) <= int(limit)).Should().Equal(true)
+
+		seq = append(seq, page...)
+		if cur == nil {
+			return seq
+		}
+
+		it.Ok(t).If(len(page)).ShouldNot().Equal(0)
+		req = append(append([]interface{ MatcherOpt(T) }{}, opts...), cur)
+	}
+
+	t.Fatal("pagination does not terminate")
+	return nil
+}
+
+// TestConditions validates conditional writes. The conditions exists and
+// notExists are defined over an attribute of T, which is always set by fixture
+// (e.g. ddb.ClauseFor[Person, string]("Name").Exists()).
+func TestConditions[T dynamo.Thing](
+	t *testing.T,
+	factory Factory[T],
+	fixture Fixture[T],
+	exists, notExists interface{ WriterOpt(T) },
+) {
+	t.Helper()
+
+	t.Run("PutNotExists", func(t *testing.T) {
+		db := factory(t)
+		expect := fixture(hashKey, "a1", 1)
+
+		err := db.Put(context.Background(), expect, notExists)
+		it.Ok(t).If(err).Should().Equal(nil)
+
+		err = db.Put(context.Background(), fixture(hashKey, "a1", 2), notExists)
+		it.Ok(t).If(isPreConditionFailed(err)).Should().Equal(true)
+
+		val, err := db.Get(context.Background(), expect)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(expect)
+	})
+
+	t.Run("PutExists", func(t *testing.T) {
+		db := factory(t)
+		expect := fixture(hashKey, "a1", 2)
+
+		err := db.Put(context.Background(), fixture(hashKey, "a1", 1), exists)
+		it.Ok(t).If(isPreConditionFailed(err)).Should().Equal(true)
+
+		_, err = db.Get(context.Background(), expect)
+		it.Ok(t).If(isNotFound(err)).Should().Equal(true)
+
+		it.Ok(t).
+			If(db.Put(context.Background(), fixture(hashKey, "a1", 1))).Should().Equal(nil).
+			If(db.Put(context.Background(), expect, exists)).Should().Equal(nil)
+	})
+
+	t.Run("UpdateExists", func(t *testing.T) {
+		db := factory(t)
+		expect := fixture(hashKey, "a1", 2)
+
+		_, err := db.Update(context.Background(), expect, exists)
+		it.Ok(t).If(isPreConditionFailed(err)).Should().Equal(true)
+
+		it.Ok(t).If(db.Put(context.Background(), fixture(hashKey, "a1", 1))).Should().Equal(nil)
+
+		val, err := db.Update(context.Background(), expect, exists)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(expect)
+	})
+
+	t.Run("UpdateNotExists", func(t *testing.T) {
+		db := factory(t)
+		it.Ok(t).If(db.Put(context.Background(), fixture(hashKey, "a1", 1))).Should().Equal(nil)
+
+		_, err := db.Update(context.Background(), fixture(hashKey, "a1", 2), notExists)
+		it.Ok(t).If(isPreConditionFailed(err)).Should().Equal(true)
+	})
+
+	t.Run("RemoveExists", func(t *testing.T) {
+		db := factory(t)
+		expect := fixture(hashKey, "a1", 1)
+		it.Ok(t).If(db.Put(context.Background(), expect)).Should().Equal(nil)
+
+		_, err := db.Remove(context.Background(), expect, notExists)
+		it.Ok(t).If(isPreConditionFailed(err)).Should().Equal(true)
+
+		val, err := db.Remove(context.Background(), expect, exists)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(expect)
+	})
+}
+
+func isNotFound(err error) bool {
+	_, ok := err.(interface{ NotFound() string })
+	return ok
+}
+
+func isPreConditionFailed(err error) bool {
+	e, ok := err.(interface{ PreConditionFailed() bool })
+	return ok && e.PreConditionFailed()
+}
@@ -0,0 +1,80 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package dynamotest_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fogfish/curie"
+	"github.com/fogfish/dynamo/v3"
+	"github.com/fogfish/dynamo/v3/dynamotest"
+	"github.com/fogfish/dynamo/v3/service/ddb"
+	"github.com/fogfish/dynamo/v3/service/ddb/ddbfake"
+	"github.com/fogfish/dynamo/v3/service/mem"
+	"github.com/fogfish/dynamo/v3/service/s3"
+	"github.com/fogfish/dynamo/v3/service/s3/s3fake"
+)
+
+type person struct {
+	Org  curie.IRI `dynamodbav:"prefix,omitempty" json:"prefix,omitempty"`
+	ID   curie.IRI `dynamodbav:"suffix,omitempty" json:"suffix,omitempty"`
+	Name string    `dynamodbav:"name,omitempty" json:"name,omitempty"`
+	Age  int       `dynamodbav:"age,omitempty" json:"age,omitempty"`
+}
+
+func (p person) HashKey() curie.IRI { return p.Org }
+func (p person) SortKey() curie.IRI { return p.ID }
+
+func fixture(hashKey, sortKey curie.IRI, seq int) person {
+	return person{
+		Org:  hashKey,
+		ID:   sortKey,
+		Name: fmt.Sprintf("Name %d", seq),
+		Age:  seq,
+	}
+}
+
+func TestMem(t *testing.T) {
+	factory := func(t *testing.T) dynamo.KeyVal[person] { return mem.New[person]() }
+	name := ddb.ClauseFor[person, string]("Name")
+
+	dynamotest.TestKeyVal(t, factory, fixture)
+	dynamotest.TestConditions(t, factory, fixture, name.Exists(), name.NotExists())
+}
+
+func TestDDB(t *testing.T) {
+	factory := func(t *testing.T) dynamo.KeyVal[person] {
+		return ddb.Must(
+			ddb.New[person](
+				ddb.WithTable("test"),
+				ddb.WithService(ddbfake.New(ddbfake.WithTable("test", "prefix", "suffix"))),
+			),
+		)
+	}
+	name := ddb.ClauseFor[person, string]("Name")
+
+	dynamotest.TestKeyVal(t, factory, fixture)
+	dynamotest.TestConditions(t, factory, fixture, name.Exists(), name.NotExists())
+}
+
+func TestS3(t *testing.T) {
+	factory := func(t *testing.T) dynamo.KeyVal[person] {
+		return s3.Must(
+			s3.New[person](
+				s3.WithBucket("test"),
+				s3.WithService(s3fake.New("test")),
+			),
+		)
+	}
+	name := ddb.ClauseFor[person, string]("Name")
+
+	dynamotest.TestKeyVal(t, factory, fixture)
+	dynamotest.TestConditions(t, factory, fixture, name.Exists(), name.NotExists())
+}
@@ -36,6 +36,7 @@ func main() {
 		ddb.WithGlobalSecondaryIndex("example-dynamo-relational-category-year"),
 		ddb.WithHashKey("category"),
 		ddb.WithSortKey("year"),
+		ddb.WithPrimaryKey("prefix", "suffix"),
 	))
 
 	//
@@ -167,11 +168,22 @@ func fetchArticleKeywords(db dynamo.KeyVal[Keyword]) error {
 func lookupArticlesByCategory(db dynamo.KeyVal[Category], category string) error {
 	log.Printf("==> lookup articles by category: %s\n", category)
 
-	seq, _, err := db.Match(context.Background(),
+	// the index is read page by page, the cursor keeps keys of index and table
+	seq := make([]Category, 0)
+	var err error
+	dynamo.Iterate[Category](context.Background(), db,
 		Category{
 			Category: category,
 		},
-	)
+		dynamo.Limit[Category](2),
+	)(func(val Category, e error) bool {
+		if e != nil {
+			err = e
+			return false
+		}
+		seq = append(seq, val)
+		return true
+	})
 
 	if err != nil {
 		return err
@@ -8,6 +8,7 @@ require (
 	github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue v1.13.2
 	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.29.0
 	github.com/aws/aws-sdk-go-v2/service/s3 v1.50.1
+	github.com/aws/smithy-go v1.20.0
 	github.com/fogfish/curie v1.8.2
 	github.com/fogfish/faults v0.2.0
 	github.com/fogfish/golem/hseq v1.1.2
@@ -32,6 +33,5 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/sso v1.19.0 // indirect
 	github.com/aws/aws-sdk-go-v2/service/ssooidc v1.22.0 // indirect
 	github.com/aws/aws-sdk-go-v2/service/sts v1.27.0 // indirect
-	github.com/aws/smithy-go v1.20.0 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
 )
@@ -0,0 +1,338 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+//
+// The file implements condition expressions
+//
+//	https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/Expressions.OperatorsAndFunctions.html
+//
+
+package ddbexpr
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Condition is evaluated expression, it is used by ConditionExpression,
+// FilterExpression and KeyConditionExpression
+type Condition func(Item) bool
+
+// Condition parses condition expression
+func (exprs *Expressions) Condition(expr string) (Condition, error) {
+	p, err := exprs.parser(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	cond, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.eof(); err != nil {
+		return nil, err
+	}
+
+	return cond, nil
+}
+
+// Or ::= And (OR And)*
+func (p *parser) or() (Condition, error) {
+	a, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("OR") {
+		b, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+
+		x := a
+		a = func(item Item) bool { return x(item) || b(item) }
+	}
+
+	return a, nil
+}
+
+// And ::= Not (AND Not)*
+func (p *parser) and() (Condition, error) {
+	a, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("AND") {
+		b, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+
+		x := a
+		a = func(item Item) bool { return x(item) && b(item) }
+	}
+
+	return a, nil
+}
+
+// Not ::= NOT Not | Primary
+func (p *parser) not() (Condition, error) {
+	if p.accept("NOT") {
+		a, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return func(item Item) bool { return !a(item) }, nil
+	}
+
+	return p.primary()
+}
+
+// Primary ::= ( Or ) | function | operand comparator operand
+//
+//	| operand BETWEEN operand AND operand
+//	| operand IN ( operand (, operand)* )
+func (p *parser) primary() (Condition, error) {
+	if p.accept("(") {
+		cond, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return cond, nil
+	}
+
+	if t := p.peek(); t.kind == tIdent && p.seq[p.at+1].is("(") && !t.is("size") {
+		return p.function()
+	}
+
+	a, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := p.next(); {
+	case t.is("=") || t.is("<>") || t.is("<") || t.is("<=") || t.is(">") || t.is(">="):
+		b, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return comparator(t.text, a, b), nil
+	case t.is("BETWEEN"):
+		lo, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("AND"); err != nil {
+			return nil, err
+		}
+		hi, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return func(item Item) bool {
+			x, okx := a.eval(item)
+			l, okl := lo.eval(item)
+			h, okh := hi.eval(item)
+			if !okx || !okl || !okh {
+				return false
+			}
+			cl, okl := Compare(x, l)
+			ch, okh := Compare(x, h)
+			return okl && okh && cl >= 0 && ch <= 0
+		}, nil
+	case t.is("IN"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		seq := make([]operand, 0)
+		for {
+			b, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, b)
+			if !p.accept(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return func(item Item) bool {
+			x, ok := a.eval(item)
+			if !ok {
+				return false
+			}
+			for _, b := range seq {
+				if y, ok := b.eval(item); ok && Equal(x, y) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	default:
+		return nil, fmt.Errorf("expected comparator, found %s", t)
+	}
+}
+
+func comparator(op string, a, b operand) Condition {
+	return func(item Item) bool {
+		x, okx := a.eval(item)
+		y, oky := b.eval(item)
+		if !okx || !oky {
+			// comparison with undefined attribute
+			return op == "<>" && (okx || oky)
+		}
+
+		switch op {
+		case "=":
+			return Equal(x, y)
+		case "<>":
+			return !Equal(x, y)
+		}
+
+		cmp, ok := Compare(x, y)
+		if !ok {
+			return false
+		}
+
+		switch op {
+		case "<":
+			return cmp < 0
+		case "<=":
+			return cmp <= 0
+		case ">":
+			return cmp > 0
+		case ">=":
+			return cmp >= 0
+		}
+		return false
+	}
+}
+
+// function ::= name ( path (, operand)? )
+func (p *parser) function() (Condition, error) {
+	fun := strings.ToLower(p.next().text)
+	p.next()
+
+	path, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+
+	var arg operand
+	if fun != "attribute_exists" && fun != "attribute_not_exists" {
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		if arg, err = p.operand(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	switch fun {
+	case "attribute_exists":
+		return func(item Item) bool {
+			_, has := path.Get(item)
+			return has
+		}, nil
+	case "attribute_not_exists":
+		return func(item Item) bool {
+			_, has := path.Get(item)
+			return !has
+		}, nil
+	case "attribute_type":
+		return func(item Item) bool {
+			x, okx := path.Get(item)
+			y, oky := arg.eval(item)
+			if !okx || !oky {
+				return false
+			}
+			t, ok := y.(*types.AttributeValueMemberS)
+			return ok && TypeOf(x) == t.Value
+		}, nil
+	case "begins_with":
+		return func(item Item) bool {
+			x, okx := path.Get(item)
+			y, oky := arg.eval(item)
+			if !okx || !oky {
+				return false
+			}
+			return beginsWith(x, y)
+		}, nil
+	case "contains":
+		return func(item Item) bool {
+			x, okx := path.Get(item)
+			y, oky := arg.eval(item)
+			if !okx || !oky {
+				return false
+			}
+			return contains(x, y)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown function %s", fun)
+	}
+}
+
+func beginsWith(x, y types.AttributeValue) bool {
+	switch a := x.(type) {
+	case *types.AttributeValueMemberS:
+		b, ok := y.(*types.AttributeValueMemberS)
+		return ok && strings.HasPrefix(a.Value, b.Value)
+	case *types.AttributeValueMemberB:
+		b, ok := y.(*types.AttributeValueMemberB)
+		return ok && bytes.HasPrefix(a.Value, b.Value)
+	}
+	return false
+}
+
+func contains(x, y types.AttributeValue) bool {
+	switch a := x.(type) {
+	case *types.AttributeValueMemberS:
+		b, ok := y.(*types.AttributeValueMemberS)
+		return ok && strings.Contains(a.Value, b.Value)
+	case *types.AttributeValueMemberB:
+		b, ok := y.(*types.AttributeValueMemberB)
+		return ok && bytes.Contains(a.Value, b.Value)
+	case *types.AttributeValueMemberSS:
+		b, ok := y.(*types.AttributeValueMemberS)
+		return ok && indexOf(len(a.Value), func(i int) bool { return a.Value[i] == b.Value }) != -1
+	case *types.AttributeValueMemberNS:
+		b, ok := y.(*types.AttributeValueMemberN)
+		return ok && indexOf(len(a.Value), func(i int) bool {
+			return Equal(&types.AttributeValueMemberN{Value: a.Value[i]}, b)
+		}) != -1
+	case *types.AttributeValueMemberBS:
+		b, ok := y.(*types.AttributeValueMemberB)
+		return ok && indexOf(len(a.Value), func(i int) bool { return bytes.Equal(a.Value[i], b.Value) }) != -1
+	case *types.AttributeValueMemberL:
+		return indexOf(len(a.Value), func(i int) bool { return Equal(a.Value[i], y) }) != -1
+	}
+	return false
+}
+
+func indexOf(n int, f func(int) bool) int {
+	for i := 0; i < n; i++ {
+		if f(i) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,322 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+//
+// The package implements parser and evaluator of DynamoDB expressions
+//
+//	https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/Expressions.html
+//
+// It is used by in-process storages that emulates DynamoDB semantic.
+//
+
+package ddbexpr
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Item is DynamoDB item
+type Item = map[string]types.AttributeValue
+
+// Expressions of single request, they share attribute names and values
+type Expressions struct {
+	names      map[string]string
+	values     map[string]types.AttributeValue
+	usedNames  map[string]bool
+	usedValues map[string]bool
+}
+
+// New expressions context from ExpressionAttributeNames and ExpressionAttributeValues
+func New(names map[string]string, values map[string]types.AttributeValue) *Expressions {
+	return &Expressions{
+		names:      names,
+		values:     values,
+		usedNames:  map[string]bool{},
+		usedValues: map[string]bool{},
+	}
+}
+
+// Unused returns error if some of attribute names or values are not used by expressions
+func (exprs *Expressions) Unused() error {
+	seq := make([]string, 0)
+	for k := range exprs.names {
+		if !exprs.usedNames[k] {
+			seq = append(seq, k)
+		}
+	}
+	for k := range exprs.values {
+		if !exprs.usedValues[k] {
+			seq = append(seq, k)
+		}
+	}
+
+	if len(seq) == 0 {
+		return nil
+	}
+
+	sort.Strings(seq)
+	return fmt.Errorf("unused placeholders: %s", strings.Join(seq, ", "))
+}
+
+//------------------------------------------------------------------------------
+//
+// Paths
+//
+//------------------------------------------------------------------------------
+
+// Elem of document path, either name of attribute or index of list
+type Elem struct {
+	Name  string
+	Index int
+}
+
+// Path to attribute within the item (e.g. a.b[1].c)
+type Path []Elem
+
+func (path Path) String() string {
+	sb := strings.Builder{}
+	for i, e := range path {
+		switch {
+		case e.Name == "":
+			sb.WriteString("[" + strconv.Itoa(e.Index) + "]")
+		case i == 0:
+			sb.WriteString(e.Name)
+		default:
+			sb.WriteString("." + e.Name)
+		}
+	}
+	return sb.String()
+}
+
+// Get value of attribute
+func (path Path) Get(item Item) (types.AttributeValue, bool) {
+	var val types.AttributeValue = &types.AttributeValueMemberM{Value: item}
+
+	for _, e := range path {
+		switch v := val.(type) {
+		case *types.AttributeValueMemberM:
+			if e.Name == "" {
+				return nil, false
+			}
+			x, has := v.Value[e.Name]
+			if !has {
+				return nil, false
+			}
+			val = x
+		case *types.AttributeValueMemberL:
+			if e.Name != "" || e.Index >= len(v.Value) {
+				return nil, false
+			}
+			val = v.Value[e.Index]
+		default:
+			return nil, false
+		}
+	}
+
+	return val, true
+}
+
+//------------------------------------------------------------------------------
+//
+// Operands
+//
+//------------------------------------------------------------------------------
+
+// operand of expression
+type operand interface {
+	eval(Item) (types.AttributeValue, bool)
+}
+
+type pathOperand struct{ path Path }
+
+func (op pathOperand) eval(item Item) (types.AttributeValue, bool) { return op.path.Get(item) }
+
+type valueOperand struct{ val types.AttributeValue }
+
+func (op valueOperand) eval(Item) (types.AttributeValue, bool) { return op.val, true }
+
+type sizeOperand struct{ path Path }
+
+func (op sizeOperand) eval(item Item) (types.AttributeValue, bool) {
+	val, has := op.path.Get(item)
+	if !has {
+		return nil, false
+	}
+
+	n, ok := sizeOf(val)
+	if !ok {
+		return nil, false
+	}
+
+	return &types.AttributeValueMemberN{Value: strconv.Itoa(n)}, true
+}
+
+//------------------------------------------------------------------------------
+//
+// Parser
+//
+//------------------------------------------------------------------------------
+
+type parser struct {
+	seq   []token
+	at    int
+	exprs *Expressions
+}
+
+func (exprs *Expressions) parser(expr string) (*parser, error) {
+	seq, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parser{seq: seq, exprs: exprs}, nil
+}
+
+func (p *parser) peek() token { return p.seq[p.at] }
+
+func (p *parser) next() token {
+	t := p.seq[p.at]
+	if t.kind != tEOF {
+		p.at++
+	}
+	return t
+}
+
+func (p *parser) accept(text string) bool {
+	if p.peek().is(text) {
+		p.at++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return fmt.Errorf("expected %q, found %s", text, p.peek())
+	}
+	return nil
+}
+
+func (p *parser) eof() error {
+	if t := p.peek(); t.kind != tEOF {
+		return fmt.Errorf("unexpected %s", t)
+	}
+	return nil
+}
+
+// path ::= name ( '.' name | '[' number ']' )*
+func (p *parser) path() (Path, error) {
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	path := Path{{Name: name}}
+	for {
+		switch {
+		case p.accept("."):
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			path = append(path, Elem{Name: name})
+		case p.accept("["):
+			t := p.next()
+			if t.kind != tNumber {
+				return nil, fmt.Errorf("expected list index, found %s", t)
+			}
+			n, err := strconv.Atoi(t.text)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			path = append(path, Elem{Index: n})
+		default:
+			return path, nil
+		}
+	}
+}
+
+func (p *parser) name() (string, error) {
+	t := p.next()
+	switch t.kind {
+	case tName:
+		name, has := p.exprs.names[t.text]
+		if !has {
+			return "", fmt.Errorf("undefined attribute name %s", t.text)
+		}
+		p.exprs.usedNames[t.text] = true
+		return name, nil
+	case tIdent:
+		if isReserved(t.text) {
+			return "", fmt.Errorf("reserved keyword %s", t)
+		}
+		return t.text, nil
+	default:
+		return "", fmt.Errorf("expected attribute name, found %s", t)
+	}
+}
+
+func (p *parser) value() (types.AttributeValue, error) {
+	t := p.next()
+	if t.kind != tValue {
+		return nil, fmt.Errorf("expected attribute value, found %s", t)
+	}
+
+	val, has := p.exprs.values[t.text]
+	if !has {
+		return nil, fmt.Errorf("undefined attribute value %s", t.text)
+	}
+	p.exprs.usedValues[t.text] = true
+
+	return val, nil
+}
+
+// operand ::= path | value | size(path)
+func (p *parser) operand() (operand, error) {
+	switch t := p.peek(); {
+	case t.kind == tValue:
+		val, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		return valueOperand{val}, nil
+	case t.is("size") && p.seq[p.at+1].is("("):
+		p.at += 2
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return sizeOperand{path}, nil
+	default:
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		return pathOperand{path}, nil
+	}
+}
+
+// keywords used by the grammar, they cannot be used as attribute names
+func isReserved(ident string) bool {
+	switch strings.ToUpper(ident) {
+	case "AND", "OR", "NOT", "BETWEEN", "IN", "SET", "REMOVE", "ADD", "DELETE":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+//
+// The file implements lexer of DynamoDB expressions
+//
+
+package ddbexpr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type kind int
+
+const (
+	tEOF kind = iota
+	tIdent
+	tName
+	tValue
+	tNumber
+	tPunct
+)
+
+type token struct {
+	kind kind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q at %d", t.text, t.pos)
+}
+
+// is checks if token is punctuation or keyword (case insensitive)
+func (t token) is(text string) bool {
+	switch t.kind {
+	case tPunct:
+		return t.text == text
+	case tIdent:
+		return strings.EqualFold(t.text, text)
+	}
+	return false
+}
+
+func lex(expr string) ([]token, error) {
+	seq := make([]token, 0)
+	rs := []rune(expr)
+
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '<' || r == '>':
+			if i+1 < len(rs) && (rs[i+1] == '=' || (r == '<' && rs[i+1] == '>')) {
+				seq = append(seq, token{kind: tPunct, text: string(rs[i : i+2]), pos: i})
+				i += 2
+			} else {
+				seq = append(seq, token{kind: tPunct, text: string(r), pos: i})
+				i++
+			}
+		case strings.ContainsRune("()[],.=+-", r):
+			seq = append(seq, token{kind: tPunct, text: string(r), pos: i})
+			i++
+		case r == '#' || r == ':':
+			j := i + 1
+			for j < len(rs) && isPlaceholder(rs[j]) {
+				j++
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("invalid placeholder at %d", i)
+			}
+			k := tName
+			if r == ':' {
+				k = tValue
+			}
+			seq = append(seq, token{kind: k, text: string(rs[i:j]), pos: i})
+			i = j
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(rs) && unicode.IsDigit(rs[j]) {
+				j++
+			}
+			seq = append(seq, token{kind: tNumber, text: string(rs[i:j]), pos: i})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_') {
+				j++
+			}
+			seq = append(seq, token{kind: tIdent, text: string(rs[i:j]), pos: i})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected symbol %q at %d", r, i)
+		}
+	}
+
+	return append(seq, token{kind: tEOF, pos: len(rs)}), nil
+}
+
+func isPlaceholder(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune("()[],.=<>+-", r)
+}
@@ -0,0 +1,108 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+//
+// The file implements projection expressions
+//
+//	https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/Expressions.ProjectionExpressions.html
+//
+
+package ddbexpr
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Projection is evaluated projection expression
+type Projection []Path
+
+// Projection parses projection expression
+//
+//	projection ::= path (, path)*
+func (exprs *Expressions) Projection(expr string) (Projection, error) {
+	p, err := exprs.parser(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	seq := make(Projection, 0)
+	for {
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, path)
+
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	if err := p.eof(); err != nil {
+		return nil, err
+	}
+
+	return seq, nil
+}
+
+// Apply projection to the item, the function returns new item with
+// attributes defined by the projection
+func (seq Projection) Apply(item Item) Item {
+	if item == nil {
+		return nil
+	}
+
+	obj := Item{}
+	for _, path := range seq {
+		val, has := path.Get(item)
+		if !has {
+			continue
+		}
+
+		project(&types.AttributeValueMemberM{Value: obj}, path, CloneValue(val))
+	}
+
+	return obj
+}
+
+// project builds document path at the target, list elements are compacted
+func project(target types.AttributeValue, path Path, val types.AttributeValue) {
+	for i, e := range path {
+		last := i == len(path)-1
+
+		switch v := target.(type) {
+		case *types.AttributeValueMemberM:
+			if last {
+				v.Value[e.Name] = val
+				return
+			}
+			next, has := v.Value[e.Name]
+			if !has {
+				next = containerOf(path[i+1])
+				v.Value[e.Name] = next
+			}
+			target = next
+		case *types.AttributeValueMemberL:
+			// elements of list are compacted in the order of projection
+			if last {
+				v.Value = append(v.Value, val)
+				return
+			}
+			next := containerOf(path[i+1])
+			v.Value = append(v.Value, next)
+			target = next
+		}
+	}
+}
+
+func containerOf(e Elem) types.AttributeValue {
+	if e.Name == "" {
+		return &types.AttributeValueMemberL{Value: []types.AttributeValue{}}
+	}
+	return &types.AttributeValueMemberM{Value: Item{}}
+}
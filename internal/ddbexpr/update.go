@@ -0,0 +1,561 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+//
+// The file implements update expressions
+//
+//	https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/Expressions.UpdateExpressions.html
+//
+
+package ddbexpr
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Update is evaluated update expression
+type Update struct {
+	actions []action
+}
+
+// action of update expression
+type action struct {
+	verb string
+	path Path
+	val  setOperand
+}
+
+// Paths returns document paths modified by the update expression
+func (u *Update) Paths() []Path {
+	seq := make([]Path, len(u.actions))
+	for i, a := range u.actions {
+		seq[i] = a.path
+	}
+	return seq
+}
+
+// Apply update expression to the item. The item is not modified, the function
+// returns updated copy of the item. All operands are evaluated against the
+// original item as DynamoDB does.
+func (u *Update) Apply(item Item) (Item, error) {
+	vals := make([]types.AttributeValue, len(u.actions))
+	for i, a := range u.actions {
+		if a.val == nil {
+			continue
+		}
+
+		val, err := a.val.value(item)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = val
+	}
+
+	obj := Clone(item)
+
+	// list elements are removed in the descending order of indexes so that
+	// indexes refers to the original item
+	removes := make([]Path, 0)
+
+	for i, a := range u.actions {
+		switch a.verb {
+		case "SET":
+			if err := a.path.set(obj, vals[i]); err != nil {
+				return nil, err
+			}
+		case "REMOVE":
+			removes = append(removes, a.path)
+		case "ADD":
+			if err := a.path.add(obj, vals[i]); err != nil {
+				return nil, err
+			}
+		case "DELETE":
+			if err := a.path.delete(obj, vals[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sortPaths(removes)
+	for i := len(removes) - 1; i >= 0; i-- {
+		removes[i].remove(obj)
+	}
+
+	return obj, nil
+}
+
+// Update parses update expression
+//
+//	update ::= clause+
+//	clause ::= SET path = value (, path = value)*
+//	        |  REMOVE path (, path)*
+//	        |  ADD path value (, path value)*
+//	        |  DELETE path value (, path value)*
+func (exprs *Expressions) Update(expr string) (*Update, error) {
+	p, err := exprs.parser(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	upd := &Update{actions: make([]action, 0)}
+	seen := map[string]bool{}
+
+	for p.peek().kind != tEOF {
+		t := p.next()
+		verb := strings.ToUpper(t.text)
+		if t.kind != tIdent || (verb != "SET" && verb != "REMOVE" && verb != "ADD" && verb != "DELETE") {
+			return nil, fmt.Errorf("expected SET, REMOVE, ADD or DELETE, found %s", t)
+		}
+
+		if seen[verb] {
+			return nil, fmt.Errorf("the %s section can only be used once in an update expression", verb)
+		}
+		seen[verb] = true
+
+		for {
+			a, err := p.action(verb)
+			if err != nil {
+				return nil, err
+			}
+			upd.actions = append(upd.actions, a)
+
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if len(upd.actions) == 0 {
+		return nil, fmt.Errorf("empty update expression")
+	}
+
+	for i := 0; i < len(upd.actions); i++ {
+		for j := i + 1; j < len(upd.actions); j++ {
+			if upd.actions[i].path.overlaps(upd.actions[j].path) {
+				return nil, fmt.Errorf("two document paths overlap with each other: [%s, %s]", upd.actions[i].path, upd.actions[j].path)
+			}
+		}
+	}
+
+	return upd, nil
+}
+
+func (p *parser) action(verb string) (action, error) {
+	path, err := p.path()
+	if err != nil {
+		return action{}, err
+	}
+
+	switch verb {
+	case "SET":
+		if err := p.expect("="); err != nil {
+			return action{}, err
+		}
+		val, err := p.setValue()
+		if err != nil {
+			return action{}, err
+		}
+		return action{verb: verb, path: path, val: val}, nil
+	case "REMOVE":
+		return action{verb: verb, path: path}, nil
+	default:
+		val, err := p.value()
+		if err != nil {
+			return action{}, err
+		}
+		return action{verb: verb, path: path, val: valueSetOperand{val}}, nil
+	}
+}
+
+//------------------------------------------------------------------------------
+//
+// Operands of SET action
+//
+//------------------------------------------------------------------------------
+
+type setOperand interface {
+	value(Item) (types.AttributeValue, error)
+}
+
+type valueSetOperand struct{ val types.AttributeValue }
+
+func (op valueSetOperand) value(Item) (types.AttributeValue, error) { return op.val, nil }
+
+type pathSetOperand struct{ path Path }
+
+func (op pathSetOperand) value(item Item) (types.AttributeValue, error) {
+	val, has := op.path.Get(item)
+	if !has {
+		return nil, fmt.Errorf("the provided expression refers to an attribute that does not exist in the item: %s", op.path)
+	}
+	return val, nil
+}
+
+type ifNotExistsOperand struct {
+	path Path
+	val  setOperand
+}
+
+func (op ifNotExistsOperand) value(item Item) (types.AttributeValue, error) {
+	if val, has := op.path.Get(item); has {
+		return val, nil
+	}
+	return op.val.value(item)
+}
+
+type listAppendOperand struct{ a, b setOperand }
+
+func (op listAppendOperand) value(item Item) (types.AttributeValue, error) {
+	a, err := op.a.value(item)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := op.b.value(item)
+	if err != nil {
+		return nil, err
+	}
+
+	la, oka := a.(*types.AttributeValueMemberL)
+	lb, okb := b.(*types.AttributeValueMemberL)
+	if !oka || !okb {
+		return nil, fmt.Errorf("incorrect operand type for operator or function; operator or function: list_append, operand type: %s", TypeOf(a)+","+TypeOf(b))
+	}
+
+	seq := make([]types.AttributeValue, 0, len(la.Value)+len(lb.Value))
+	seq = append(seq, la.Value...)
+	seq = append(seq, lb.Value...)
+	return &types.AttributeValueMemberL{Value: seq}, nil
+}
+
+type arithmeticOperand struct {
+	op   string
+	a, b setOperand
+}
+
+func (op arithmeticOperand) value(item Item) (types.AttributeValue, error) {
+	a, err := op.a.value(item)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := op.b.value(item)
+	if err != nil {
+		return nil, err
+	}
+
+	x, okx := numberOf(a)
+	y, oky := numberOf(b)
+	if !okx || !oky {
+		return nil, fmt.Errorf("incorrect operand type for operator or function; operator: %s, operand type: %s", op.op, TypeOf(a)+","+TypeOf(b))
+	}
+
+	if op.op == "+" {
+		return &types.AttributeValueMemberN{Value: FormatNumber(x.Add(x, y))}, nil
+	}
+	return &types.AttributeValueMemberN{Value: FormatNumber(x.Sub(x, y))}, nil
+}
+
+// value ::= operand | operand + operand | operand - operand
+func (p *parser) setValue() (setOperand, error) {
+	a, err := p.setOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.is("+") || t.is("-") {
+		p.next()
+		b, err := p.setOperand()
+		if err != nil {
+			return nil, err
+		}
+		return arithmeticOperand{op: t.text, a: a, b: b}, nil
+	}
+
+	return a, nil
+}
+
+// operand ::= path | value | if_not_exists(path, operand) | list_append(operand, operand)
+func (p *parser) setOperand() (setOperand, error) {
+	switch t := p.peek(); {
+	case t.kind == tValue:
+		val, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		return valueSetOperand{val}, nil
+	case t.is("if_not_exists") && p.seq[p.at+1].is("("):
+		p.at += 2
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		val, err := p.setOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return ifNotExistsOperand{path: path, val: val}, nil
+	case t.is("list_append") && p.seq[p.at+1].is("("):
+		p.at += 2
+		a, err := p.setOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		b, err := p.setOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return listAppendOperand{a: a, b: b}, nil
+	default:
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		return pathSetOperand{path}, nil
+	}
+}
+
+//------------------------------------------------------------------------------
+//
+// Modification of document paths
+//
+//------------------------------------------------------------------------------
+
+// overlaps checks if paths are equal or one path is prefix of another
+func (path Path) overlaps(other Path) bool {
+	n := len(path)
+	if len(other) < n {
+		n = len(other)
+	}
+
+	for i := 0; i < n; i++ {
+		if path[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// parent returns container of the last path element
+func (path Path) parent(item Item) (types.AttributeValue, error) {
+	parent, has := path[:len(path)-1].Get(item)
+	if !has {
+		return nil, fmt.Errorf("the document path provided in the update expression is invalid for update: %s", path)
+	}
+	return parent, nil
+}
+
+func (path Path) set(item Item, val types.AttributeValue) error {
+	parent, err := path.parent(item)
+	if err != nil {
+		return err
+	}
+
+	e := path[len(path)-1]
+	switch v := parent.(type) {
+	case *types.AttributeValueMemberM:
+		if e.Name == "" {
+			break
+		}
+		v.Value[e.Name] = val
+		return nil
+	case *types.AttributeValueMemberL:
+		if e.Name != "" {
+			break
+		}
+		if e.Index < len(v.Value) {
+			v.Value[e.Index] = val
+		} else {
+			v.Value = append(v.Value, val)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("the document path provided in the update expression is invalid for update: %s", path)
+}
+
+func (path Path) remove(item Item) {
+	parent, has := path[:len(path)-1].Get(item)
+	if !has {
+		return
+	}
+
+	e := path[len(path)-1]
+	switch v := parent.(type) {
+	case *types.AttributeValueMemberM:
+		delete(v.Value, e.Name)
+	case *types.AttributeValueMemberL:
+		if e.Name == "" && e.Index < len(v.Value) {
+			v.Value = append(v.Value[:e.Index], v.Value[e.Index+1:]...)
+		}
+	}
+}
+
+func (path Path) add(item Item, val types.AttributeValue) error {
+	cur, has := path.Get(item)
+	if !has {
+		switch val.(type) {
+		case *types.AttributeValueMemberN, *types.AttributeValueMemberSS, *types.AttributeValueMemberNS, *types.AttributeValueMemberBS:
+			return path.set(item, val)
+		}
+		return fmt.Errorf("incorrect operand type for operator or function; operator: ADD, operand type: %s", TypeOf(val))
+	}
+
+	switch a := cur.(type) {
+	case *types.AttributeValueMemberN:
+		x, okx := numberOf(a)
+		y, oky := numberOf(val)
+		if okx && oky {
+			return path.set(item, &types.AttributeValueMemberN{Value: FormatNumber(x.Add(x, y))})
+		}
+	case *types.AttributeValueMemberSS:
+		if b, ok := val.(*types.AttributeValueMemberSS); ok {
+			seq := append([]string{}, a.Value...)
+			for _, x := range b.Value {
+				if !contains(&types.AttributeValueMemberSS{Value: seq}, &types.AttributeValueMemberS{Value: x}) {
+					seq = append(seq, x)
+				}
+			}
+			return path.set(item, &types.AttributeValueMemberSS{Value: seq})
+		}
+	case *types.AttributeValueMemberNS:
+		if b, ok := val.(*types.AttributeValueMemberNS); ok {
+			seq := append([]string{}, a.Value...)
+			for _, x := range b.Value {
+				if !contains(&types.AttributeValueMemberNS{Value: seq}, &types.AttributeValueMemberN{Value: x}) {
+					seq = append(seq, x)
+				}
+			}
+			return path.set(item, &types.AttributeValueMemberNS{Value: seq})
+		}
+	case *types.AttributeValueMemberBS:
+		if b, ok := val.(*types.AttributeValueMemberBS); ok {
+			seq := append([][]byte{}, a.Value...)
+			for _, x := range b.Value {
+				if !contains(&types.AttributeValueMemberBS{Value: seq}, &types.AttributeValueMemberB{Value: x}) {
+					seq = append(seq, x)
+				}
+			}
+			return path.set(item, &types.AttributeValueMemberBS{Value: seq})
+		}
+	}
+
+	return fmt.Errorf("incorrect operand type for operator or function; operator: ADD, operand type: %s", TypeOf(cur)+","+TypeOf(val))
+}
+
+func (path Path) delete(item Item, val types.AttributeValue) error {
+	cur, has := path.Get(item)
+	if !has {
+		return nil
+	}
+
+	var (
+		seq types.AttributeValue
+		n   int
+	)
+
+	switch a := cur.(type) {
+	case *types.AttributeValueMemberSS:
+		b, ok := val.(*types.AttributeValueMemberSS)
+		if !ok {
+			break
+		}
+		set := &types.AttributeValueMemberSS{Value: []string{}}
+		for _, x := range a.Value {
+			if !contains(b, &types.AttributeValueMemberS{Value: x}) {
+				set.Value = append(set.Value, x)
+			}
+		}
+		seq, n = set, len(set.Value)
+	case *types.AttributeValueMemberNS:
+		b, ok := val.(*types.AttributeValueMemberNS)
+		if !ok {
+			break
+		}
+		set := &types.AttributeValueMemberNS{Value: []string{}}
+		for _, x := range a.Value {
+			if !contains(b, &types.AttributeValueMemberN{Value: x}) {
+				set.Value = append(set.Value, x)
+			}
+		}
+		seq, n = set, len(set.Value)
+	case *types.AttributeValueMemberBS:
+		b, ok := val.(*types.AttributeValueMemberBS)
+		if !ok {
+			break
+		}
+		set := &types.AttributeValueMemberBS{Value: [][]byte{}}
+		for _, x := range a.Value {
+			if !contains(b, &types.AttributeValueMemberB{Value: x}) {
+				set.Value = append(set.Value, x)
+			}
+		}
+		seq, n = set, len(set.Value)
+	}
+
+	if seq == nil {
+		return fmt.Errorf("incorrect operand type for operator or function; operator: DELETE, operand type: %s", TypeOf(cur)+","+TypeOf(val))
+	}
+
+	// DynamoDB does not support empty sets
+	if n == 0 {
+		path.remove(item)
+		return nil
+	}
+
+	return path.set(item, seq)
+}
+
+// sortPaths orders paths, list indexes are compared as numbers
+func sortPaths(seq []Path) {
+	less := func(a, b Path) bool {
+		for i := 0; i < len(a) && i < len(b); i++ {
+			switch {
+			case a[i].Name < b[i].Name:
+				return true
+			case a[i].Name > b[i].Name:
+				return false
+			case a[i].Index < b[i].Index:
+				return true
+			case a[i].Index > b[i].Index:
+				return false
+			}
+		}
+		return len(a) < len(b)
+	}
+
+	for i := 1; i < len(seq); i++ {
+		for j := i; j > 0 && less(seq[j], seq[j-1]); j-- {
+			seq[j], seq[j-1] = seq[j-1], seq[j]
+		}
+	}
+}
+
+func numberOf(val types.AttributeValue) (*big.Rat, bool) {
+	n, ok := val.(*types.AttributeValueMemberN)
+	if !ok {
+		return nil, false
+	}
+	return Number(n.Value)
+}
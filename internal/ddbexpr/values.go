@@ -0,0 +1,234 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+//
+// The file implements algebra of attribute values
+//
+
+package ddbexpr
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// TypeOf returns DynamoDB type of attribute value (e.g. S, N, SS, M)
+func TypeOf(val types.AttributeValue) string {
+	switch val.(type) {
+	case *types.AttributeValueMemberS:
+		return "S"
+	case *types.AttributeValueMemberN:
+		return "N"
+	case *types.AttributeValueMemberB:
+		return "B"
+	case *types.AttributeValueMemberSS:
+		return "SS"
+	case *types.AttributeValueMemberNS:
+		return "NS"
+	case *types.AttributeValueMemberBS:
+		return "BS"
+	case *types.AttributeValueMemberBOOL:
+		return "BOOL"
+	case *types.AttributeValueMemberNULL:
+		return "NULL"
+	case *types.AttributeValueMemberL:
+		return "L"
+	case *types.AttributeValueMemberM:
+		return "M"
+	}
+	return ""
+}
+
+// Number parses numeric value
+func Number(val string) (*big.Rat, bool) {
+	return new(big.Rat).SetString(val)
+}
+
+// Equal checks equality of attribute values, sets are unordered
+func Equal(x, y types.AttributeValue) bool {
+	switch a := x.(type) {
+	case *types.AttributeValueMemberS:
+		b, ok := y.(*types.AttributeValueMemberS)
+		return ok && a.Value == b.Value
+	case *types.AttributeValueMemberN:
+		b, ok := y.(*types.AttributeValueMemberN)
+		if !ok {
+			return false
+		}
+		cmp, ok := Compare(a, b)
+		return ok && cmp == 0
+	case *types.AttributeValueMemberB:
+		b, ok := y.(*types.AttributeValueMemberB)
+		return ok && bytes.Equal(a.Value, b.Value)
+	case *types.AttributeValueMemberBOOL:
+		b, ok := y.(*types.AttributeValueMemberBOOL)
+		return ok && a.Value == b.Value
+	case *types.AttributeValueMemberNULL:
+		_, ok := y.(*types.AttributeValueMemberNULL)
+		return ok
+	case *types.AttributeValueMemberSS:
+		b, ok := y.(*types.AttributeValueMemberSS)
+		return ok && equalSet(len(a.Value), len(b.Value), func(i, j int) bool { return a.Value[i] == b.Value[j] })
+	case *types.AttributeValueMemberNS:
+		b, ok := y.(*types.AttributeValueMemberNS)
+		return ok && equalSet(len(a.Value), len(b.Value), func(i, j int) bool {
+			return Equal(&types.AttributeValueMemberN{Value: a.Value[i]}, &types.AttributeValueMemberN{Value: b.Value[j]})
+		})
+	case *types.AttributeValueMemberBS:
+		b, ok := y.(*types.AttributeValueMemberBS)
+		return ok && equalSet(len(a.Value), len(b.Value), func(i, j int) bool { return bytes.Equal(a.Value[i], b.Value[j]) })
+	case *types.AttributeValueMemberL:
+		b, ok := y.(*types.AttributeValueMemberL)
+		if !ok || len(a.Value) != len(b.Value) {
+			return false
+		}
+		for i := range a.Value {
+			if !Equal(a.Value[i], b.Value[i]) {
+				return false
+			}
+		}
+		return true
+	case *types.AttributeValueMemberM:
+		b, ok := y.(*types.AttributeValueMemberM)
+		if !ok || len(a.Value) != len(b.Value) {
+			return false
+		}
+		for k, v := range a.Value {
+			if w, has := b.Value[k]; !has || !Equal(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func equalSet(n, m int, eq func(int, int) bool) bool {
+	if n != m {
+		return false
+	}
+
+	for i := 0; i < n; i++ {
+		if indexOf(m, func(j int) bool { return eq(i, j) }) == -1 {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare ordered attribute values (numbers, strings and binaries)
+func Compare(x, y types.AttributeValue) (int, bool) {
+	switch a := x.(type) {
+	case *types.AttributeValueMemberN:
+		b, ok := y.(*types.AttributeValueMemberN)
+		if !ok {
+			return 0, false
+		}
+		na, oka := Number(a.Value)
+		nb, okb := Number(b.Value)
+		if !oka || !okb {
+			return 0, false
+		}
+		return na.Cmp(nb), true
+	case *types.AttributeValueMemberS:
+		b, ok := y.(*types.AttributeValueMemberS)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(a.Value, b.Value), true
+	case *types.AttributeValueMemberB:
+		b, ok := y.(*types.AttributeValueMemberB)
+		if !ok {
+			return 0, false
+		}
+		return bytes.Compare(a.Value, b.Value), true
+	}
+	return 0, false
+}
+
+func sizeOf(val types.AttributeValue) (int, bool) {
+	switch v := val.(type) {
+	case *types.AttributeValueMemberS:
+		return utf8.RuneCountInString(v.Value), true
+	case *types.AttributeValueMemberB:
+		return len(v.Value), true
+	case *types.AttributeValueMemberSS:
+		return len(v.Value), true
+	case *types.AttributeValueMemberNS:
+		return len(v.Value), true
+	case *types.AttributeValueMemberBS:
+		return len(v.Value), true
+	case *types.AttributeValueMemberL:
+		return len(v.Value), true
+	case *types.AttributeValueMemberM:
+		return len(v.Value), true
+	}
+	return 0, false
+}
+
+// FormatNumber formats number using decimal notation
+func FormatNumber(n *big.Rat) string {
+	if n.IsInt() {
+		return n.Num().String()
+	}
+
+	s := strings.TrimRight(n.FloatString(38), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Clone makes deep copy of the item
+func Clone(item Item) Item {
+	if item == nil {
+		return nil
+	}
+
+	obj := make(Item, len(item))
+	for k, v := range item {
+		obj[k] = CloneValue(v)
+	}
+	return obj
+}
+
+// CloneValue makes deep copy of attribute value
+func CloneValue(val types.AttributeValue) types.AttributeValue {
+	switch v := val.(type) {
+	case *types.AttributeValueMemberS:
+		return &types.AttributeValueMemberS{Value: v.Value}
+	case *types.AttributeValueMemberN:
+		return &types.AttributeValueMemberN{Value: v.Value}
+	case *types.AttributeValueMemberB:
+		return &types.AttributeValueMemberB{Value: append([]byte{}, v.Value...)}
+	case *types.AttributeValueMemberBOOL:
+		return &types.AttributeValueMemberBOOL{Value: v.Value}
+	case *types.AttributeValueMemberNULL:
+		return &types.AttributeValueMemberNULL{Value: v.Value}
+	case *types.AttributeValueMemberSS:
+		return &types.AttributeValueMemberSS{Value: append([]string{}, v.Value...)}
+	case *types.AttributeValueMemberNS:
+		return &types.AttributeValueMemberNS{Value: append([]string{}, v.Value...)}
+	case *types.AttributeValueMemberBS:
+		seq := make([][]byte, len(v.Value))
+		for i, x := range v.Value {
+			seq[i] = append([]byte{}, x...)
+		}
+		return &types.AttributeValueMemberBS{Value: seq}
+	case *types.AttributeValueMemberL:
+		seq := make([]types.AttributeValue, len(v.Value))
+		for i, x := range v.Value {
+			seq[i] = CloneValue(x)
+		}
+		return &types.AttributeValueMemberL{Value: seq}
+	case *types.AttributeValueMemberM:
+		return &types.AttributeValueMemberM{Value: Clone(v.Value)}
+	}
+	return val
+}
@@ -16,7 +16,12 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
@@ -38,6 +43,7 @@ func mock[T dynamo.Thing](mock ddbapi.DynamoDB) dynamo.KeyVal[T] {
 			ddbapi.WithHashKey("prefix"),
 			ddbapi.WithSortKey("suffix"),
 			ddbapi.WithStrictType(true),
+			ddbapi.WithBackoff(3, time.Millisecond),
 		),
 	)
 }
@@ -205,6 +211,286 @@ func (mock *ddbQuery) Query(ctx context.Context, input *dynamodb.QueryInput, opt
 	}, nil
 }
 
+/*
+BatchWriteItem mock, it leaves the last item of the request unprocessed
+for first n requests.
+*/
+func BatchWriteItem[T dynamo.Thing](
+	unprocessed int,
+	returnKeys *[]map[string]types.AttributeValue,
+) dynamo.KeyVal[T] {
+	return mock[T](&ddbBatchWriteItem{
+		unprocessed: unprocessed,
+		returnKeys:  returnKeys,
+	})
+}
+
+type ddbBatchWriteItem struct {
+	ddbapi.DynamoDB
+	unprocessed int
+	returnKeys  *[]map[string]types.AttributeValue
+}
+
+func (mock *ddbBatchWriteItem) BatchWriteItem(ctx context.Context, input *dynamodb.BatchWriteItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	seq, exists := input.RequestItems["test"]
+	if !exists || len(seq) == 0 || len(seq) > 25 {
+		return nil, errors.New("unexpected request")
+	}
+
+	var unprocessed map[string][]types.WriteRequest
+	if mock.unprocessed > 0 {
+		mock.unprocessed--
+		unprocessed = map[string][]types.WriteRequest{"test": seq[len(seq)-1:]}
+		seq = seq[:len(seq)-1]
+	}
+
+	for _, req := range seq {
+		switch {
+		case req.PutRequest != nil:
+			*mock.returnKeys = append(*mock.returnKeys, req.PutRequest.Item)
+		case req.DeleteRequest != nil:
+			*mock.returnKeys = append(*mock.returnKeys, req.DeleteRequest.Key)
+		}
+	}
+
+	return &dynamodb.BatchWriteItemOutput{UnprocessedItems: unprocessed}, nil
+}
+
+/*
+BatchGetItem mock, it returns items of the storage in reverse order and leaves
+the last key of the request unprocessed for first n requests.
+*/
+func BatchGetItem[T dynamo.Thing](
+	unprocessed int,
+	returnVal []map[string]types.AttributeValue,
+) dynamo.KeyVal[T] {
+	return mock[T](&ddbBatchGetItem{
+		unprocessed: unprocessed,
+		returnVal:   returnVal,
+	})
+}
+
+type ddbBatchGetItem struct {
+	ddbapi.DynamoDB
+	sync.Mutex
+	unprocessed int
+	returnVal   []map[string]types.AttributeValue
+}
+
+func (mock *ddbBatchGetItem) BatchGetItem(ctx context.Context, input *dynamodb.BatchGetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	mock.Lock()
+	defer mock.Unlock()
+
+	req, exists := input.RequestItems["test"]
+	if !exists || len(req.Keys) == 0 || len(req.Keys) > 100 {
+		return nil, errors.New("unexpected request")
+	}
+
+	keys := req.Keys
+	var unprocessed map[string]types.KeysAndAttributes
+	if mock.unprocessed > 0 {
+		mock.unprocessed--
+		unprocessed = map[string]types.KeysAndAttributes{
+			"test": {Keys: keys[len(keys)-1:]},
+		}
+		keys = keys[:len(keys)-1]
+	}
+
+	seq := []map[string]types.AttributeValue{}
+	for i := len(keys) - 1; i >= 0; i-- {
+		for _, val := range mock.returnVal {
+			if reflect.DeepEqual(keys[i]["prefix"], val["prefix"]) && reflect.DeepEqual(keys[i]["suffix"], val["suffix"]) {
+				seq = append(seq, val)
+			}
+		}
+	}
+
+	return &dynamodb.BatchGetItemOutput{
+		Responses:       map[string][]map[string]types.AttributeValue{"test": seq},
+		UnprocessedKeys: unprocessed,
+	}, nil
+}
+
+/*
+TransactWriteItems mock, it cancels transaction if item at failAt
+has condition expression.
+*/
+func TransactWriteItems(
+	failAt int,
+	returnItems *[]types.TransactWriteItem,
+) ddbapi.DynamoDB {
+	return &ddbTransactWriteItems{
+		failAt:      failAt,
+		returnItems: returnItems,
+	}
+}
+
+type ddbTransactWriteItems struct {
+	ddbapi.DynamoDB
+	failAt      int
+	returnItems *[]types.TransactWriteItem
+}
+
+func (mock *ddbTransactWriteItems) TransactWriteItems(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	if mock.failAt >= 0 && mock.failAt < len(input.TransactItems) {
+		reasons := make([]types.CancellationReason, len(input.TransactItems))
+		for i := range reasons {
+			reasons[i] = types.CancellationReason{Code: aws.String("None")}
+		}
+		reasons[mock.failAt] = types.CancellationReason{Code: aws.String("ConditionalCheckFailed")}
+
+		return nil, &types.TransactionCanceledException{CancellationReasons: reasons}
+	}
+
+	*mock.returnItems = append(*mock.returnItems, input.TransactItems...)
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+/*
+TransactGetItems mock, it returns items of the storage matching the key
+*/
+func TransactGetItems(
+	returnVal []map[string]types.AttributeValue,
+) ddbapi.DynamoDB {
+	return &ddbTransactGetItems{returnVal: returnVal}
+}
+
+type ddbTransactGetItems struct {
+	ddbapi.DynamoDB
+	returnVal []map[string]types.AttributeValue
+}
+
+func (mock *ddbTransactGetItems) TransactGetItems(ctx context.Context, input *dynamodb.TransactGetItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error) {
+	seq := make([]types.ItemResponse, len(input.TransactItems))
+	for i, item := range input.TransactItems {
+		if item.Get == nil {
+			return nil, errors.New("unexpected request")
+		}
+
+		for _, val := range mock.returnVal {
+			if reflect.DeepEqual(item.Get.Key["prefix"], val["prefix"]) && reflect.DeepEqual(item.Get.Key["suffix"], val["suffix"]) {
+				seq[i] = types.ItemResponse{Item: val}
+			}
+		}
+	}
+
+	return &dynamodb.TransactGetItemsOutput{Responses: seq}, nil
+}
+
+/*
+QueryRequest mock, it captures the request and returns empty result
+*/
+func QueryRequest(
+	returnReq **dynamodb.QueryInput,
+) ddbapi.DynamoDB {
+	return &ddbQueryRequest{returnReq: returnReq}
+}
+
+type ddbQueryRequest struct {
+	ddbapi.DynamoDB
+	returnReq **dynamodb.QueryInput
+}
+
+func (mock *ddbQueryRequest) Query(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	*mock.returnReq = input
+	return &dynamodb.QueryOutput{}, nil
+}
+
+/*
+QueryPages mock, it emulates filter expression that drops items, each request
+returns one item and last evaluated key. The mock captures requests.
+*/
+func QueryPages(
+	returnVal map[string]types.AttributeValue,
+	returnReqs *[]dynamodb.QueryInput,
+) ddbapi.DynamoDB {
+	return &ddbQueryPages{returnVal: returnVal, returnReqs: returnReqs}
+}
+
+type ddbQueryPages struct {
+	ddbapi.DynamoDB
+	returnVal  map[string]types.AttributeValue
+	returnReqs *[]dynamodb.QueryInput
+}
+
+func (mock *ddbQueryPages) Query(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	*mock.returnReqs = append(*mock.returnReqs, *input)
+
+	return &dynamodb.QueryOutput{
+		Count:            1,
+		ScannedCount:     aws.ToInt32(input.Limit),
+		Items:            []map[string]types.AttributeValue{mock.returnVal},
+		LastEvaluatedKey: map[string]types.AttributeValue{"prefix": mock.returnVal["prefix"], "suffix": mock.returnVal["suffix"]},
+	}, nil
+}
+
+/*
+Scan mock, it serves the sequence of items in pages of size at most 2 items,
+the item belongs to segment i if its position modulo total segments is i.
+The mock captures requests.
+*/
+func Scan(
+	returnVal []map[string]types.AttributeValue,
+	returnReqs *[]dynamodb.ScanInput,
+) ddbapi.DynamoDB {
+	return &ddbScan{returnVal: returnVal, returnReqs: returnReqs}
+}
+
+type ddbScan struct {
+	ddbapi.DynamoDB
+	sync.Mutex
+	returnVal  []map[string]types.AttributeValue
+	returnReqs *[]dynamodb.ScanInput
+}
+
+func (mock *ddbScan) Scan(ctx context.Context, input *dynamodb.ScanInput, opts ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	mock.Lock()
+	defer mock.Unlock()
+
+	*mock.returnReqs = append(*mock.returnReqs, *input)
+
+	segment, total := int(aws.ToInt32(input.Segment)), int(aws.ToInt32(input.TotalSegments))
+	if total == 0 {
+		total = 1
+	}
+
+	limit := int(aws.ToInt32(input.Limit))
+	if limit == 0 || limit > 2 {
+		limit = 2
+	}
+
+	seq := []map[string]types.AttributeValue{}
+	after := input.ExclusiveStartKey == nil
+	for i, val := range mock.returnVal {
+		if i%total != segment {
+			continue
+		}
+
+		if !after {
+			after = reflect.DeepEqual(input.ExclusiveStartKey["suffix"], val["suffix"])
+			continue
+		}
+
+		seq = append(seq, val)
+	}
+
+	var lastEvaluatedKey map[string]types.AttributeValue
+	if len(seq) > limit {
+		seq = seq[:limit]
+		lastEvaluatedKey = map[string]types.AttributeValue{
+			"prefix": seq[limit-1]["prefix"],
+			"suffix": seq[limit-1]["suffix"],
+		}
+	}
+
+	return &dynamodb.ScanOutput{
+		Count:            int32(len(seq)),
+		ScannedCount:     int32(len(seq)),
+		Items:            seq,
+		LastEvaluatedKey: lastEvaluatedKey,
+	}, nil
+}
+
 func Constrains[T dynamo.Thing](
 	returnVal map[string]types.AttributeValue,
 ) dynamo.KeyVal[T] {
@@ -263,3 +549,76 @@ func (mock ddbConstrains) UpdateItem(ctx context.Context, input *dynamodb.Update
 		Attributes: mock.returnVal,
 	}, nil
 }
+
+/*
+Version mock, it emulates optimistic locking of single item using the
+"version" attribute.
+*/
+func Version(version *int64) ddbapi.DynamoDB {
+	return &ddbVersion{version: version}
+}
+
+type ddbVersion struct {
+	ddbapi.DynamoDB
+	version *int64
+}
+
+func (mock *ddbVersion) assert(expr *string, values map[string]types.AttributeValue) error {
+	if strings.Contains(aws.ToString(expr), "attribute_not_exists") {
+		if *mock.version != 0 {
+			return &types.ConditionalCheckFailedException{}
+		}
+		return nil
+	}
+
+	expected, ok := values[":__c_version__"].(*types.AttributeValueMemberN)
+	if !ok || expected.Value != strconv.FormatInt(*mock.version, 10) {
+		return &types.ConditionalCheckFailedException{}
+	}
+
+	return nil
+}
+
+func (mock *ddbVersion) commit(val types.AttributeValue) error {
+	num, ok := val.(*types.AttributeValueMemberN)
+	if !ok {
+		return errors.New("unexpected version")
+	}
+
+	v, err := strconv.ParseInt(num.Value, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*mock.version = v
+	return nil
+}
+
+func (mock *ddbVersion) PutItem(ctx context.Context, input *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	if err := mock.assert(input.ConditionExpression, input.ExpressionAttributeValues); err != nil {
+		return nil, err
+	}
+
+	if err := mock.commit(input.Item["version"]); err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (mock *ddbVersion) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	if err := mock.assert(input.ConditionExpression, input.ExpressionAttributeValues); err != nil {
+		return nil, err
+	}
+
+	if err := mock.commit(input.ExpressionAttributeValues[":__version__"]); err != nil {
+		return nil, err
+	}
+
+	val := map[string]types.AttributeValue{"version": input.ExpressionAttributeValues[":__version__"]}
+	for k, v := range input.Key {
+		val[k] = v
+	}
+
+	return &dynamodb.UpdateItemOutput{Attributes: val}, nil
+}
@@ -0,0 +1,194 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+//
+// The package implements pointer-aware partial updates. Fields of the patch
+// are classified either as untouched, updated or removed so that storages
+// builds the update from the diff of fields.
+//
+
+package partial
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/fogfish/curie"
+)
+
+// Action of the field
+type Action int
+
+const (
+	// Skip leaves the attribute untouched
+	Skip Action = iota
+	// Set updates the attribute
+	Set
+	// Remove removes the attribute
+	Remove
+)
+
+// Field of the struct with action
+type Field struct {
+	// Name of the attribute (dynamodbav tag or name of field)
+	Name string
+	// Index of the field as defined by reflect.Value.FieldByIndex
+	Index []int
+	// Action to apply on the attribute
+	Action Action
+}
+
+// sentinels of nulls per type
+var nulls sync.Map
+
+// Null returns sentinel pointer of the type
+func Null[A any]() *A {
+	t := reflect.TypeOf((*A)(nil)).Elem()
+	if v, has := nulls.Load(t); has {
+		return v.(*A)
+	}
+
+	v, _ := nulls.LoadOrStore(t, new(A))
+	return v.(*A)
+}
+
+// IsNull checks if the value is sentinel pointer
+func IsNull(v reflect.Value) bool {
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return false
+	}
+
+	null, has := nulls.Load(v.Type().Elem())
+	return has && reflect.ValueOf(null).Pointer() == v.Pointer()
+}
+
+// Requested checks if options demand partial update
+func Requested[O any](opts []O) bool {
+	for _, opt := range opts {
+		if v, ok := any(opt).(interface{ PartialUpdate() bool }); ok && v.PartialUpdate() {
+			return true
+		}
+	}
+	return false
+}
+
+// ActionOf classifies the value of field:
+//   - nil pointers, slices and maps are untouched;
+//   - Null sentinel, empty slices and maps are removed;
+//   - pointers are updated, including pointers to zero values;
+//   - zero values are untouched;
+//   - other values are updated.
+func ActionOf(v reflect.Value) Action {
+	switch v.Kind() {
+	case reflect.Pointer:
+		switch {
+		case v.IsNil():
+			return Skip
+		case IsNull(v):
+			return Remove
+		default:
+			return Set
+		}
+	case reflect.Slice, reflect.Map:
+		switch {
+		case v.IsNil():
+			return Skip
+		case v.Len() == 0:
+			return Remove
+		default:
+			return Set
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return Skip
+		}
+		return Set
+	default:
+		if v.IsZero() {
+			return Skip
+		}
+		return Set
+	}
+}
+
+// Fields classifies fields of the struct, embedded structs are flatten
+// as attributevalue codec does.
+func Fields(val any) []Field {
+	v := reflect.ValueOf(val)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return fieldsOf(v, nil)
+}
+
+func fieldsOf(v reflect.Value, index []int) []Field {
+	seq := make([]Field, 0, v.NumField())
+
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		tag := strings.Split(f.Tag.Get("dynamodbav"), ",")[0]
+		if tag == "-" {
+			continue
+		}
+
+		at := append(append([]int{}, index...), i)
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			seq = append(seq, fieldsOf(v.Field(i), at)...)
+			continue
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		name := tag
+		if name == "" {
+			name = f.Name
+		}
+
+		seq = append(seq, Field{Name: name, Index: at, Action: ActionOf(v.Field(i))})
+	}
+
+	return seq
+}
+
+// Empty checks if the patch does not define any attribute, fields that
+// carry the identity of the entity are not counted.
+func Empty(val interface {
+	HashKey() curie.IRI
+	SortKey() curie.IRI
+}) bool {
+	v := reflect.ValueOf(val)
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+
+	for _, f := range Fields(val) {
+		switch f.Action {
+		case Remove:
+			return false
+		case Set:
+			fv := v.FieldByIndex(f.Index)
+			if fv.Kind() == reflect.String && (fv.String() == string(val.HashKey()) || fv.String() == string(val.SortKey())) {
+				continue
+			}
+			return false
+		}
+	}
+
+	return true
+}
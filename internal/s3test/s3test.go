@@ -18,11 +18,18 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"net/http"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 
 	"github.com/fogfish/curie"
 	"github.com/fogfish/dynamo/v3"
@@ -227,3 +234,193 @@ func (mock *s3GetListObjects[T]) ListObjectsV2(ctx context.Context, input *s3.Li
 		NextContinuationToken: lastEvaluatedKey,
 	}, nil
 }
+
+/*
+ListObjects mock, it serves the sorted sequence of objects in pages of
+size at most 2 objects.
+*/
+func ListObjects[T dynamo.Thing](
+	returnVal []T,
+) dynamo.KeyVal[T] {
+	seq := make([]T, len(returnVal))
+	copy(seq, returnVal)
+	sort.Slice(seq, func(i, j int) bool { return encodeKey(seq[i]) < encodeKey(seq[j]) })
+
+	return mock[T](&s3ListObjects[T]{returnVal: seq})
+}
+
+type s3ListObjects[T dynamo.Thing] struct {
+	s3api.S3
+	returnVal []T
+}
+
+func (mock *s3ListObjects[T]) GetObject(ctx context.Context, input *s3.GetObjectInput, opts ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	for _, x := range mock.returnVal {
+		if encodeKey(x) == *input.Key {
+			val, _ := json.Marshal(x)
+			return &s3.GetObjectOutput{
+				Body: io.NopCloser(bytes.NewReader(val)),
+			}, nil
+		}
+	}
+
+	return nil, &types.NoSuchKey{}
+}
+
+func (mock *s3ListObjects[T]) ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input, opts ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	limit := int(aws.ToInt32(input.MaxKeys))
+	if limit == 0 || limit > 2 {
+		limit = 2
+	}
+
+	after := aws.ToString(input.StartAfter)
+	if input.ContinuationToken != nil {
+		after = *input.ContinuationToken
+	}
+
+	seq := []types.Object{}
+	for _, x := range mock.returnVal {
+		key := encodeKey(x)
+		if strings.HasPrefix(key, aws.ToString(input.Prefix)) && key > after {
+			seq = append(seq, types.Object{Key: aws.String(key)})
+		}
+	}
+
+	var token *string
+	if len(seq) > limit {
+		seq = seq[:limit]
+		token = seq[limit-1].Key
+	}
+
+	return &s3.ListObjectsV2Output{
+		KeyCount:              aws.Int32(int32(len(seq))),
+		Contents:              seq,
+		NextContinuationToken: token,
+	}, nil
+}
+
+/*
+Objects mock, it keeps objects in memory, assigns ETag to each object and
+evaluates If-Match, If-None-Match preconditions of PutObject.
+*/
+func Objects[T dynamo.Thing](opts ...s3api.Option) (*s3api.Storage[T], S3Objects) {
+	mock := &s3Objects{objects: map[string][]byte{}, etags: map[string]string{}}
+	db := s3api.Must(
+		s3api.New[T](
+			append([]s3api.Option{
+				s3api.WithBucket("test"),
+				s3api.WithService(mock),
+				s3api.WithPrefixes(curie.Namespaces{}),
+			}, opts...)...,
+		),
+	)
+
+	return db, mock
+}
+
+// S3Objects is the state of Objects mock
+type S3Objects interface {
+	// Race emulates concurrent writer, it changes ETag of the object right
+	// after each of n following reads of the object.
+	Race(key string, n int)
+}
+
+type s3Objects struct {
+	s3api.S3
+	seq     int
+	race    string
+	raceN   int
+	objects map[string][]byte
+	etags   map[string]string
+}
+
+func (mock *s3Objects) Race(key string, n int) { mock.race, mock.raceN = key, n }
+
+func (mock *s3Objects) touch(key string) {
+	mock.seq++
+	mock.etags[key] = strconv.Itoa(mock.seq)
+}
+
+func (mock *s3Objects) GetObject(ctx context.Context, input *s3.GetObjectInput, opts ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	val, has := mock.objects[aws.ToString(input.Key)]
+	if !has {
+		return nil, &types.NoSuchKey{}
+	}
+
+	out := &s3.GetObjectOutput{
+		Body: io.NopCloser(bytes.NewReader(val)),
+		ETag: aws.String(mock.etags[aws.ToString(input.Key)]),
+	}
+
+	if mock.race == aws.ToString(input.Key) && mock.raceN > 0 {
+		mock.raceN--
+		mock.touch(aws.ToString(input.Key))
+	}
+
+	return out, nil
+}
+
+func (mock *s3Objects) PutObject(ctx context.Context, input *s3.PutObjectInput, opts ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	key := aws.ToString(input.Key)
+	etag, has := mock.etags[key]
+	header := headersOf(opts)
+
+	if h := header.Get("If-None-Match"); h == "*" && has {
+		return nil, &smithy.GenericAPIError{Code: "PreconditionFailed"}
+	}
+
+	if h := header.Get("If-Match"); h != "" && h != etag {
+		return nil, &smithy.GenericAPIError{Code: "PreconditionFailed"}
+	}
+
+	val, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	mock.objects[key] = val
+	mock.touch(key)
+
+	return &s3.PutObjectOutput{ETag: aws.String(mock.etags[key])}, nil
+}
+
+func (mock *s3Objects) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput, opts ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	key := aws.ToString(input.Key)
+
+	if h := headersOf(opts).Get("If-Match"); h != "" && h != mock.etags[key] {
+		return nil, &smithy.GenericAPIError{Code: "PreconditionFailed"}
+	}
+
+	delete(mock.objects, key)
+	delete(mock.etags, key)
+
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+// headersOf evaluates request options and returns HTTP headers defined by them
+func headersOf(opts []func(*s3.Options)) http.Header {
+	var conf s3.Options
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	stack := middleware.NewStack("mock", smithyhttp.NewStackRequest)
+	for _, fn := range conf.APIOptions {
+		if err := fn(stack); err != nil {
+			return nil
+		}
+	}
+
+	header := http.Header{}
+	handler := middleware.HandlerFunc(
+		func(ctx context.Context, input interface{}) (interface{}, middleware.Metadata, error) {
+			if req, ok := input.(*smithyhttp.Request); ok {
+				header = req.Header
+			}
+			return nil, middleware.Metadata{}, nil
+		},
+	)
+
+	middleware.DecorateHandler(handler, stack).Handle(context.Background(), nil)
+	return header
+}
@@ -0,0 +1,105 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+//
+// The file declares lazy sequence of elements on top of Matcher
+//
+
+package dynamo
+
+import (
+	"context"
+)
+
+// Seq is a lazy sequence of elements, it pushes elements to yield function
+// until the function returns false. The sequence yields the error as last
+// element if the I/O fails. The type is equivalent to iter.Seq2[T, error],
+// Go 1.23 and later ranges over it:
+//
+//	for val, err := range seq { ... }
+//
+// Earlier versions of Go calls the sequence with yield function:
+//
+//	seq(func(val T, err error) bool { ... })
+type Seq[T Thing] func(yield func(T, error) bool)
+
+// MaxItems option for Iterate, caps the total number of elements in sequence
+func MaxItems[T Thing](n int) interface{ MatcherOpt(T) } { return maxItems[T](n) }
+
+type maxItems[T Thing] int
+
+func (maxItems[T]) MatcherOpt(T) {}
+
+func (n maxItems[T]) MaxItems() int { return int(n) }
+
+// Iterate builds lazy sequence of elements matching the key. Pages are
+// fetched from storage on demand, the cursor is threaded automatically.
+// Options are passed to Match (e.g. Limit defines the page size, Descending),
+// use MaxItems to cap the total number of elements. The sequence is
+// terminated with context error when context is cancelled.
+func Iterate[T Thing](ctx context.Context, db Matcher[T], key T, opts ...interface{ MatcherOpt(T) }) Seq[T] {
+	total := -1
+	req := make([]interface{ MatcherOpt(T) }, 0, len(opts)+1)
+	var start interface{ MatcherOpt(T) }
+
+	for _, opt := range opts {
+		switch v := opt.(type) {
+		case interface{ MaxItems() int }:
+			total = v.MaxItems()
+		case Thing:
+			start = opt
+		default:
+			req = append(req, opt)
+		}
+	}
+
+	return func(yield func(T, error) bool) {
+		var none T
+		seen := 0
+		cursor := start
+
+		for total != 0 {
+			if err := ctx.Err(); err != nil {
+				yield(none, err)
+				return
+			}
+
+			page := req
+			if cursor != nil {
+				page = append(page[:len(page):len(page)], cursor)
+			}
+
+			seq, next, err := db.Match(ctx, key, page...)
+			if err != nil {
+				yield(none, err)
+				return
+			}
+
+			for _, val := range seq {
+				if err := ctx.Err(); err != nil {
+					yield(none, err)
+					return
+				}
+
+				if !yield(val, nil) {
+					return
+				}
+
+				seen++
+				if total > 0 && seen == total {
+					return
+				}
+			}
+
+			if next == nil {
+				return
+			}
+			cursor = next
+		}
+	}
+}
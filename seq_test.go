@@ -0,0 +1,192 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package dynamo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fogfish/curie"
+	"github.com/fogfish/dynamo/v3"
+	"github.com/fogfish/it"
+)
+
+// cursor of pages returned by matcher, it refers to index of page
+type pageCursor struct{ thing }
+
+func (pageCursor) MatcherOpt(thing) {}
+
+// matcher returns pages, it fails with error at page fail (if defined)
+type matcher struct {
+	pages   [][]thing
+	fail    int
+	err     error
+	cursors []interface{ MatcherOpt(thing) }
+}
+
+func (m *matcher) MatchKey(ctx context.Context, key dynamo.Thing, opts ...interface{ MatcherOpt(thing) }) ([]thing, interface{ MatcherOpt(thing) }, error) {
+	return m.Match(ctx, thing{hashKey: key.HashKey(), sortKey: key.SortKey()}, opts...)
+}
+
+func (m *matcher) Match(ctx context.Context, key thing, opts ...interface{ MatcherOpt(thing) }) ([]thing, interface{ MatcherOpt(thing) }, error) {
+	var cursor interface{ MatcherOpt(thing) }
+	at := 0
+	for _, opt := range opts {
+		if c, ok := opt.(pageCursor); ok {
+			cursor = c
+			at = len(c.sortKey)
+		}
+	}
+	m.cursors = append(m.cursors, cursor)
+
+	if m.err != nil && at == m.fail {
+		return nil, nil, m.err
+	}
+
+	if at+1 == len(m.pages) {
+		return m.pages[at], nil, nil
+	}
+
+	// cursor encodes index of the next page as length of sort key
+	next := pageCursor{thing{hashKey: key.hashKey, sortKey: curie.IRI(make([]byte, at+1))}}
+	return m.pages[at], next, nil
+}
+
+func things(seq ...curie.IRI) []thing {
+	out := make([]thing, len(seq))
+	for i, x := range seq {
+		out[i] = thing{hashKey: "a", sortKey: x}
+	}
+	return out
+}
+
+func collect(seq dynamo.Seq[thing], n int) ([]curie.IRI, error) {
+	var (
+		out  = make([]curie.IRI, 0)
+		fail error
+	)
+
+	seq(func(val thing, err error) bool {
+		if err != nil {
+			fail = err
+			return false
+		}
+		out = append(out, val.sortKey)
+		return n < 0 || len(out) < n
+	})
+
+	return out, fail
+}
+
+func TestIterate(t *testing.T) {
+	key := thing{hashKey: "a"}
+
+	t.Run("Pages", func(t *testing.T) {
+		db := &matcher{pages: [][]thing{things("1", "2"), things("3"), things("4", "5")}}
+
+		seq, err := collect(dynamo.Iterate[thing](context.Background(), db, key), -1)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(seq).Should().Equal([]curie.IRI{"1", "2", "3", "4", "5"}).
+			If(len(db.cursors)).Should().Equal(3)
+	})
+
+	t.Run("Cursor", func(t *testing.T) {
+		db := &matcher{pages: [][]thing{things("1"), things("2"), things("3")}}
+
+		seq, err := collect(dynamo.Iterate[thing](context.Background(), db, key), -1)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(seq).Should().Equal([]curie.IRI{"1", "2", "3"}).
+			If(db.cursors[0]).Should().Equal(nil).
+			If(db.cursors[1].(pageCursor).sortKey).Should().Equal(curie.IRI(make([]byte, 1))).
+			If(db.cursors[2].(pageCursor).sortKey).Should().Equal(curie.IRI(make([]byte, 2)))
+	})
+
+	t.Run("StartCursor", func(t *testing.T) {
+		db := &matcher{pages: [][]thing{things("1"), things("2"), things("3")}}
+		cursor := pageCursor{thing{hashKey: "a", sortKey: curie.IRI(make([]byte, 1))}}
+
+		seq, err := collect(dynamo.Iterate[thing](context.Background(), db, key, cursor), -1)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(seq).Should().Equal([]curie.IRI{"2", "3"}).
+			If(db.cursors[0]).Should().Equal(cursor)
+	})
+
+	t.Run("Reiterate", func(t *testing.T) {
+		db := &matcher{pages: [][]thing{things("1", "2"), things("3", "4"), things("5")}}
+		iter := dynamo.Iterate[thing](context.Background(), db, key, dynamo.Limit[thing](2))
+
+		seqA, errA := collect(iter, -1)
+		seqB, errB := collect(iter, -1)
+		it.Ok(t).
+			If(errA).Should().Equal(nil).
+			If(errB).Should().Equal(nil).
+			If(seqA).Should().Equal([]curie.IRI{"1", "2", "3", "4", "5"}).
+			If(seqB).Should().Equal(seqA).
+			If(len(db.cursors)).Should().Equal(6)
+	})
+
+	t.Run("EarlyTermination", func(t *testing.T) {
+		db := &matcher{pages: [][]thing{things("1", "2"), things("3"), things("4")}}
+
+		seq, err := collect(dynamo.Iterate[thing](context.Background(), db, key), 1)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(seq).Should().Equal([]curie.IRI{"1"}).
+			If(len(db.cursors)).Should().Equal(1)
+	})
+
+	t.Run("MaxItems", func(t *testing.T) {
+		db := &matcher{pages: [][]thing{things("1", "2"), things("3", "4"), things("5")}}
+
+		seq, err := collect(dynamo.Iterate[thing](context.Background(), db, key, dynamo.MaxItems[thing](3)), -1)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(seq).Should().Equal([]curie.IRI{"1", "2", "3"}).
+			If(len(db.cursors)).Should().Equal(2)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		fail := errors.New("fail")
+		db := &matcher{pages: [][]thing{things("1"), things("2"), things("3")}, fail: 1, err: fail}
+
+		seq, err := collect(dynamo.Iterate[thing](context.Background(), db, key), -1)
+		it.Ok(t).
+			If(err).Should().Equal(fail).
+			If(seq).Should().Equal([]curie.IRI{"1"}).
+			If(len(db.cursors)).Should().Equal(2)
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		db := &matcher{pages: [][]thing{things("1", "2"), things("3")}}
+
+		var (
+			seq  = make([]curie.IRI, 0)
+			fail error
+		)
+		dynamo.Iterate[thing](ctx, db, key)(func(val thing, err error) bool {
+			if err != nil {
+				fail = err
+				return false
+			}
+			seq = append(seq, val.sortKey)
+			cancel()
+			return true
+		})
+
+		it.Ok(t).
+			If(fail).Should().Equal(context.Canceled).
+			If(seq).Should().Equal([]curie.IRI{"1"}).
+			If(len(db.cursors)).Should().Equal(1)
+	})
+}
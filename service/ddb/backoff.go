@@ -0,0 +1,50 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package ddb
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// backoff is exponential retry policy with jitter, it is used to re-request
+// items that are not processed by batch operations
+type backoff struct {
+	attempts int
+	delay    time.Duration
+	maxDelay time.Duration
+}
+
+// Wait suspends execution before the attempt.
+// It returns false if no more attempts are allowed.
+func (b backoff) Wait(ctx context.Context, attempt int) (bool, error) {
+	if attempt >= b.attempts {
+		return false, nil
+	}
+
+	delay := b.delay << attempt
+	if delay <= 0 || delay > b.maxDelay {
+		delay = b.maxDelay
+	}
+
+	if delay > 0 {
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-timer.C:
+		return true, nil
+	}
+}
@@ -197,13 +197,23 @@ func Encode(val interface{}, coder ...Coder) (types.AttributeValue, error) {
 type codec[T dynamo.Thing] struct {
 	pkPrefix  string
 	skSuffix  string
+	primary   []string // primary key of the table
 	undefined T
 }
 
 func newCodec[T dynamo.Thing](conf *Options) *codec[T] {
+	primary := conf.primaryKey
+	if primary == nil {
+		primary = []string{conf.hashKey, conf.sortKey}
+		if conf.index != "" {
+			primary = []string{defaultOptions().hashKey, defaultOptions().sortKey}
+		}
+	}
+
 	return &codec[T]{
 		pkPrefix: conf.hashKey,
 		skSuffix: conf.sortKey,
+		primary:  primary,
 	}
 }
 
@@ -226,6 +236,27 @@ func (codec codec[T]) EncodeKey(key dynamo.Thing) (map[string]types.AttributeVal
 	return gen, nil
 }
 
+// DecodeKey extracts identity of the item from dynamo representation
+func (codec codec[T]) DecodeKey(gen map[string]types.AttributeValue) dynamo.Thing {
+	var hkey, skey string
+
+	if prefix, isPrefix := gen[codec.pkPrefix]; isPrefix {
+		switch v := prefix.(type) {
+		case *types.AttributeValueMemberS:
+			hkey = v.Value
+		}
+	}
+
+	if suffix, isSuffix := gen[codec.skSuffix]; isSuffix {
+		switch v := suffix.(type) {
+		case *types.AttributeValueMemberS:
+			skey = v.Value
+		}
+	}
+
+	return &cursor{hashKey: hkey, sortKey: skey}
+}
+
 // KeyOnly extracts key value from generic representation
 func (codec codec[T]) KeyOnly(gen map[string]types.AttributeValue) map[string]types.AttributeValue {
 	key := map[string]types.AttributeValue{}
@@ -235,6 +266,22 @@ func (codec codec[T]) KeyOnly(gen map[string]types.AttributeValue) map[string]ty
 	return key
 }
 
+// LastKey extracts position of the item in the table or index from generic
+// representation. The position consists of index and table primary keys,
+// nil is returned if any of key attributes is missing.
+func (codec codec[T]) LastKey(gen map[string]types.AttributeValue) map[string]types.AttributeValue {
+	key := map[string]types.AttributeValue{}
+	for _, attr := range append([]string{codec.pkPrefix, codec.skSuffix}, codec.primary...) {
+		val, has := gen[attr]
+		if !has {
+			return nil
+		}
+		key[attr] = val
+	}
+
+	return key
+}
+
 // Encode object to dynamo representation
 func (codec codec[T]) Encode(entity T) (map[string]types.AttributeValue, error) {
 	gen, err := attributevalue.MarshalMap(entity)
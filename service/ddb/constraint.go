@@ -13,6 +13,7 @@
 package ddb
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -65,10 +66,17 @@ func ClauseFor[T dynamo.Thing, A any](attr ...string) ConditionExpression[T, A]
 		seq = hseq.New[T](attr[0])
 	}
 
-	return hseq.FMap1(seq, newConditionExpression[T, A])
+	ce := hseq.FMap1(seq, newConditionExpression[T, A])
+	if len(attr) > 1 {
+		path, leaf, _ := nestedPathOf(seq[0].Type, attr[1:])
+		ce.key = append(ce.key, path...)
+		leafOf[T, A](ce.key, leaf)
+	}
+
+	return ce
 }
 
-type ConditionExpression[T dynamo.Thing, A any] struct{ key string }
+type ConditionExpression[T dynamo.Thing, A any] struct{ key documentPath }
 
 func newConditionExpression[T dynamo.Thing, A any](t hseq.Type[T]) ConditionExpression[T, A] {
 	tag := t.Tag.Get("dynamodbav")
@@ -76,7 +84,7 @@ func newConditionExpression[T dynamo.Thing, A any](t hseq.Type[T]) ConditionExpr
 		panic(fmt.Errorf("field %s of type %T do not have `dynamodbav` tag", t.Name, *new(T)))
 	}
 
-	return ConditionExpression[T, A]{strings.Split(tag, ",")[0]}
+	return ConditionExpression[T, A]{attributePath(strings.Split(tag, ",")[0])}
 }
 
 // Internal implementation of Constrain effects for storage
@@ -127,37 +135,34 @@ func (ce ConditionExpression[T, A]) Ge(val A) interface{ WriterOpt(T) } {
 // dyadic condition implementation
 type dyadicCondition[T any, A any] struct {
 	op  string
-	key string
+	key documentPath
 	val A
 }
 
-func (op dyadicCondition[T, A]) WriterOpt(T) {}
+func (op dyadicCondition[T, A]) WriterOpt(T)  {}
+func (op dyadicCondition[T, A]) MatcherOpt(T) {}
 
 func (op dyadicCondition[T, A]) Apply(
 	conditionExpression **string,
 	expressionAttributeNames map[string]string,
 	expressionAttributeValues map[string]types.AttributeValue,
-) {
-	if op.key == "" {
-		return
+) error {
+	if len(op.key) == 0 {
+		return errUndefinedAttribute
 	}
 
 	lit, err := attributevalue.Marshal(op.val)
 	if err != nil {
-		return
+		return fmt.Errorf("%s: %w", op.key, err)
 	}
 
-	key := "#__c_" + op.key + "__"
-	let := ":__c_" + op.key + "__"
+	key := op.key.expr("c_", expressionAttributeNames)
+	let := uniqueValueOf(expressionAttributeValues, "c_", op.key.slug())
 	expressionAttributeValues[let] = lit
-	expressionAttributeNames[key] = op.key
 	expr := "(" + key + " " + op.op + " " + let + ")"
 
-	if *conditionExpression == nil {
-		*conditionExpression = aws.String(expr)
-	} else {
-		*conditionExpression = aws.String(**conditionExpression + " and " + expr)
-	}
+	joinConditionExpression(conditionExpression, " and ", expr)
+	return nil
 }
 
 // Exists attribute constrain
@@ -177,29 +182,26 @@ func (ce ConditionExpression[T, A]) NotExists() interface{ WriterOpt(T) } {
 // unary condition implementation
 type unaryCondition[T any] struct {
 	op  string
-	key string
+	key documentPath
 }
 
-func (op unaryCondition[T]) WriterOpt(T) {}
+func (op unaryCondition[T]) WriterOpt(T)  {}
+func (op unaryCondition[T]) MatcherOpt(T) {}
 
 func (op unaryCondition[T]) Apply(
 	conditionExpression **string,
 	expressionAttributeNames map[string]string,
 	expressionAttributeValues map[string]types.AttributeValue,
-) {
-	if op.key == "" {
-		return
+) error {
+	if len(op.key) == 0 {
+		return errUndefinedAttribute
 	}
 
-	key := "#__c_" + op.key + "__"
-	expressionAttributeNames[key] = op.key
+	key := op.key.expr("c_", expressionAttributeNames)
 	expr := "(" + op.op + "(" + key + ")" + ")"
 
-	if *conditionExpression == nil {
-		*conditionExpression = aws.String(expr)
-	} else {
-		*conditionExpression = aws.String(**conditionExpression + " and " + expr)
-	}
+	joinConditionExpression(conditionExpression, " and ", expr)
+	return nil
 }
 
 // Is matches either Eq or NotExists if value is not defined
@@ -220,44 +222,41 @@ func (ce ConditionExpression[T, A]) Between(a, b A) interface{ WriterOpt(T) } {
 
 // between condition implementation
 type betweenCondition[T any, A any] struct {
-	key  string
+	key  documentPath
 	a, b A
 }
 
-func (op betweenCondition[T, A]) WriterOpt(T) {}
+func (op betweenCondition[T, A]) WriterOpt(T)  {}
+func (op betweenCondition[T, A]) MatcherOpt(T) {}
 
 func (op betweenCondition[T, A]) Apply(
 	conditionExpression **string,
 	expressionAttributeNames map[string]string,
 	expressionAttributeValues map[string]types.AttributeValue,
-) {
-	if op.key == "" {
-		return
+) error {
+	if len(op.key) == 0 {
+		return errUndefinedAttribute
 	}
 
 	litA, err := attributevalue.Marshal(op.a)
 	if err != nil {
-		return
+		return fmt.Errorf("%s: %w", op.key, err)
 	}
 
 	litB, err := attributevalue.Marshal(op.b)
 	if err != nil {
-		return
+		return fmt.Errorf("%s: %w", op.key, err)
 	}
 
-	key := "#__c_" + op.key + "__"
-	letA := ":__c_" + op.key + "_a__"
-	letB := ":__c_" + op.key + "_b__"
+	key := op.key.expr("c_", expressionAttributeNames)
+	letA := uniqueValueOf(expressionAttributeValues, "c_", op.key.slug()+"_a")
 	expressionAttributeValues[letA] = litA
+	letB := uniqueValueOf(expressionAttributeValues, "c_", op.key.slug()+"_b")
 	expressionAttributeValues[letB] = litB
-	expressionAttributeNames[key] = op.key
 	expr := "(" + key + " BETWEEN " + letA + " AND " + letB + ")"
 
-	if *conditionExpression == nil {
-		*conditionExpression = aws.String(expr)
-	} else {
-		*conditionExpression = aws.String(**conditionExpression + " and " + expr)
-	}
+	joinConditionExpression(conditionExpression, " and ", expr)
+	return nil
 }
 
 // In attribute condition
@@ -269,43 +268,47 @@ func (ce ConditionExpression[T, A]) In(seq ...A) interface{ WriterOpt(T) } {
 
 // between condition implementation
 type inCondition[T any, A any] struct {
-	key string
+	key documentPath
 	seq []A
 }
 
-func (op inCondition[T, A]) WriterOpt(T) {}
+func (op inCondition[T, A]) WriterOpt(T)  {}
+func (op inCondition[T, A]) MatcherOpt(T) {}
 
 func (op inCondition[T, A]) Apply(
 	conditionExpression **string,
 	expressionAttributeNames map[string]string,
 	expressionAttributeValues map[string]types.AttributeValue,
-) {
-	if op.key == "" {
-		return
+) error {
+	if len(op.key) == 0 {
+		return errUndefinedAttribute
 	}
 
-	key := "#__c_" + op.key + "__"
-	expressionAttributeNames[key] = op.key
+	if len(op.seq) == 0 {
+		return fmt.Errorf("%s: IN requires at least one value", op.key)
+	}
 
 	lits := make([]types.AttributeValue, len(op.seq))
-	lets := make([]string, len((op.seq)))
 	for i := 0; i < len(op.seq); i++ {
 		lit, err := attributevalue.Marshal(op.seq[i])
 		if err != nil {
-			return
+			return fmt.Errorf("%s: %w", op.key, err)
 		}
 		lits[i] = lit
-		lets[i] = ":__c_" + op.key + "_" + strconv.Itoa(i) + "__"
+	}
+
+	key := op.key.expr("c_", expressionAttributeNames)
+
+	lets := make([]string, len((op.seq)))
+	for i := 0; i < len(op.seq); i++ {
+		lets[i] = uniqueValueOf(expressionAttributeValues, "c_", op.key.slug()+"_"+strconv.Itoa(i))
 		expressionAttributeValues[lets[i]] = lits[i]
 	}
 
 	expr := "(" + key + " IN (" + strings.Join(lets, ",") + "))"
 
-	if *conditionExpression == nil {
-		*conditionExpression = aws.String(expr)
-	} else {
-		*conditionExpression = aws.String(**conditionExpression + " and " + expr)
-	}
+	joinConditionExpression(conditionExpression, " and ", expr)
+	return nil
 }
 
 // HasPrefix attribute condition
@@ -325,36 +328,140 @@ func (ce ConditionExpression[T, A]) Contains(val A) interface{ WriterOpt(T) } {
 // functional condition implementation
 type functionalCondition[T any, A any] struct {
 	fun string
-	key string
+	key documentPath
 	val A
 }
 
-func (op functionalCondition[T, A]) WriterOpt(T) {}
+func (op functionalCondition[T, A]) WriterOpt(T)  {}
+func (op functionalCondition[T, A]) MatcherOpt(T) {}
 
 func (op functionalCondition[T, A]) Apply(
 	conditionExpression **string,
 	expressionAttributeNames map[string]string,
 	expressionAttributeValues map[string]types.AttributeValue,
-) {
-	if op.key == "" {
-		return
+) error {
+	if len(op.key) == 0 {
+		return errUndefinedAttribute
 	}
 
 	lit, err := attributevalue.Marshal(op.val)
 	if err != nil {
-		return
+		return fmt.Errorf("%s: %w", op.key, err)
 	}
 
-	key := "#__c_" + op.key + "__"
-	let := ":__c_" + op.key + "__"
+	key := op.key.expr("c_", expressionAttributeNames)
+	let := uniqueValueOf(expressionAttributeValues, "c_", op.key.slug())
 	expressionAttributeValues[let] = lit
-	expressionAttributeNames[key] = op.key
 	expr := "(" + op.fun + "(" + key + "," + let + "))"
 
+	joinConditionExpression(conditionExpression, " and ", expr)
+	return nil
+}
+
+// Or combines conditions with logical disjunction, the expression is true
+// if any of conditions is true.
+//
+//	ddb.Or(status.Eq("draft"), status.NotExists()) ⟼ ((#__c_status__ = :__c_status__) OR (attribute_not_exists(#__c_status__)))
+func Or[T dynamo.Thing](seq ...interface{ WriterOpt(T) }) interface{ WriterOpt(T) } {
+	return &logicalCondition[T]{op: " OR ", seq: seq}
+}
+
+// And combines conditions with logical conjunction, the expression is true
+// if all of conditions are true.
+//
+//	ddb.And(name.Exists(), age.Gt(18)) ⟼ ((attribute_exists(#__c_name__)) AND (#__c_age__ > :__c_age__))
+//
+// Multiple options are implicitly combined with conjunction, the combinator
+// is required to nest conjunction into Or and Not.
+func And[T dynamo.Thing](seq ...interface{ WriterOpt(T) }) interface{ WriterOpt(T) } {
+	return &logicalCondition[T]{op: " AND ", seq: seq}
+}
+
+// Not negates the condition
+//
+//	ddb.Not(name.Eq("x")) ⟼ (NOT (#__c_name__ = :__c_name__))
+func Not[T dynamo.Thing](cond interface{ WriterOpt(T) }) interface{ WriterOpt(T) } {
+	return &logicalCondition[T]{op: "NOT ", seq: []interface{ WriterOpt(T) }{cond}}
+}
+
+// logical condition implementation
+type logicalCondition[T any] struct {
+	op  string
+	seq []interface{ WriterOpt(T) }
+}
+
+func (op logicalCondition[T]) WriterOpt(T)  {}
+func (op logicalCondition[T]) MatcherOpt(T) {}
+
+func (op logicalCondition[T]) Apply(
+	conditionExpression **string,
+	expressionAttributeNames map[string]string,
+	expressionAttributeValues map[string]types.AttributeValue,
+) error {
+	terms := make([]string, 0, len(op.seq))
+	errs := make([]error, 0)
+	for _, cond := range op.seq {
+		ap, ok := cond.(interface {
+			Apply(**string, map[string]string, map[string]types.AttributeValue) error
+		})
+		if !ok {
+			errs = append(errs, fmt.Errorf("%T is not a condition expression", cond))
+			continue
+		}
+
+		var term *string
+		if err := ap.Apply(&term, expressionAttributeNames, expressionAttributeValues); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if term != nil {
+			terms = append(terms, *term)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var expr string
+	switch {
+	case op.op == "NOT ":
+		expr = "(NOT " + terms[0] + ")"
+	default:
+		expr = "(" + strings.Join(terms, op.op) + ")"
+	}
+
+	joinConditionExpression(conditionExpression, " and ", expr)
+	return nil
+}
+
+// errUndefinedAttribute is an error of expression declared without attribute
+// (e.g. zero value of ConditionExpression)
+var errUndefinedAttribute = errors.New("attribute of expression is not defined")
+
+// joins expression with existing condition expression
+func joinConditionExpression(conditionExpression **string, op string, expr string) {
 	if *conditionExpression == nil {
 		*conditionExpression = aws.String(expr)
 	} else {
-		*conditionExpression = aws.String(**conditionExpression + " and " + expr)
+		*conditionExpression = aws.String(**conditionExpression + op + expr)
+	}
+}
+
+// uniqueValueOf returns placeholder for the value of attribute, which is not
+// used by the expression yet. Same attribute might be used by multiple
+// conditions (e.g. a > :a AND a < :b).
+func uniqueValueOf(expressionAttributeValues map[string]types.AttributeValue, prefix, key string) string {
+	let := ":__" + prefix + key + "__"
+	for i := 1; ; i++ {
+		if _, has := expressionAttributeValues[let]; !has {
+			return let
+		}
+		let = ":__" + prefix + key + "_" + strconv.Itoa(i) + "__"
 	}
 }
 
@@ -367,19 +474,28 @@ func maybeConditionExpression[T dynamo.Thing](
 ) (
 	expressionAttributeNames map[string]string,
 	expressionAttributeValues map[string]types.AttributeValue,
+	err error,
 ) {
 	if len(opts) > 0 {
 		expressionAttributeNames = map[string]string{}
 		expressionAttributeValues = map[string]types.AttributeValue{}
 
+		errs := make([]error, 0)
 		for _, opt := range opts {
 			if ap, ok := opt.(interface {
-				Apply(**string, map[string]string, map[string]types.AttributeValue)
+				Apply(**string, map[string]string, map[string]types.AttributeValue) error
 			}); ok {
-				ap.Apply(conditionExpression, expressionAttributeNames, expressionAttributeValues)
+				if err := ap.Apply(conditionExpression, expressionAttributeNames, expressionAttributeValues); err != nil {
+					errs = append(errs, err)
+				}
 			}
 		}
 
+		if len(errs) > 0 {
+			err = errInvalidExpression(errs...)
+			return
+		}
+
 		// Unfortunately empty maps are not accepted by DynamoDB
 		if len(expressionAttributeNames) == 0 {
 			expressionAttributeNames = nil
@@ -400,12 +516,21 @@ func maybeUpdateConditionExpression[T dynamo.Thing](
 	expressionAttributeNames map[string]string,
 	expressionAttributeValues map[string]types.AttributeValue,
 	opts []interface{ WriterOpt(T) },
-) {
+) error {
+	errs := make([]error, 0)
 	for _, opt := range opts {
 		if ap, ok := opt.(interface {
-			Apply(**string, map[string]string, map[string]types.AttributeValue)
+			Apply(**string, map[string]string, map[string]types.AttributeValue) error
 		}); ok {
-			ap.Apply(conditionExpression, expressionAttributeNames, expressionAttributeValues)
+			if err := ap.Apply(conditionExpression, expressionAttributeNames, expressionAttributeValues); err != nil {
+				errs = append(errs, err)
+			}
 		}
 	}
+
+	if len(errs) > 0 {
+		return errInvalidExpression(errs...)
+	}
+
+	return nil
 }
@@ -46,7 +46,7 @@ func TestConditionExpression(t *testing.T) {
 	for op, fn := range spec {
 		expr = nil
 		opts := []interface{ WriterOpt(tConstrain) }{fn("abc")}
-		name, vals := maybeConditionExpression(&expr, opts)
+		name, vals, _ := maybeConditionExpression(&expr, opts)
 
 		expectExpr := fmt.Sprintf("(#__c_anothername__ %s :__c_anothername__)", op)
 		expectName := "anothername"
@@ -65,7 +65,7 @@ func TestExists(t *testing.T) {
 	)
 
 	opts := []interface{ WriterOpt(tConstrain) }{Name.Exists()}
-	name, vals := maybeConditionExpression(&expr, opts)
+	name, vals, _ := maybeConditionExpression(&expr, opts)
 
 	expectExpr := "(attribute_exists(#__c_anothername__))"
 	expectName := map[string]string{"#__c_anothername__": "anothername"}
@@ -82,7 +82,7 @@ func TestNotExists(t *testing.T) {
 	)
 
 	opts := []interface{ WriterOpt(tConstrain) }{Name.NotExists()}
-	name, vals := maybeConditionExpression(&expr, opts)
+	name, vals, _ := maybeConditionExpression(&expr, opts)
 
 	expectExpr := "(attribute_not_exists(#__c_anothername__))"
 	expectName := map[string]string{"#__c_anothername__": "anothername"}
@@ -99,7 +99,7 @@ func TestBetween(t *testing.T) {
 	)
 
 	opts := []interface{ WriterOpt(tConstrain) }{Name.Between("abc", "def")}
-	name, vals := maybeConditionExpression(&expr, opts)
+	name, vals, _ := maybeConditionExpression(&expr, opts)
 
 	expectExpr := "(#__c_anothername__ BETWEEN :__c_anothername_a__ AND :__c_anothername_b__)"
 	expectName := map[string]string{"#__c_anothername__": "anothername"}
@@ -120,7 +120,7 @@ func TestIn(t *testing.T) {
 	)
 
 	opts := []interface{ WriterOpt(tConstrain) }{Name.In("abc", "def", "foo")}
-	name, vals := maybeConditionExpression(&expr, opts)
+	name, vals, _ := maybeConditionExpression(&expr, opts)
 
 	expectExpr := "(#__c_anothername__ IN (:__c_anothername_0__,:__c_anothername_1__,:__c_anothername_2__))"
 	expectName := map[string]string{"#__c_anothername__": "anothername"}
@@ -142,7 +142,7 @@ func TestHasPrefix(t *testing.T) {
 	)
 
 	opts := []interface{ WriterOpt(tConstrain) }{Name.HasPrefix("abc")}
-	name, vals := maybeConditionExpression(&expr, opts)
+	name, vals, _ := maybeConditionExpression(&expr, opts)
 
 	expectExpr := "(begins_with(#__c_anothername__,:__c_anothername__))"
 	expectName := map[string]string{"#__c_anothername__": "anothername"}
@@ -162,7 +162,7 @@ func TestContains(t *testing.T) {
 	)
 
 	opts := []interface{ WriterOpt(tConstrain) }{Name.Contains("abc")}
-	name, vals := maybeConditionExpression(&expr, opts)
+	name, vals, _ := maybeConditionExpression(&expr, opts)
 
 	expectExpr := "(contains(#__c_anothername__,:__c_anothername__))"
 	expectName := map[string]string{"#__c_anothername__": "anothername"}
@@ -182,7 +182,7 @@ func TestIs(t *testing.T) {
 	)
 
 	opts := []interface{ WriterOpt(tConstrain) }{Name.Is("_")}
-	name, vals := maybeConditionExpression(&expr, opts)
+	name, vals, _ := maybeConditionExpression(&expr, opts)
 
 	expectExpr := "(attribute_not_exists(#__c_anothername__))"
 	expectName := map[string]string{"#__c_anothername__": "anothername"}
@@ -195,7 +195,7 @@ func TestIs(t *testing.T) {
 	//
 	expr = nil
 	opts = []interface{ WriterOpt(tConstrain) }{Name.Is("abc")}
-	name, vals = maybeConditionExpression(&expr, opts)
+	name, vals, _ = maybeConditionExpression(&expr, opts)
 
 	expectExpr = "(#__c_anothername__ = :__c_anothername__)"
 	expectVals := map[string]types.AttributeValue{
@@ -207,3 +207,69 @@ func TestIs(t *testing.T) {
 		If(vals).Should().Equal(expectVals).
 		If(name).Should().Equal(expectName)
 }
+
+func TestLogicalCondition(t *testing.T) {
+	var expr *string
+
+	opts := []interface{ WriterOpt(tConstrain) }{
+		Or(Name.Eq("a"), And(Name.Ne("b"), Not(Name.Exists()))),
+		Name.In("c", "d"),
+	}
+	name, vals, _ := maybeConditionExpression(&expr, opts)
+
+	it.Ok(t).
+		If(*expr).Should().Equal("((#__c_anothername__ = :__c_anothername__) OR ((#__c_anothername__ <> :__c_anothername_1__) AND (NOT (attribute_exists(#__c_anothername__))))) and (#__c_anothername__ IN (:__c_anothername_0__,:__c_anothername_1_1__))").
+		If(name["#__c_anothername__"]).Should().Equal("anothername").
+		If(vals[":__c_anothername__"]).Should().Equal(&types.AttributeValueMemberS{Value: "a"}).
+		If(vals[":__c_anothername_1__"]).Should().Equal(&types.AttributeValueMemberS{Value: "b"}).
+		If(vals[":__c_anothername_0__"]).Should().Equal(&types.AttributeValueMemberS{Value: "c"}).
+		If(vals[":__c_anothername_1_1__"]).Should().Equal(&types.AttributeValueMemberS{Value: "d"})
+}
+
+func TestLogicalConditionInvalid(t *testing.T) {
+	var expr *string
+
+	opts := []interface{ WriterOpt(tConstrain) }{
+		Or(Name.Eq("a"), ReturnValues[tConstrain](types.ReturnValueAllOld)),
+	}
+	_, _, err := maybeConditionExpression(&expr, opts)
+
+	it.Ok(t).
+		IfNotNil(err).
+		If(expr == nil).Should().Equal(true)
+}
+
+type tNested struct {
+	Address struct {
+		City string `dynamodbav:"city,omitempty"`
+	} `dynamodbav:"address,omitempty"`
+	Labels   map[string]string `dynamodbav:"labels,omitempty"`
+	Tags     []string          `dynamodbav:"tags,omitempty"`
+	Counters map[string]int    `dynamodbav:"counters,omitempty"`
+}
+
+func (tNested) HashKey() curie.IRI { return "" }
+func (tNested) SortKey() curie.IRI { return "" }
+
+func TestNestedPathCondition(t *testing.T) {
+	var expr *string
+
+	opts := []interface{ WriterOpt(tNested) }{
+		ClauseFor[tNested, string]("Address", "City").Eq("Berne"),
+		ClauseFor[tNested, string]("Labels", "owner-id").Exists(),
+		ClauseFor[tNested, string]("Tags", "[1]").Ne("x"),
+	}
+	name, vals, _ := maybeConditionExpression(&expr, opts)
+
+	it.Ok(t).
+		If(*expr).Should().Equal("(#__c_address__.#__c_city__ = :__c_address_city__) and (attribute_exists(#__c_labels__.#__c_owner_id__)) and (#__c_tags__[1] <> :__c_tags_1__)").
+		If(name).Should().Equal(map[string]string{
+		"#__c_address__":  "address",
+		"#__c_city__":     "city",
+		"#__c_labels__":   "labels",
+		"#__c_owner_id__": "owner-id",
+		"#__c_tags__":     "tags",
+	}).
+		If(vals[":__c_address_city__"]).Should().Equal(&types.AttributeValueMemberS{Value: "Berne"}).
+		If(vals[":__c_tags_1__"]).Should().Equal(&types.AttributeValueMemberS{Value: "x"})
+}
@@ -18,12 +18,15 @@ import (
 
 // Storage type
 type Storage[T dynamo.Thing] struct {
-	service   DynamoDB
-	table     *string
-	index     *string
-	codec     *codec[T]
-	schema    *schema[T]
-	undefined T
+	service     DynamoDB
+	table       *string
+	index       *string
+	codec       *codec[T]
+	schema      *schema[T]
+	backoff     backoff
+	concurrency int
+	version     string
+	undefined   T
 }
 
 func Must[T dynamo.Thing](keyval *Storage[T], err error) *Storage[T] {
@@ -56,12 +59,20 @@ func New[T dynamo.Thing](opts ...Option) (*Storage[T], error) {
 		index = &conf.index
 	}
 
+	version, err := versionAttribute[T](conf.version)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Storage[T]{
-		service: aws,
-		table:   &table,
-		index:   index,
-		codec:   newCodec[T](conf),
-		schema:  newSchema[T](conf.useStrictType),
+		service:     aws,
+		table:       &table,
+		index:       index,
+		codec:       newCodec[T](conf),
+		schema:      newSchema[T](conf.useStrictType),
+		backoff:     conf.backoff,
+		concurrency: conf.concurrency,
+		version:     version,
 	}, nil
 }
 
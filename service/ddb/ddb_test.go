@@ -10,14 +10,18 @@ package ddb_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/fogfish/curie"
+	"github.com/fogfish/dynamo/v3"
 	"github.com/fogfish/dynamo/v3/internal/ddbtest"
 	"github.com/fogfish/dynamo/v3/internal/dynamotest"
 	"github.com/fogfish/dynamo/v3/service/ddb"
+	"github.com/fogfish/dynamo/v3/service/ddb/ddbfake"
 	"github.com/fogfish/it"
 )
 
@@ -144,3 +148,775 @@ func TestDdbUpdateWithExpression(t *testing.T) {
 	it.Ok(t).
 		If(success).Should().Equal(nil)
 }
+
+func TestDdbBatchPut(t *testing.T) {
+	seq := make([]person, 60)
+	for i := 0; i < len(seq); i++ {
+		seq[i] = entityStruct()
+		seq[i].Suffix = curie.New("%d", i)
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		keys := []map[string]types.AttributeValue{}
+		db := ddbtest.BatchWriteItem[person](2, &keys).(*ddb.Storage[person])
+
+		err := db.BatchPut(context.Background(), seq)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(len(keys)).Should().Equal(60)
+	})
+
+	t.Run("Unprocessed", func(t *testing.T) {
+		keys := []map[string]types.AttributeValue{}
+		db := ddbtest.BatchWriteItem[person](100, &keys).(*ddb.Storage[person])
+
+		err := db.BatchPut(context.Background(), seq)
+		e, ok := err.(interface{ Unprocessed() []dynamo.Thing })
+		it.Ok(t).
+			IfTrue(ok).
+			If(len(keys)).Should().Equal(57).
+			If(len(e.Unprocessed())).Should().Equal(3).
+			If(e.Unprocessed()[0].SortKey()).Should().Equal(curie.IRI("24"))
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		db, err := ddb.New[person](
+			ddb.WithTable("test"),
+			ddb.WithService(&failingBatchWrite{ok: 1}),
+		)
+		it.Ok(t).If(err).Should().Equal(nil)
+
+		err = db.BatchPut(context.Background(), seq)
+		e, ok := err.(interface{ Unprocessed() []dynamo.Thing })
+		it.Ok(t).
+			IfTrue(ok).
+			If(len(e.Unprocessed())).Should().Equal(35).
+			If(e.Unprocessed()[0].SortKey()).Should().Equal(curie.IRI("25"))
+	})
+
+	t.Run("Condition", func(t *testing.T) {
+		keys := []map[string]types.AttributeValue{}
+		db := ddbtest.BatchWriteItem[person](0, &keys).(*ddb.Storage[person])
+
+		err := db.BatchPut(context.Background(), seq, ddb.ClauseFor[person, string]("Name").NotExists())
+		it.Ok(t).
+			IfNotNil(err).
+			If(len(keys)).Should().Equal(0)
+	})
+}
+
+// failingBatchWrite fails batch write after ok requests
+type failingBatchWrite struct {
+	ddb.DynamoDB
+	ok int
+}
+
+func (mock *failingBatchWrite) BatchWriteItem(context.Context, *dynamodb.BatchWriteItemInput, ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	if mock.ok == 0 {
+		return nil, fmt.Errorf("service unavailable")
+	}
+	mock.ok--
+	return &dynamodb.BatchWriteItemOutput{}, nil
+}
+
+func TestDdbBatchRemove(t *testing.T) {
+	seq := make([]person, 30)
+	for i := 0; i < len(seq); i++ {
+		seq[i] = person{Prefix: curie.New("dead:beef"), Suffix: curie.New("%d", i)}
+	}
+
+	keys := []map[string]types.AttributeValue{}
+	db := ddbtest.BatchWriteItem[person](1, &keys).(*ddb.Storage[person])
+
+	err := db.BatchRemove(context.Background(), seq)
+	it.Ok(t).
+		If(err).Should().Equal(nil).
+		If(len(keys)).Should().Equal(30).
+		If(keys[0]).Should().Equal(map[string]types.AttributeValue{
+		"prefix": &types.AttributeValueMemberS{Value: "dead:beef"},
+		"suffix": &types.AttributeValueMemberS{Value: "0"},
+	})
+}
+
+func TestDdbBatchGet(t *testing.T) {
+	keys := make([]person, 250)
+	vals := make([]map[string]types.AttributeValue, 0)
+	for i := 0; i < len(keys); i++ {
+		keys[i] = person{Prefix: curie.New("dead:beef"), Suffix: curie.New("%d", i)}
+		if i%5 != 0 {
+			val := entityDynamo()
+			val["suffix"] = &types.AttributeValueMemberS{Value: string(keys[i].Suffix)}
+			vals = append(vals, val)
+		}
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		db := ddbtest.BatchGetItem[person](2, vals).(*ddb.Storage[person])
+
+		seq, err := db.BatchGet(context.Background(), keys)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(len(seq)).Should().Equal(200)
+
+		for i, val := range seq {
+			it.Ok(t).If(val.Suffix).Should().Equal(curie.New("%d", i+i/4+1))
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		db := ddbtest.BatchGetItem[person](0, vals).(*ddb.Storage[person])
+
+		seq, err := db.BatchGet(context.Background(), keys, ddb.ReportNotFound[person]())
+		e, ok := err.(interface{ NotFoundKeys() []dynamo.Thing })
+		it.Ok(t).
+			IfTrue(ok).
+			If(len(seq)).Should().Equal(200).
+			If(len(e.NotFoundKeys())).Should().Equal(50).
+			If(e.NotFoundKeys()[1].SortKey()).Should().Equal(curie.IRI("5"))
+	})
+
+	t.Run("Unprocessed", func(t *testing.T) {
+		db := ddbtest.BatchGetItem[person](100, vals).(*ddb.Storage[person])
+
+		_, err := db.BatchGet(context.Background(), keys)
+		e, ok := err.(interface{ Unprocessed() []dynamo.Thing })
+		it.Ok(t).
+			IfTrue(ok).
+			If(len(e.Unprocessed())).Should().Equal(3)
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		db := ddbtest.BatchGetItem[person](0, vals).(*ddb.Storage[person])
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		seq, err := db.BatchGet(ctx, keys)
+		it.Ok(t).
+			IfNotNil(err).
+			If(len(seq)).Should().Equal(0)
+	})
+}
+
+type keyword struct {
+	Prefix curie.IRI `dynamodbav:"prefix,omitempty"`
+	Suffix curie.IRI `dynamodbav:"suffix,omitempty"`
+	Text   string    `dynamodbav:"text,omitempty"`
+}
+
+func (k keyword) HashKey() curie.IRI { return k.Prefix }
+func (k keyword) SortKey() curie.IRI { return k.Suffix }
+
+func TestDdbTransactWrite(t *testing.T) {
+	name := ddb.ClauseFor[person, string]("Name")
+	age := ddb.UpdateFor[person, int]("Age")
+	text := ddb.ClauseFor[keyword, string]("Text")
+
+	keyA := keyword{Prefix: "keyword:theory", Suffix: "article:neumann/automata", Text: "automata"}
+	keyB := keyword{Prefix: "article:neumann/automata", Suffix: "keyword:theory", Text: "theory"}
+
+	txs := func(mock ddb.DynamoDB) []ddb.TxWriter {
+		dbp := ddb.Must(ddb.New[person](ddb.WithTable("person"), ddb.WithService(mock)))
+		dbk := ddb.Must(ddb.New[keyword](ddb.WithTable("keyword"), ddb.WithService(mock)))
+
+		return []ddb.TxWriter{
+			dbk.TxPut(keyA, text.NotExists()),
+			dbk.TxPut(keyB),
+			dbp.TxUpdate(entityStruct(), name.Eq("Verner Pleishner")),
+			dbp.TxUpdateWith(ddb.Updater(entityStruct(), age.Inc(1))),
+			dbk.TxRemove(keyword{Prefix: "keyword:theory", Suffix: "article:neumann/other"}),
+			dbp.TxCheck(entityStruct(), name.Exists()),
+		}
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		items := []types.TransactWriteItem{}
+		err := ddb.TransactWrite(context.Background(),
+			txs(ddbtest.TransactWriteItems(-1, &items))...,
+		)
+
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(len(items)).Should().Equal(6).
+			If(*items[0].Put.TableName).Should().Equal("keyword").
+			If(*items[0].Put.ConditionExpression).Should().Equal("(attribute_not_exists(#__c_text__))").
+			IfNil(items[1].Put.ConditionExpression).
+			If(*items[2].Update.TableName).Should().Equal("person").
+			If(*items[2].Update.ConditionExpression).Should().Equal("(#__c_name__ = :__c_name__)").
+			If(*items[3].Update.UpdateExpression).Should().Equal("SET #__age__ = #__age__ + :__age__").
+			If(*items[4].Delete.TableName).Should().Equal("keyword").
+			If(*items[5].ConditionCheck.ConditionExpression).Should().Equal("(attribute_exists(#__c_name__))")
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		items := []types.TransactWriteItem{}
+		err := ddb.TransactWrite(context.Background(),
+			txs(ddbtest.TransactWriteItems(2, &items))...,
+		)
+
+		e, ok := err.(interface{ PreConditionFailed() bool })
+		k, _ := err.(dynamo.Thing)
+		it.Ok(t).
+			IfTrue(ok).
+			IfTrue(e.PreConditionFailed()).
+			If(k.HashKey()).Should().Equal(curie.IRI("dead:beef")).
+			If(len(items)).Should().Equal(0)
+	})
+
+	t.Run("CheckWithoutCondition", func(t *testing.T) {
+		items := []types.TransactWriteItem{}
+		dbp := ddb.Must(ddb.New[person](ddb.WithTable("person"), ddb.WithService(ddbtest.TransactWriteItems(-1, &items))))
+
+		err := ddb.TransactWrite(context.Background(), dbp.TxCheck(entityStruct()))
+		it.Ok(t).
+			IfNotNil(err).
+			If(len(items)).Should().Equal(0)
+	})
+
+	t.Run("ReturnValues", func(t *testing.T) {
+		items := []types.TransactWriteItem{}
+		dbp := ddb.Must(ddb.New[person](ddb.WithTable("person"), ddb.WithService(ddbtest.TransactWriteItems(-1, &items))))
+
+		for _, tx := range []ddb.TxWriter{
+			dbp.TxPut(entityStruct(), ddb.ReturnValues[person](types.ReturnValueAllOld)),
+			dbp.TxUpdate(entityStruct(), ddb.ReturnValues[person](types.ReturnValueAllNew)),
+			dbp.TxUpdateWith(ddb.Updater(entityStruct(), age.Inc(1)), ddb.ReturnValues[person](types.ReturnValueUpdatedNew)),
+			dbp.TxRemove(entityStruct(), ddb.ReturnValues[person](types.ReturnValueAllOld)),
+		} {
+			it.Ok(t).IfNotNil(ddb.TransactWrite(context.Background(), tx))
+		}
+
+		err := ddb.TransactWrite(context.Background(),
+			dbp.TxRemove(entityStruct(), ddb.ReturnValuesOnConditionCheckFailure[person](types.ReturnValuesOnConditionCheckFailureAllOld)),
+		)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(len(items)).Should().Equal(1)
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		items := []types.TransactWriteItem{}
+		dbk := ddb.Must(ddb.New[keyword](ddb.WithTable("keyword"), ddb.WithService(ddbtest.TransactWriteItems(-1, &items))))
+
+		seq := make([]ddb.TxWriter, 101)
+		for i := 0; i < len(seq); i++ {
+			seq[i] = dbk.TxPut(keyword{Prefix: "keyword:theory", Suffix: curie.New("article:%d", i)})
+		}
+
+		err := ddb.TransactWrite(context.Background(), seq...)
+		it.Ok(t).
+			IfNotNil(err).
+			If(len(items)).Should().Equal(0)
+	})
+}
+
+func TestDdbTransactGet(t *testing.T) {
+	mock := ddbtest.TransactGetItems([]map[string]types.AttributeValue{
+		entityDynamo(),
+		{
+			"prefix": &types.AttributeValueMemberS{Value: "keyword:theory"},
+			"suffix": &types.AttributeValueMemberS{Value: "article:neumann/automata"},
+			"text":   &types.AttributeValueMemberS{Value: "automata"},
+		},
+	})
+	dbp := ddb.Must(ddb.New[person](ddb.WithTable("person"), ddb.WithService(mock)))
+	dbk := ddb.Must(ddb.New[keyword](ddb.WithTable("keyword"), ddb.WithService(mock)))
+
+	p := dbp.TxGet(person{Prefix: curie.New("dead:beef"), Suffix: curie.New("1")})
+	k := dbk.TxGet(keyword{Prefix: "keyword:theory", Suffix: "article:neumann/automata"})
+	n := dbk.TxGet(keyword{Prefix: "keyword:theory", Suffix: "article:neumann/other"})
+
+	err := ddb.TransactGet(context.Background(), p, k, n)
+	it.Ok(t).If(err).Should().Equal(nil)
+
+	valP, errP := p.Value()
+	valK, errK := k.Value()
+	valN, errN := n.Value()
+	_, isnfe := errN.(interface{ NotFound() string })
+
+	it.Ok(t).
+		If(errP).Should().Equal(nil).
+		If(valP).Should().Equal(entityStruct()).
+		If(errK).Should().Equal(nil).
+		If(valK.Text).Should().Equal("automata").
+		If(valN).Should().Equal(keyword{}).
+		IfTrue(isnfe)
+}
+
+func TestDdbTransactGetTooLarge(t *testing.T) {
+	dbk := ddb.Must(ddb.New[keyword](ddb.WithTable("keyword"), ddb.WithService(ddbtest.TransactGetItems(nil))))
+
+	seq := make([]ddb.TxReader, 101)
+	for i := 0; i < len(seq); i++ {
+		seq[i] = dbk.TxGet(keyword{Prefix: "keyword:theory", Suffix: curie.New("article:%d", i)})
+	}
+
+	err := ddb.TransactGet(context.Background(), seq...)
+	it.Ok(t).IfNotNil(err)
+}
+
+func TestDdbMatchSortKeyCondition(t *testing.T) {
+	var req *dynamodb.QueryInput
+	db := ddb.Must(ddb.New[person](ddb.WithTable("test"), ddb.WithService(ddbtest.QueryRequest(&req))))
+	gsi := ddb.Must(ddb.New[person](
+		ddb.WithTable("test"),
+		ddb.WithService(ddbtest.QueryRequest(&req)),
+		ddb.WithGlobalSecondaryIndex("test-name"),
+		ddb.WithSortKey("name"),
+	))
+	key := person{Prefix: curie.New("dead:beef")}
+
+	for expect, opt := range map[string]interface{ MatcherOpt(person) }{
+		"suffix < :__suffix__":  dynamo.SortKeyLt[person]("2023-06"),
+		"suffix <= :__suffix__": dynamo.SortKeyLe[person]("2023-06"),
+		"suffix > :__suffix__":  dynamo.SortKeyGt[person]("2023-06"),
+		"suffix >= :__suffix__": dynamo.SortKeyGe[person]("2023-06"),
+	} {
+		_, _, err := db.Match(context.Background(), key, opt)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(*req.KeyConditionExpression).Should().Equal("prefix = :__prefix__ and " + expect).
+			If(req.ExpressionAttributeValues[":__suffix__"]).Should().Equal(&types.AttributeValueMemberS{Value: "2023-06"})
+	}
+
+	t.Run("Between", func(t *testing.T) {
+		_, _, err := gsi.MatchKey(context.Background(), key,
+			dynamo.SortKeyBetween[person]("2023-01", "2023-06"),
+		)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(*req.IndexName).Should().Equal("test-name").
+			If(*req.KeyConditionExpression).Should().Equal("prefix = :__prefix__ and name BETWEEN :__name_a__ AND :__name_b__").
+			If(req.ExpressionAttributeValues[":__name_a__"]).Should().Equal(&types.AttributeValueMemberS{Value: "2023-01"}).
+			If(req.ExpressionAttributeValues[":__name_b__"]).Should().Equal(&types.AttributeValueMemberS{Value: "2023-06"})
+	})
+
+	t.Run("Exclusive", func(t *testing.T) {
+		_, _, err := db.Match(context.Background(), entityStruct(),
+			dynamo.SortKeyGt[person]("2023-06"),
+		)
+		it.Ok(t).IfNotNil(err)
+	})
+
+	t.Run("Multiple", func(t *testing.T) {
+		_, _, err := db.Match(context.Background(), key,
+			dynamo.SortKeyGt[person]("2023-01"),
+			dynamo.SortKeyLt[person]("2023-06"),
+		)
+		it.Ok(t).IfNotNil(err)
+	})
+}
+
+func TestDdbMatchDescending(t *testing.T) {
+	var req *dynamodb.QueryInput
+	db := ddb.Must(ddb.New[person](ddb.WithTable("test"), ddb.WithService(ddbtest.QueryRequest(&req))))
+
+	_, _, err := db.Match(context.Background(), person{Prefix: curie.New("dead:beef")})
+	it.Ok(t).
+		If(err).Should().Equal(nil).
+		IfNil(req.ScanIndexForward)
+
+	_, _, err = db.Match(context.Background(), person{Prefix: curie.New("dead:beef")},
+		dynamo.Descending[person](),
+	)
+	it.Ok(t).
+		If(err).Should().Equal(nil).
+		If(*req.ScanIndexForward).Should().Equal(false)
+}
+
+func TestDdbMatchConsistentRead(t *testing.T) {
+	var req *dynamodb.QueryInput
+	db := ddb.Must(ddb.New[person](ddb.WithTable("test"), ddb.WithService(ddbtest.QueryRequest(&req))))
+	gsi := ddb.Must(ddb.New[person](
+		ddb.WithTable("test"),
+		ddb.WithService(ddbtest.QueryRequest(&req)),
+		ddb.WithGlobalSecondaryIndex("test-name"),
+		ddb.WithSortKey("name"),
+	))
+	key := person{Prefix: curie.New("dead:beef")}
+
+	_, _, err := db.Match(context.Background(), key)
+	it.Ok(t).
+		If(err).Should().Equal(nil).
+		IfNil(req.ConsistentRead)
+
+	_, _, err = db.Match(context.Background(), key, dynamo.ConsistentRead[person]())
+	it.Ok(t).
+		If(err).Should().Equal(nil).
+		If(*req.ConsistentRead).Should().Equal(true)
+
+	_, _, err = gsi.Match(context.Background(), key, dynamo.ConsistentRead[person]())
+	it.Ok(t).IfNotNil(err)
+}
+
+func TestDdbMatchWithFilter(t *testing.T) {
+	name := ddb.FilterFor[person, string]("Name")
+	age := ddb.FilterFor[person, int]("Age")
+	key := person{Prefix: curie.New("dead:beef")}
+
+	t.Run("Expression", func(t *testing.T) {
+		var req *dynamodb.QueryInput
+		db := ddb.Must(ddb.New[person](ddb.WithTable("test"), ddb.WithService(ddbtest.QueryRequest(&req))))
+
+		_, _, err := db.Match(context.Background(), key, name.Eq("Verner Pleishner"), age.Gt(60))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(*req.KeyConditionExpression).Should().Equal("prefix = :__prefix__").
+			If(*req.FilterExpression).Should().Equal("(#__c_name__ = :__c_name__) and (#__c_age__ > :__c_age__)").
+			If(req.ExpressionAttributeNames).Should().Equal(map[string]string{"#__c_name__": "name", "#__c_age__": "age"}).
+			If(req.ExpressionAttributeValues[":__c_name__"]).Should().Equal(&types.AttributeValueMemberS{Value: "Verner Pleishner"}).
+			If(req.ExpressionAttributeValues[":__c_age__"]).Should().Equal(&types.AttributeValueMemberN{Value: "60"})
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		reqs := []dynamodb.QueryInput{}
+		db := ddb.Must(ddb.New[person](ddb.WithTable("test"), ddb.WithService(ddbtest.QueryPages(entityDynamo(), &reqs))))
+
+		seq, cursor, err := db.Match(context.Background(), key, name.Eq("Verner Pleishner"), dynamo.Limit[person](3))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(len(seq)).Should().Equal(3).
+			IfNotNil(cursor).
+			If(len(reqs)).Should().Equal(3).
+			If(*reqs[0].Limit).Should().Equal(int32(3)).
+			If(*reqs[1].Limit).Should().Equal(int32(2)).
+			If(*reqs[2].Limit).Should().Equal(int32(1)).
+			If(len(reqs[0].ExclusiveStartKey)).Should().Equal(0).
+			If(len(reqs[2].ExclusiveStartKey)).Should().Equal(2)
+	})
+
+	t.Run("NoFilter", func(t *testing.T) {
+		reqs := []dynamodb.QueryInput{}
+		db := ddb.Must(ddb.New[person](ddb.WithTable("test"), ddb.WithService(ddbtest.QueryPages(entityDynamo(), &reqs))))
+
+		seq, _, err := db.Match(context.Background(), key, dynamo.Limit[person](3))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(len(seq)).Should().Equal(1).
+			If(len(reqs)).Should().Equal(1)
+	})
+}
+
+func TestDdbScan(t *testing.T) {
+	vals := make([]map[string]types.AttributeValue, 10)
+	for i := 0; i < len(vals); i++ {
+		vals[i] = entityDynamo()
+		vals[i]["suffix"] = &types.AttributeValueMemberS{Value: fmt.Sprintf("%d", i)}
+	}
+
+	t.Run("Pages", func(t *testing.T) {
+		reqs := []dynamodb.ScanInput{}
+		db := ddb.Must(ddb.New[person](ddb.WithTable("test"), ddb.WithService(ddbtest.Scan(vals, &reqs))))
+
+		seq, cursor, err := db.Scan(context.Background(), dynamo.Limit[person](2))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(len(seq)).Should().Equal(2).
+			IfNotNil(cursor)
+
+		seq, _, err = db.Scan(context.Background(), dynamo.Limit[person](2), cursor)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(len(seq)).Should().Equal(2).
+			If(seq[0].Suffix).Should().Equal(curie.IRI("2"))
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		name := ddb.FilterFor[person, string]("Name")
+		reqs := []dynamodb.ScanInput{}
+		db := ddb.Must(ddb.New[person](ddb.WithTable("test"), ddb.WithService(ddbtest.Scan(vals, &reqs))))
+
+		_, _, err := db.Scan(context.Background(), name.Eq("Verner Pleishner"))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(*reqs[0].FilterExpression).Should().Equal("(#__c_name__ = :__c_name__)").
+			If(reqs[0].ExpressionAttributeValues[":__c_name__"]).Should().Equal(&types.AttributeValueMemberS{Value: "Verner Pleishner"})
+	})
+
+	t.Run("Parallel", func(t *testing.T) {
+		reqs := []dynamodb.ScanInput{}
+		db := ddb.Must(ddb.New[person](ddb.WithTable("test"), ddb.WithService(ddbtest.Scan(vals, &reqs))))
+
+		keys := map[curie.IRI]bool{}
+		seq, errs := db.ScanParallel(context.Background(), 3)
+		for x := range seq {
+			keys[x.Suffix] = true
+		}
+
+		it.Ok(t).
+			If(<-errs).Should().Equal(nil).
+			If(len(keys)).Should().Equal(10)
+
+		for _, req := range reqs {
+			it.Ok(t).If(*req.TotalSegments).Should().Equal(int32(3))
+		}
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		reqs := []dynamodb.ScanInput{}
+		db := ddb.Must(ddb.New[person](ddb.WithTable("test"), ddb.WithService(ddbtest.Scan(vals, &reqs))))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		seq, errs := db.ScanParallel(ctx, 3)
+		<-seq
+		cancel()
+
+		// segments are released without consumer
+		err := <-errs
+		_, closed := <-errs
+		it.Ok(t).
+			IfNotNil(err).
+			IfFalse(closed)
+	})
+
+	t.Run("InvalidSegments", func(t *testing.T) {
+		reqs := []dynamodb.ScanInput{}
+		db := ddb.Must(ddb.New[person](ddb.WithTable("test"), ddb.WithService(ddbtest.Scan(vals, &reqs))))
+
+		for _, n := range []int32{0, -1, 1000001} {
+			seq, errs := db.ScanParallel(context.Background(), n)
+			_, has := <-seq
+			it.Ok(t).
+				IfFalse(has).
+				IfNotNil(<-errs)
+		}
+		it.Ok(t).If(len(reqs)).Should().Equal(0)
+	})
+}
+
+type document struct {
+	Prefix  curie.IRI `dynamodbav:"prefix,omitempty"`
+	Suffix  curie.IRI `dynamodbav:"suffix,omitempty"`
+	Version int       `dynamodbav:"version,omitempty"`
+}
+
+func (d document) HashKey() curie.IRI { return d.Prefix }
+func (d document) SortKey() curie.IRI { return d.Suffix }
+
+func TestDdbVersion(t *testing.T) {
+	var version int64
+	db := ddb.Must(ddb.New[document](
+		ddb.WithTable("test"),
+		ddb.WithVersion("Version"),
+		ddb.WithService(ddbtest.Version(&version)),
+	))
+	doc := document{Prefix: curie.New("dead:beef"), Suffix: curie.New("1")}
+
+	t.Run("Create", func(t *testing.T) {
+		err := db.Put(context.Background(), doc)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(version).Should().Equal(int64(1))
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		val, err := db.Update(context.Background(), document{Prefix: doc.Prefix, Suffix: doc.Suffix, Version: 1})
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val.Version).Should().Equal(2).
+			If(version).Should().Equal(int64(2))
+	})
+
+	t.Run("Put", func(t *testing.T) {
+		err := db.Put(context.Background(), document{Prefix: doc.Prefix, Suffix: doc.Suffix, Version: 2})
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(version).Should().Equal(int64(3))
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		for _, err := range []error{
+			db.Put(context.Background(), doc),
+			db.Put(context.Background(), document{Prefix: doc.Prefix, Suffix: doc.Suffix, Version: 2}),
+		} {
+			e, ok := err.(interface{ Conflict() bool })
+			it.Ok(t).
+				IfTrue(ok).
+				IfTrue(e.Conflict())
+		}
+
+		_, err := db.Update(context.Background(), document{Prefix: doc.Prefix, Suffix: doc.Suffix, Version: 1})
+		e, ok := err.(interface{ Conflict() bool })
+		it.Ok(t).
+			IfTrue(ok).
+			IfTrue(e.Conflict()).
+			If(version).Should().Equal(int64(3))
+	})
+
+	t.Run("Undefined", func(t *testing.T) {
+		_, err := ddb.New[document](ddb.WithTable("test"), ddb.WithVersion("Unknown"))
+		it.Ok(t).IfNotNil(err)
+	})
+
+	t.Run("NotNumber", func(t *testing.T) {
+		_, err := ddb.New[person](ddb.WithTable("test"), ddb.WithVersion("Name"))
+		it.Ok(t).IfNotNil(err)
+	})
+
+	t.Run("BatchPut", func(t *testing.T) {
+		err := db.BatchPut(context.Background(), []document{doc})
+		it.Ok(t).
+			IfNotNil(err).
+			If(version).Should().Equal(int64(3))
+	})
+}
+
+type article struct {
+	Author   curie.IRI `dynamodbav:"prefix,omitempty"`
+	ID       curie.IRI `dynamodbav:"suffix,omitempty"`
+	Category string    `dynamodbav:"category,omitempty"`
+	Year     int       `dynamodbav:"year,omitempty"`
+}
+
+func (a article) HashKey() curie.IRI { return a.Author }
+func (a article) SortKey() curie.IRI { return a.ID }
+
+// category is projection of article to global secondary index
+type category article
+
+func (c category) HashKey() curie.IRI { return curie.IRI(c.Category) }
+func (c category) SortKey() curie.IRI {
+	if c.Year == 0 {
+		return ""
+	}
+	return curie.IRI(fmt.Sprintf("%d", c.Year))
+}
+
+func TestDdbMatchCursorToken(t *testing.T) {
+	service := ddbfake.New(
+		ddbfake.WithTable("test", "prefix", "suffix"),
+		ddbfake.WithGlobalSecondaryIndex("test", "index", "category", "year"),
+	)
+
+	db := ddb.Must(ddb.New[article](ddb.WithTable("test"), ddb.WithService(service)))
+	for i := 1; i <= 5; i++ {
+		err := db.Put(context.Background(), article{
+			Author:   curie.IRI(fmt.Sprintf("author:%d", i%2)),
+			ID:       curie.IRI(fmt.Sprintf("article:%d", i)),
+			Category: "math",
+			Year:     2000 + i,
+		})
+		it.Ok(t).If(err).Should().Equal(nil)
+	}
+
+	gsi := ddb.Must(ddb.New[category](
+		ddb.WithTable("test"),
+		ddb.WithGlobalSecondaryIndex("index"),
+		ddb.WithHashKey("category"),
+		ddb.WithSortKey("year"),
+		ddb.WithService(service),
+	))
+
+	secret := dynamo.WithHMAC([]byte("secret"))
+	years := []int{}
+	token := ""
+	for {
+		opts := []interface{ MatcherOpt(category) }{dynamo.Limit[category](2)}
+		cursor, err := dynamo.DecodeCursor[category](token, secret)
+		it.Ok(t).If(err).Should().Equal(nil)
+		if cursor != nil {
+			opts = append(opts, cursor)
+		}
+
+		seq, cursor, err := gsi.Match(context.Background(), category{Category: "math"}, opts...)
+		it.Ok(t).If(err).Should().Equal(nil)
+		for _, x := range seq {
+			years = append(years, x.Year)
+		}
+
+		token, err = dynamo.EncodeCursor[category](cursor, secret)
+		it.Ok(t).If(err).Should().Equal(nil)
+		if token == "" {
+			break
+		}
+	}
+
+	it.Ok(t).If(years).Should().Equal([]int{2001, 2002, 2003, 2004, 2005})
+}
+
+type post struct {
+	Author   curie.IRI `dynamodbav:"author,omitempty"`
+	ID       curie.IRI `dynamodbav:"id,omitempty"`
+	Category string    `dynamodbav:"category,omitempty"`
+	Year     int       `dynamodbav:"year,omitempty"`
+}
+
+func (p post) HashKey() curie.IRI { return p.Author }
+func (p post) SortKey() curie.IRI { return p.ID }
+
+// postByCategory is projection of post to global secondary index
+type postByCategory post
+
+func (p postByCategory) HashKey() curie.IRI { return curie.IRI(p.Category) }
+func (p postByCategory) SortKey() curie.IRI { return "" }
+
+func TestDdbMatchIndexCursor(t *testing.T) {
+	service := ddbfake.New(
+		ddbfake.WithTable("test", "author", "id"),
+		ddbfake.WithGlobalSecondaryIndex("test", "index", "category", "year"),
+	)
+
+	db := ddb.Must(ddb.New[post](
+		ddb.WithTable("test"),
+		ddb.WithHashKey("author"),
+		ddb.WithSortKey("id"),
+		ddb.WithService(service),
+	))
+	for i := 1; i <= 5; i++ {
+		err := db.Put(context.Background(), post{
+			Author:   curie.IRI(fmt.Sprintf("author:%d", i%2)),
+			ID:       curie.IRI(fmt.Sprintf("post:%d", i)),
+			Category: "math",
+			Year:     2000 + i,
+		})
+		it.Ok(t).If(err).Should().Equal(nil)
+	}
+
+	gsi := ddb.Must(ddb.New[postByCategory](
+		ddb.WithTable("test"),
+		ddb.WithGlobalSecondaryIndex("index"),
+		ddb.WithHashKey("category"),
+		ddb.WithSortKey("year"),
+		ddb.WithPrimaryKey("author", "id"),
+		ddb.WithService(service),
+	))
+
+	t.Run("CursorOfItem", func(t *testing.T) {
+		years := []int{}
+		opts := []interface{ MatcherOpt(postByCategory) }{dynamo.Limit[postByCategory](2)}
+		for {
+			seq, cursor, err := gsi.Match(context.Background(), postByCategory{Category: "math"}, opts...)
+			it.Ok(t).If(err).Should().Equal(nil)
+			for _, x := range seq {
+				years = append(years, x.Year)
+			}
+
+			if cursor == nil {
+				break
+			}
+
+			// continue after the last seen item
+			last := seq[len(seq)-1]
+			opts = []interface{ MatcherOpt(postByCategory) }{
+				dynamo.Limit[postByCategory](2),
+				dynamo.Cursor[postByCategory](last),
+			}
+		}
+
+		it.Ok(t).If(years).Should().Equal([]int{2001, 2002, 2003, 2004, 2005})
+	})
+
+	t.Run("Iterate", func(t *testing.T) {
+		years := []int{}
+		dynamo.Iterate[postByCategory](context.Background(), gsi, postByCategory{Category: "math"},
+			dynamo.Limit[postByCategory](2),
+			dynamo.Descending[postByCategory](),
+		)(func(x postByCategory, err error) bool {
+			it.Ok(t).If(err).Should().Equal(nil)
+			years = append(years, x.Year)
+			return true
+		})
+
+		it.Ok(t).If(years).Should().Equal([]int{2005, 2004, 2003, 2002, 2001})
+	})
+}
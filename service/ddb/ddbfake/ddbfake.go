@@ -0,0 +1,340 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+// Package ddbfake implements in-process DynamoDB service. The service keeps
+// tables in memory and evaluates key condition, condition, filter, update and
+// projection expressions, which makes it possible to test expressions built by
+// the library end-to-end without AWS.
+//
+//	db := ddb.Must(
+//		ddb.New[Person](
+//			ddb.WithTable("test"),
+//			ddb.WithService(ddbfake.New(ddbfake.WithTable("test", "prefix", "suffix"))),
+//		),
+//	)
+package ddbfake
+
+import (
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/dynamo/v3/internal/ddbexpr"
+)
+
+// Option type to configure the service
+type Option func(*DynamoDB)
+
+// WithTable declares the table and its primary key
+func WithTable(name, hashKey, sortKey string) Option {
+	return func(db *DynamoDB) {
+		db.tables[name] = &table{
+			name:    name,
+			schema:  schema{hashKey: hashKey, sortKey: sortKey},
+			indexes: map[string]schema{},
+			items:   map[string]item{},
+		}
+	}
+}
+
+// WithGlobalSecondaryIndex declares global secondary index of the table,
+// the index projects all attributes
+func WithGlobalSecondaryIndex(name, index, hashKey, sortKey string) Option {
+	return func(db *DynamoDB) {
+		if t, has := db.tables[name]; has {
+			t.indexes[index] = schema{hashKey: hashKey, sortKey: sortKey}
+		}
+	}
+}
+
+// DynamoDB is in-process implementation of DynamoDB service
+type DynamoDB struct {
+	sync.Mutex
+	tables map[string]*table
+}
+
+// New creates in-process DynamoDB service
+func New(opts ...Option) *DynamoDB {
+	db := &DynamoDB{tables: map[string]*table{}}
+	for _, opt := range opts {
+		opt(db)
+	}
+	return db
+}
+
+// Items returns copy of all items stored in the table, ordered by primary key
+func (db *DynamoDB) Items(name string) []map[string]types.AttributeValue {
+	db.Lock()
+	defer db.Unlock()
+
+	t, has := db.tables[name]
+	if !has {
+		return nil
+	}
+
+	seq := make([]map[string]types.AttributeValue, 0, len(t.items))
+	for _, x := range t.sorted(t.schema) {
+		seq = append(seq, ddbexpr.Clone(x))
+	}
+	return seq
+}
+
+func (db *DynamoDB) tableOf(name *string) (*table, error) {
+	if name == nil {
+		return nil, errValidation("value null at 'tableName' failed to satisfy constraint: Member must not be null")
+	}
+
+	t, has := db.tables[*name]
+	if !has {
+		return nil, errResourceNotFound(*name)
+	}
+
+	return t, nil
+}
+
+//------------------------------------------------------------------------------
+//
+// Tables
+//
+//------------------------------------------------------------------------------
+
+type item = map[string]types.AttributeValue
+
+// schema of keys (primary or index)
+type schema struct {
+	hashKey string
+	sortKey string
+}
+
+func (s schema) attributes() []string {
+	if s.sortKey == "" {
+		return []string{s.hashKey}
+	}
+	return []string{s.hashKey, s.sortKey}
+}
+
+// table is in-memory table, items are indexed by primary key
+type table struct {
+	name    string
+	schema  schema
+	indexes map[string]schema
+	items   map[string]item
+}
+
+// keyOf validates key attributes of the item and returns its identity
+func (t *table) keyOf(obj item) (string, error) {
+	sb := strings.Builder{}
+	for _, attr := range t.schema.attributes() {
+		val, has := obj[attr]
+		if !has {
+			return "", errValidation("the provided key element does not match the schema, missing %s", attr)
+		}
+
+		switch v := val.(type) {
+		case *types.AttributeValueMemberS:
+			if v.Value == "" {
+				return "", errValidation("one or more parameter values are not valid, the AttributeValue for a key attribute cannot contain an empty string value: %s", attr)
+			}
+			sb.WriteString("S" + v.Value)
+		case *types.AttributeValueMemberN:
+			n, ok := ddbexpr.Number(v.Value)
+			if !ok {
+				return "", errValidation("invalid number %s of key attribute %s", v.Value, attr)
+			}
+			sb.WriteString("N" + ddbexpr.FormatNumber(n))
+		case *types.AttributeValueMemberB:
+			if len(v.Value) == 0 {
+				return "", errValidation("one or more parameter values are not valid, the AttributeValue for a key attribute cannot contain an empty binary value: %s", attr)
+			}
+			sb.WriteString("B" + string(v.Value))
+		default:
+			return "", errValidation("the provided key element does not match the schema, invalid type %s of %s", ddbexpr.TypeOf(val), attr)
+		}
+		sb.WriteRune(0)
+	}
+
+	return sb.String(), nil
+}
+
+// keyOnly validates the key and returns its identity
+func (t *table) keyOnly(key item) (string, error) {
+	if len(key) != len(t.schema.attributes()) {
+		return "", errValidation("the provided key element does not match the schema")
+	}
+	return t.keyOf(key)
+}
+
+// keyAttributes returns key attributes of the item in the context of the
+// primary key and the index
+func (t *table) keyAttributes(obj item, index *schema) item {
+	key := item{}
+	for _, attr := range t.schema.attributes() {
+		key[attr] = ddbexpr.CloneValue(obj[attr])
+	}
+
+	if index != nil {
+		for _, attr := range index.attributes() {
+			key[attr] = ddbexpr.CloneValue(obj[attr])
+		}
+	}
+
+	return key
+}
+
+// index resolves schema of the index
+func (t *table) index(name *string) (*schema, error) {
+	if name == nil {
+		return nil, nil
+	}
+
+	s, has := t.indexes[*name]
+	if !has {
+		return nil, errValidation("the table does not have the specified index: %s", *name)
+	}
+
+	return &s, nil
+}
+
+// sorted returns items of the table ordered by the key schema, items that
+// do not have attributes of the key are excluded (sparse index)
+func (t *table) sorted(s schema) []item {
+	seq := make([]item, 0, len(t.items))
+	for _, x := range t.items {
+		if hasAttributes(x, s.attributes()) {
+			seq = append(seq, x)
+		}
+	}
+
+	sort.SliceStable(seq, func(i, j int) bool { return t.less(s, seq[i], seq[j]) })
+	return seq
+}
+
+// less defines ordering of items: hash key, sort key and then primary key
+func (t *table) less(s schema, a, b item) bool {
+	attrs := append(s.attributes(), t.schema.attributes()...)
+	for _, attr := range attrs {
+		if c := compare(a[attr], b[attr]); c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
+// segment of item for parallel scan
+func (t *table) segment(obj item, total int32) int32 {
+	h := fnv.New32a()
+	key, _ := t.keyOf(obj)
+	h.Write([]byte(key))
+	return int32(h.Sum32() % uint32(total))
+}
+
+func hasAttributes(obj item, attrs []string) bool {
+	for _, attr := range attrs {
+		if _, has := obj[attr]; !has {
+			return false
+		}
+	}
+	return true
+}
+
+// compare key values, values of different types are ordered by type
+func compare(a, b types.AttributeValue) int {
+	if c, ok := ddbexpr.Compare(a, b); ok {
+		return c
+	}
+	return strings.Compare(ddbexpr.TypeOf(a), ddbexpr.TypeOf(b))
+}
+
+//------------------------------------------------------------------------------
+//
+// Expressions
+//
+//------------------------------------------------------------------------------
+
+// expressions of the request
+type expressions struct {
+	*ddbexpr.Expressions
+}
+
+func newExpressions(names map[string]string, values map[string]types.AttributeValue) (*expressions, error) {
+	if names != nil && len(names) == 0 {
+		return nil, errValidation("ExpressionAttributeNames must not be empty")
+	}
+
+	if values != nil && len(values) == 0 {
+		return nil, errValidation("ExpressionAttributeValues must not be empty")
+	}
+
+	return &expressions{ddbexpr.New(names, values)}, nil
+}
+
+func (exprs *expressions) condition(kind string, expr *string) (ddbexpr.Condition, error) {
+	if expr == nil {
+		return nil, nil
+	}
+
+	cond, err := exprs.Condition(*expr)
+	if err != nil {
+		return nil, errValidation("invalid %s: %s", kind, err)
+	}
+
+	return cond, nil
+}
+
+func (exprs *expressions) projection(expr *string) (ddbexpr.Projection, error) {
+	if expr == nil {
+		return nil, nil
+	}
+
+	proj, err := exprs.Projection(*expr)
+	if err != nil {
+		return nil, errValidation("invalid ProjectionExpression: %s", err)
+	}
+
+	return proj, nil
+}
+
+func (exprs *expressions) update(expr *string) (*ddbexpr.Update, error) {
+	if expr == nil {
+		return nil, errValidation("UpdateExpression must be defined")
+	}
+
+	upd, err := exprs.Update(*expr)
+	if err != nil {
+		return nil, errValidation("invalid UpdateExpression: %s", err)
+	}
+
+	return upd, nil
+}
+
+// unused placeholders are not accepted by DynamoDB
+func (exprs *expressions) unused() error {
+	if err := exprs.Unused(); err != nil {
+		return errValidation("%s", err)
+	}
+	return nil
+}
+
+func project(proj ddbexpr.Projection, obj item) item {
+	if proj == nil {
+		return ddbexpr.Clone(obj)
+	}
+	return proj.Apply(obj)
+}
+
+func isTrue(cond ddbexpr.Condition, obj item) bool {
+	if cond == nil {
+		return true
+	}
+	if obj == nil {
+		obj = item{}
+	}
+	return cond(obj)
+}
@@ -0,0 +1,261 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package ddbfake_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/dynamo/v3/service/ddb/ddbfake"
+	"github.com/fogfish/it"
+)
+
+func s(v string) types.AttributeValue { return &types.AttributeValueMemberS{Value: v} }
+func n(v string) types.AttributeValue { return &types.AttributeValueMemberN{Value: v} }
+
+func key(suffix string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"prefix": s("dead:beef"), "suffix": s(suffix)}
+}
+
+func fixture() *ddbfake.DynamoDB {
+	db := ddbfake.New(
+		ddbfake.WithTable("test", "prefix", "suffix"),
+		ddbfake.WithGlobalSecondaryIndex("test", "index", "suffix", "prefix"),
+	)
+
+	db.PutItem(context.Background(), &dynamodb.PutItemInput{
+		TableName: aws.String("test"),
+		Item: map[string]types.AttributeValue{
+			"prefix": s("dead:beef"),
+			"suffix": s("1"),
+			"name":   s("Verner Pleishner"),
+			"age":    n("64"),
+			"address": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"city": s("Berne"),
+			}},
+			"tags": &types.AttributeValueMemberL{Value: []types.AttributeValue{s("a"), s("b"), s("c")}},
+		},
+	})
+
+	return db
+}
+
+func errorCode(err error) string {
+	var e interface{ ErrorCode() string }
+	if errors.As(err, &e) {
+		return e.ErrorCode()
+	}
+	return ""
+}
+
+func TestGetItem(t *testing.T) {
+	db := fixture()
+
+	val, err := db.GetItem(context.Background(), &dynamodb.GetItemInput{
+		TableName:                aws.String("test"),
+		Key:                      key("1"),
+		ProjectionExpression:     aws.String("#n, address.city, tags[1]"),
+		ExpressionAttributeNames: map[string]string{"#n": "name"},
+	})
+
+	it.Ok(t).
+		If(err).Should().Equal(nil).
+		If(val.Item).Should().Equal(map[string]types.AttributeValue{
+		"name": s("Verner Pleishner"),
+		"address": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"city": s("Berne"),
+		}},
+		"tags": &types.AttributeValueMemberL{Value: []types.AttributeValue{s("b")}},
+	})
+}
+
+func TestValidation(t *testing.T) {
+	db := fixture()
+
+	for spec, req := range map[string]*dynamodb.UpdateItemInput{
+		"UnusedValue": {
+			UpdateExpression:          aws.String("SET age = :a"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":a": n("1"), ":b": n("2")},
+		},
+		"UndefinedName": {
+			UpdateExpression:          aws.String("SET #a = :a"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":a": n("1")},
+		},
+		"EmptyValues": {
+			UpdateExpression:          aws.String("REMOVE age"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{},
+		},
+		"OverlappingPaths": {
+			UpdateExpression:          aws.String("SET age = :a, age = age + :a"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":a": n("1")},
+		},
+		"DuplicateSection": {
+			UpdateExpression:          aws.String("SET age = :a SET #n = :a"),
+			ExpressionAttributeNames:  map[string]string{"#n": "name"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":a": n("1")},
+		},
+		"MixedSection": {
+			UpdateExpression:          aws.String("ADD age :a, #n = :a"),
+			ExpressionAttributeNames:  map[string]string{"#n": "name"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":a": n("1")},
+		},
+		"KeyAttribute": {
+			UpdateExpression:          aws.String("SET suffix = :a"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":a": s("2")},
+		},
+		"InvalidOperand": {
+			UpdateExpression:          aws.String("SET age = #n + :a"),
+			ExpressionAttributeNames:  map[string]string{"#n": "name"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":a": n("1")},
+		},
+	} {
+		t.Run(spec, func(t *testing.T) {
+			req.TableName = aws.String("test")
+			req.Key = key("1")
+
+			_, err := db.UpdateItem(context.Background(), req)
+			it.Ok(t).If(errorCode(err)).Should().Equal("ValidationException")
+		})
+	}
+
+	t.Run("UnknownTable", func(t *testing.T) {
+		_, err := db.GetItem(context.Background(), &dynamodb.GetItemInput{
+			TableName: aws.String("unknown"),
+			Key:       key("1"),
+		})
+		it.Ok(t).If(errorCode(err)).Should().Equal("ResourceNotFoundException")
+	})
+}
+
+func TestUpdateItem(t *testing.T) {
+	db := fixture()
+
+	val, err := db.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+		TableName:                 aws.String("test"),
+		Key:                       key("1"),
+		UpdateExpression:          aws.String("SET #a.#c = :c, tags[1] = :t, age = age - :n REMOVE tags[0], tags[2]"),
+		ExpressionAttributeNames:  map[string]string{"#a": "address", "#c": "city"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":c": s("Bern"), ":t": s("x"), ":n": n("0.5")},
+		ReturnValues:              types.ReturnValueUpdatedNew,
+	})
+
+	it.Ok(t).
+		If(err).Should().Equal(nil).
+		If(val.Attributes).Should().Equal(map[string]types.AttributeValue{
+		"address": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"city": s("Bern"),
+		}},
+		"tags": &types.AttributeValueMemberL{Value: []types.AttributeValue{s("x")}},
+		"age":  n("63.5"),
+	})
+}
+
+func TestConditionalCheckFailed(t *testing.T) {
+	db := fixture()
+
+	_, err := db.DeleteItem(context.Background(), &dynamodb.DeleteItemInput{
+		TableName:                           aws.String("test"),
+		Key:                                 key("1"),
+		ConditionExpression:                 aws.String("age > :a AND NOT attribute_exists(address.city)"),
+		ExpressionAttributeValues:           map[string]types.AttributeValue{":a": n("60")},
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+
+	var e *types.ConditionalCheckFailedException
+	it.Ok(t).
+		IfTrue(errors.As(err, &e)).
+		If(e.Item["name"]).Should().Equal(s("Verner Pleishner"))
+}
+
+func TestQuery(t *testing.T) {
+	db := ddbfake.New(
+		ddbfake.WithTable("test", "prefix", "suffix"),
+		ddbfake.WithGlobalSecondaryIndex("test", "index", "suffix", "prefix"),
+	)
+	for _, prefix := range []string{"a", "b", "c"} {
+		db.PutItem(context.Background(), &dynamodb.PutItemInput{
+			TableName: aws.String("test"),
+			Item:      map[string]types.AttributeValue{"prefix": s(prefix), "suffix": s("1")},
+		})
+	}
+
+	req := &dynamodb.QueryInput{
+		TableName:                 aws.String("test"),
+		IndexName:                 aws.String("index"),
+		KeyConditionExpression:    aws.String("suffix = :s"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":s": s("1")},
+		ScanIndexForward:          aws.Bool(false),
+		Limit:                     aws.Int32(2),
+	}
+
+	page, err := db.Query(context.Background(), req)
+	it.Ok(t).
+		If(err).Should().Equal(nil).
+		If(page.Count).Should().Equal(int32(2)).
+		If(page.Items[0]["prefix"]).Should().Equal(s("c")).
+		If(page.LastEvaluatedKey).Should().Equal(map[string]types.AttributeValue{"prefix": s("b"), "suffix": s("1")})
+
+	req.ExclusiveStartKey = page.LastEvaluatedKey
+	page, err = db.Query(context.Background(), req)
+	it.Ok(t).
+		If(err).Should().Equal(nil).
+		If(page.Count).Should().Equal(int32(1)).
+		If(page.Items[0]["prefix"]).Should().Equal(s("a")).
+		If(page.LastEvaluatedKey).Should().Equal(map[string]types.AttributeValue(nil))
+}
+
+func TestScan(t *testing.T) {
+	db := ddbfake.New(ddbfake.WithTable("test", "prefix", "suffix"))
+	for _, suffix := range []string{"1", "2", "3", "4", "5", "6", "7", "8"} {
+		db.PutItem(context.Background(), &dynamodb.PutItemInput{
+			TableName: aws.String("test"),
+			Item:      key(suffix),
+		})
+	}
+
+	count := 0
+	for segment := int32(0); segment < 3; segment++ {
+		page, err := db.Scan(context.Background(), &dynamodb.ScanInput{
+			TableName:     aws.String("test"),
+			Segment:       aws.Int32(segment),
+			TotalSegments: aws.Int32(3),
+		})
+		it.Ok(t).If(err).Should().Equal(nil)
+		count += int(page.Count)
+	}
+
+	it.Ok(t).If(count).Should().Equal(8)
+}
+
+func TestTransactWriteItems(t *testing.T) {
+	db := fixture()
+
+	_, err := db.TransactWriteItems(context.Background(), &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{TableName: aws.String("test"), Item: key("2")}},
+			{ConditionCheck: &types.ConditionCheck{
+				TableName:                 aws.String("test"),
+				Key:                       key("1"),
+				ConditionExpression:       aws.String("age = :a"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{":a": n("65")},
+			}},
+		},
+	})
+
+	var e *types.TransactionCanceledException
+	it.Ok(t).
+		IfTrue(errors.As(err, &e)).
+		If(aws.ToString(e.CancellationReasons[0].Code)).Should().Equal("None").
+		If(aws.ToString(e.CancellationReasons[1].Code)).Should().Equal("ConditionalCheckFailed").
+		If(len(db.Items("test"))).Should().Equal(1)
+}
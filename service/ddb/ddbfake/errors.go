@@ -0,0 +1,55 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package ddbfake
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+// errValidation is ValidationException of DynamoDB
+func errValidation(format string, args ...any) error {
+	return &smithy.GenericAPIError{
+		Code:    "ValidationException",
+		Message: fmt.Sprintf(format, args...),
+		Fault:   smithy.FaultClient,
+	}
+}
+
+func errResourceNotFound(table string) error {
+	return &types.ResourceNotFoundException{
+		Message: aws.String("Requested resource not found: Table: " + table + " not found"),
+	}
+}
+
+// errConditionalCheckFailed is returned by single item operation, the item
+// is returned if request demands ReturnValuesOnConditionCheckFailure
+func errConditionalCheckFailed(obj item) error {
+	return &types.ConditionalCheckFailedException{
+		Message: aws.String("The conditional request failed"),
+		Item:    obj,
+	}
+}
+
+func isConditionalCheckFailed(err error) (*types.ConditionalCheckFailedException, bool) {
+	var e *types.ConditionalCheckFailedException
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+func errTransactionCanceled(reasons []types.CancellationReason) error {
+	return &types.TransactionCanceledException{
+		Message:             aws.String("Transaction cancelled, please refer cancellation reasons for specific reasons"),
+		CancellationReasons: reasons,
+	}
+}
@@ -0,0 +1,117 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package ddbfake
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// BatchGetItem implements dynamodb.BatchGetItem
+func (db *DynamoDB) BatchGetItem(ctx context.Context, req *dynamodb.BatchGetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	n := 0
+	for _, keys := range req.RequestItems {
+		n += len(keys.Keys)
+	}
+	if n == 0 || n > 100 {
+		return nil, errValidation("too many items requested for the BatchGetItem call, 1 to 100 keys are allowed")
+	}
+
+	out := &dynamodb.BatchGetItemOutput{
+		Responses:       map[string][]map[string]types.AttributeValue{},
+		UnprocessedKeys: map[string]types.KeysAndAttributes{},
+	}
+
+	for table, keys := range req.RequestItems {
+		seq := make([]map[string]types.AttributeValue, 0)
+		for _, key := range keys.Keys {
+			obj, err := db.get(aws.String(table), key, keys.ProjectionExpression, keys.ExpressionAttributeNames)
+			if err != nil {
+				return nil, err
+			}
+			if obj != nil {
+				seq = append(seq, obj)
+			}
+		}
+		out.Responses[table] = seq
+	}
+
+	return out, nil
+}
+
+// BatchWriteItem implements dynamodb.BatchWriteItem
+func (db *DynamoDB) BatchWriteItem(ctx context.Context, req *dynamodb.BatchWriteItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	n := 0
+	for _, seq := range req.RequestItems {
+		n += len(seq)
+	}
+	if n == 0 || n > 25 {
+		return nil, errValidation("too many items requested for the BatchWriteItem call, 1 to 25 requests are allowed")
+	}
+
+	writes := make([]*write, 0, n)
+	for table, seq := range req.RequestItems {
+		for _, wr := range seq {
+			var (
+				w   *write
+				err error
+			)
+
+			switch {
+			case wr.PutRequest != nil:
+				w, _, err = db.preparePut(request{table: aws.String(table), item: wr.PutRequest.Item})
+			case wr.DeleteRequest != nil:
+				w, _, err = db.prepareDelete(request{table: aws.String(table), key: wr.DeleteRequest.Key})
+			default:
+				err = errValidation("write request must define either PutRequest or DeleteRequest")
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			writes = append(writes, w)
+		}
+	}
+
+	if err := unique(writes); err != nil {
+		return nil, err
+	}
+
+	for _, w := range writes {
+		w.commit()
+	}
+
+	return &dynamodb.BatchWriteItemOutput{
+		UnprocessedItems: map[string][]types.WriteRequest{},
+	}, nil
+}
+
+// unique checks that request does not contain multiple operations on one item
+func unique(writes []*write) error {
+	seen := map[*table]map[string]bool{}
+	for _, w := range writes {
+		if seen[w.table] == nil {
+			seen[w.table] = map[string]bool{}
+		}
+		if seen[w.table][w.key] {
+			return errValidation("provided list of item keys contains duplicates")
+		}
+		seen[w.table][w.key] = true
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package ddbfake
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/dynamo/v3/internal/ddbexpr"
+)
+
+// GetItem implements dynamodb.GetItem
+func (db *DynamoDB) GetItem(ctx context.Context, req *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	obj, err := db.get(req.TableName, req.Key, req.ProjectionExpression, req.ExpressionAttributeNames)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.GetItemOutput{Item: obj}, nil
+}
+
+func (db *DynamoDB) get(table *string, key item, projection *string, names map[string]string) (item, error) {
+	t, err := db.tableOf(table)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := t.keyOnly(key)
+	if err != nil {
+		return nil, err
+	}
+
+	exprs, err := newExpressions(names, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	proj, err := exprs.projection(projection)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := exprs.unused(); err != nil {
+		return nil, err
+	}
+
+	obj, has := t.items[id]
+	if !has {
+		return nil, nil
+	}
+
+	return project(proj, obj), nil
+}
+
+// PutItem implements dynamodb.PutItem
+func (db *DynamoDB) PutItem(ctx context.Context, req *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	if req.ReturnValues != "" && req.ReturnValues != types.ReturnValueNone && req.ReturnValues != types.ReturnValueAllOld {
+		return nil, errValidation("ReturnValues can only be ALL_OLD or NONE")
+	}
+
+	w, old, err := db.preparePut(request{
+		table:     req.TableName,
+		item:      req.Item,
+		condition: req.ConditionExpression,
+		names:     req.ExpressionAttributeNames,
+		values:    req.ExpressionAttributeValues,
+		onFailure: req.ReturnValuesOnConditionCheckFailure,
+	})
+	if err != nil {
+		return nil, err
+	}
+	w.commit()
+
+	out := &dynamodb.PutItemOutput{}
+	if req.ReturnValues == types.ReturnValueAllOld {
+		out.Attributes = ddbexpr.Clone(old)
+	}
+
+	return out, nil
+}
+
+// DeleteItem implements dynamodb.DeleteItem
+func (db *DynamoDB) DeleteItem(ctx context.Context, req *dynamodb.DeleteItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	if req.ReturnValues != "" && req.ReturnValues != types.ReturnValueNone && req.ReturnValues != types.ReturnValueAllOld {
+		return nil, errValidation("ReturnValues can only be ALL_OLD or NONE")
+	}
+
+	w, old, err := db.prepareDelete(request{
+		table:     req.TableName,
+		key:       req.Key,
+		condition: req.ConditionExpression,
+		names:     req.ExpressionAttributeNames,
+		values:    req.ExpressionAttributeValues,
+		onFailure: req.ReturnValuesOnConditionCheckFailure,
+	})
+	if err != nil {
+		return nil, err
+	}
+	w.commit()
+
+	out := &dynamodb.DeleteItemOutput{}
+	if req.ReturnValues == types.ReturnValueAllOld {
+		out.Attributes = ddbexpr.Clone(old)
+	}
+
+	return out, nil
+}
+
+// UpdateItem implements dynamodb.UpdateItem
+func (db *DynamoDB) UpdateItem(ctx context.Context, req *dynamodb.UpdateItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	switch req.ReturnValues {
+	case "", types.ReturnValueNone, types.ReturnValueAllOld, types.ReturnValueAllNew, types.ReturnValueUpdatedOld, types.ReturnValueUpdatedNew:
+	default:
+		return nil, errValidation("invalid ReturnValues %s", req.ReturnValues)
+	}
+
+	w, old, paths, err := db.prepareUpdate(request{
+		table:     req.TableName,
+		key:       req.Key,
+		condition: req.ConditionExpression,
+		update:    req.UpdateExpression,
+		names:     req.ExpressionAttributeNames,
+		values:    req.ExpressionAttributeValues,
+		onFailure: req.ReturnValuesOnConditionCheckFailure,
+	})
+	if err != nil {
+		return nil, err
+	}
+	w.commit()
+
+	out := &dynamodb.UpdateItemOutput{}
+	switch req.ReturnValues {
+	case types.ReturnValueAllOld:
+		out.Attributes = ddbexpr.Clone(old)
+	case types.ReturnValueAllNew:
+		out.Attributes = ddbexpr.Clone(w.item)
+	case types.ReturnValueUpdatedOld:
+		out.Attributes = ddbexpr.Projection(paths).Apply(old)
+	case types.ReturnValueUpdatedNew:
+		out.Attributes = ddbexpr.Projection(paths).Apply(w.item)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,229 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package ddbfake
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/fogfish/dynamo/v3/internal/ddbexpr"
+)
+
+// Query implements dynamodb.Query
+func (db *DynamoDB) Query(ctx context.Context, req *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	t, err := db.tableOf(req.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := t.index(req.IndexName)
+	if err != nil {
+		return nil, err
+	}
+
+	if index != nil && aws.ToBool(req.ConsistentRead) {
+		return nil, errValidation("consistent reads are not supported on global secondary indexes")
+	}
+
+	if req.KeyConditionExpression == nil {
+		return nil, errValidation("KeyConditionExpression must be defined")
+	}
+
+	exprs, err := newExpressions(req.ExpressionAttributeNames, req.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := exprs.condition("KeyConditionExpression", req.KeyConditionExpression)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := exprs.condition("FilterExpression", req.FilterExpression)
+	if err != nil {
+		return nil, err
+	}
+
+	proj, err := exprs.projection(req.ProjectionExpression)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := exprs.unused(); err != nil {
+		return nil, err
+	}
+
+	seq := make([]item, 0)
+	for _, x := range t.sorted(t.schemaOf(index)) {
+		if key(x) {
+			seq = append(seq, x)
+		}
+	}
+
+	forward := req.ScanIndexForward == nil || *req.ScanIndexForward
+	if !forward {
+		for i, j := 0, len(seq)-1; i < j; i, j = i+1, j-1 {
+			seq[i], seq[j] = seq[j], seq[i]
+		}
+	}
+
+	p, err := t.page(seq, index, forward, req.ExclusiveStartKey, req.Limit, filter, proj)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.QueryOutput{
+		Items:            p.items,
+		Count:            int32(len(p.items)),
+		ScannedCount:     p.scanned,
+		LastEvaluatedKey: p.lastEvaluatedKey,
+	}, nil
+}
+
+// Scan implements dynamodb.Scan
+func (db *DynamoDB) Scan(ctx context.Context, req *dynamodb.ScanInput, opts ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	t, err := db.tableOf(req.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := t.index(req.IndexName)
+	if err != nil {
+		return nil, err
+	}
+
+	if index != nil && aws.ToBool(req.ConsistentRead) {
+		return nil, errValidation("consistent reads are not supported on global secondary indexes")
+	}
+
+	if (req.Segment == nil) != (req.TotalSegments == nil) {
+		return nil, errValidation("the Segment parameter is required but was not present in the request when parameter TotalSegments is present")
+	}
+
+	if req.TotalSegments != nil && (*req.TotalSegments < 1 || *req.Segment < 0 || *req.Segment >= *req.TotalSegments) {
+		return nil, errValidation("the Segment parameter is not valid, 0 <= Segment < TotalSegments")
+	}
+
+	exprs, err := newExpressions(req.ExpressionAttributeNames, req.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := exprs.condition("FilterExpression", req.FilterExpression)
+	if err != nil {
+		return nil, err
+	}
+
+	proj, err := exprs.projection(req.ProjectionExpression)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := exprs.unused(); err != nil {
+		return nil, err
+	}
+
+	seq := make([]item, 0)
+	for _, x := range t.sorted(t.schemaOf(index)) {
+		if req.TotalSegments == nil || t.segment(x, *req.TotalSegments) == *req.Segment {
+			seq = append(seq, x)
+		}
+	}
+
+	p, err := t.page(seq, index, true, req.ExclusiveStartKey, req.Limit, filter, proj)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.ScanOutput{
+		Items:            p.items,
+		Count:            int32(len(p.items)),
+		ScannedCount:     p.scanned,
+		LastEvaluatedKey: p.lastEvaluatedKey,
+	}, nil
+}
+
+// page of query or scan results
+type page struct {
+	items            []item
+	scanned          int32
+	lastEvaluatedKey item
+}
+
+// page evaluates the page of ordered items. The page starts after the
+// exclusive start key, limit is number of evaluated items, the filter
+// is applied after the limit.
+func (t *table) page(
+	seq []item,
+	index *schema,
+	forward bool,
+	exclusiveStartKey item,
+	limit *int32,
+	filter ddbexpr.Condition,
+	proj ddbexpr.Projection,
+) (*page, error) {
+	s := t.schemaOf(index)
+
+	if exclusiveStartKey != nil {
+		attrs := t.schema.attributes()
+		if index != nil {
+			attrs = append(attrs, index.attributes()...)
+		}
+		if len(exclusiveStartKey) != len(t.keyAttributes(exclusiveStartKey, index)) || !hasAttributes(exclusiveStartKey, attrs) {
+			return nil, errValidation("the provided starting key is invalid")
+		}
+
+		at := 0
+		for at < len(seq) {
+			if forward && t.less(s, exclusiveStartKey, seq[at]) {
+				break
+			}
+			if !forward && t.less(s, seq[at], exclusiveStartKey) {
+				break
+			}
+			at++
+		}
+		seq = seq[at:]
+	}
+
+	if limit != nil && *limit < 1 {
+		return nil, errValidation("limit must be greater than or equal to 1")
+	}
+
+	p := &page{items: make([]item, 0)}
+	for i, x := range seq {
+		p.scanned++
+		if isTrue(filter, x) {
+			p.items = append(p.items, project(proj, x))
+		}
+
+		if limit != nil && p.scanned == *limit {
+			if i < len(seq)-1 {
+				p.lastEvaluatedKey = t.keyAttributes(x, index)
+			}
+			break
+		}
+	}
+
+	return p, nil
+}
+
+func (t *table) schemaOf(index *schema) schema {
+	if index == nil {
+		return t.schema
+	}
+	return *index
+}
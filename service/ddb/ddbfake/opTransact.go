@@ -0,0 +1,145 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package ddbfake
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// TransactGetItems implements dynamodb.TransactGetItems
+func (db *DynamoDB) TransactGetItems(ctx context.Context, req *dynamodb.TransactGetItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	if len(req.TransactItems) == 0 || len(req.TransactItems) > 100 {
+		return nil, errValidation("transaction must contain 1 to 100 items")
+	}
+
+	out := &dynamodb.TransactGetItemsOutput{
+		Responses: make([]types.ItemResponse, len(req.TransactItems)),
+	}
+
+	for i, x := range req.TransactItems {
+		if x.Get == nil {
+			return nil, errValidation("transaction item must define Get")
+		}
+
+		obj, err := db.get(x.Get.TableName, x.Get.Key, x.Get.ProjectionExpression, x.Get.ExpressionAttributeNames)
+		if err != nil {
+			return nil, err
+		}
+		out.Responses[i] = types.ItemResponse{Item: obj}
+	}
+
+	return out, nil
+}
+
+// TransactWriteItems implements dynamodb.TransactWriteItems. All conditions
+// are evaluated before any modification, the transaction is cancelled if
+// any of conditions fails.
+func (db *DynamoDB) TransactWriteItems(ctx context.Context, req *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	if len(req.TransactItems) == 0 || len(req.TransactItems) > 100 {
+		return nil, errValidation("transaction must contain 1 to 100 items")
+	}
+
+	writes := make([]*write, 0, len(req.TransactItems))
+	reasons := make([]types.CancellationReason, len(req.TransactItems))
+	canceled := false
+
+	for i, x := range req.TransactItems {
+		var (
+			w   *write
+			err error
+		)
+
+		switch {
+		case x.ConditionCheck != nil:
+			op := x.ConditionCheck
+			w, err = db.prepareCheck(request{
+				table:     op.TableName,
+				key:       op.Key,
+				condition: op.ConditionExpression,
+				names:     op.ExpressionAttributeNames,
+				values:    op.ExpressionAttributeValues,
+				onFailure: op.ReturnValuesOnConditionCheckFailure,
+			})
+		case x.Put != nil:
+			op := x.Put
+			w, _, err = db.preparePut(request{
+				table:     op.TableName,
+				item:      op.Item,
+				condition: op.ConditionExpression,
+				names:     op.ExpressionAttributeNames,
+				values:    op.ExpressionAttributeValues,
+				onFailure: op.ReturnValuesOnConditionCheckFailure,
+			})
+		case x.Delete != nil:
+			op := x.Delete
+			w, _, err = db.prepareDelete(request{
+				table:     op.TableName,
+				key:       op.Key,
+				condition: op.ConditionExpression,
+				names:     op.ExpressionAttributeNames,
+				values:    op.ExpressionAttributeValues,
+				onFailure: op.ReturnValuesOnConditionCheckFailure,
+			})
+		case x.Update != nil:
+			op := x.Update
+			w, _, _, err = db.prepareUpdate(request{
+				table:     op.TableName,
+				key:       op.Key,
+				condition: op.ConditionExpression,
+				update:    op.UpdateExpression,
+				names:     op.ExpressionAttributeNames,
+				values:    op.ExpressionAttributeValues,
+				onFailure: op.ReturnValuesOnConditionCheckFailure,
+			})
+		default:
+			err = errValidation("transaction item must define one of ConditionCheck, Put, Delete or Update")
+		}
+
+		if e, ok := isConditionalCheckFailed(err); ok {
+			canceled = true
+			reasons[i] = types.CancellationReason{
+				Code:    aws.String("ConditionalCheckFailed"),
+				Message: aws.String("The conditional request failed"),
+				Item:    e.Item,
+			}
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		reasons[i] = types.CancellationReason{Code: aws.String("None")}
+		writes = append(writes, w)
+	}
+
+	if canceled {
+		return nil, errTransactionCanceled(reasons)
+	}
+
+	if err := unique(writes); err != nil {
+		return nil, errValidation("transaction request cannot include multiple operations on one item")
+	}
+
+	for _, w := range writes {
+		w.commit()
+	}
+
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
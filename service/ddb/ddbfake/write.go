@@ -0,0 +1,199 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+//
+// The file implements modification of single item, the modification is
+// prepared in two phases (evaluate and commit) so that transactions reuses it.
+//
+
+package ddbfake
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/dynamo/v3/internal/ddbexpr"
+)
+
+// request to modify single item
+type request struct {
+	table     *string
+	key       item
+	item      item
+	condition *string
+	update    *string
+	names     map[string]string
+	values    map[string]types.AttributeValue
+	onFailure types.ReturnValuesOnConditionCheckFailure
+}
+
+// write is prepared modification of the table
+type write struct {
+	table *table
+	key   string
+	item  item // nil item removes the key
+	check bool // condition check does not modify the table
+}
+
+func (w *write) commit() {
+	switch {
+	case w.check:
+		return
+	case w.item == nil:
+		delete(w.table.items, w.key)
+	default:
+		w.table.items[w.key] = w.item
+	}
+}
+
+// evaluate condition expression of the request against the item
+func (r request) check(exprs *expressions, obj item) error {
+	cond, err := exprs.condition("ConditionExpression", r.condition)
+	if err != nil {
+		return err
+	}
+
+	if err := exprs.unused(); err != nil {
+		return err
+	}
+
+	if !isTrue(cond, obj) {
+		if r.onFailure == types.ReturnValuesOnConditionCheckFailureAllOld {
+			return errConditionalCheckFailed(ddbexpr.Clone(obj))
+		}
+		return errConditionalCheckFailed(nil)
+	}
+
+	return nil
+}
+
+// prepareCheck evaluates ConditionCheck
+func (db *DynamoDB) prepareCheck(r request) (*write, error) {
+	t, err := db.tableOf(r.table)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := t.keyOnly(r.key)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.condition == nil {
+		return nil, errValidation("ConditionExpression must be defined")
+	}
+
+	exprs, err := newExpressions(r.names, r.values)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.check(exprs, t.items[key]); err != nil {
+		return nil, err
+	}
+
+	return &write{table: t, key: key, check: true}, nil
+}
+
+// preparePut evaluates PutItem
+func (db *DynamoDB) preparePut(r request) (*write, item, error) {
+	t, err := db.tableOf(r.table)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := t.keyOf(r.item)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exprs, err := newExpressions(r.names, r.values)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	old := t.items[key]
+	if err := r.check(exprs, old); err != nil {
+		return nil, nil, err
+	}
+
+	return &write{table: t, key: key, item: ddbexpr.Clone(r.item)}, old, nil
+}
+
+// prepareDelete evaluates DeleteItem
+func (db *DynamoDB) prepareDelete(r request) (*write, item, error) {
+	t, err := db.tableOf(r.table)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := t.keyOnly(r.key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exprs, err := newExpressions(r.names, r.values)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	old := t.items[key]
+	if err := r.check(exprs, old); err != nil {
+		return nil, nil, err
+	}
+
+	return &write{table: t, key: key}, old, nil
+}
+
+// prepareUpdate evaluates UpdateItem, the function returns paths modified
+// by the update expression
+func (db *DynamoDB) prepareUpdate(r request) (*write, item, []ddbexpr.Path, error) {
+	t, err := db.tableOf(r.table)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	key, err := t.keyOnly(r.key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	exprs, err := newExpressions(r.names, r.values)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	upd, err := exprs.update(r.update)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	paths := upd.Paths()
+	for _, path := range paths {
+		for _, attr := range t.schema.attributes() {
+			if path[0].Name == attr {
+				return nil, nil, nil, errValidation("cannot update attribute %s, this attribute is part of the key", attr)
+			}
+		}
+	}
+
+	old := t.items[key]
+	if err := r.check(exprs, old); err != nil {
+		return nil, nil, nil, err
+	}
+
+	obj := old
+	if obj == nil {
+		obj = ddbexpr.Clone(r.key)
+	}
+
+	obj, err = upd.Apply(obj)
+	if err != nil {
+		return nil, nil, nil, errValidation("%s", err)
+	}
+
+	return &write{table: t, key: key, item: obj}, old, paths, nil
+}
@@ -10,6 +10,7 @@ package ddb
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -25,6 +26,82 @@ import (
 //
 
 func UpdateFor[T dynamo.Thing, A any](attr ...string) UpdateExpression[T, A] {
+	ue, t := updateExpressionOf[T, A](attr)
+	if len(attr) > 1 {
+		leafOf[T, A](ue.key, t)
+	}
+	return ue
+}
+
+// UpdateMapFor declares builder of update expressions for entries of
+// the map attribute, V is type of map values. The map is referred by
+// the path as UpdateFor does, the entry is defined by the key.
+//
+//	var labels = ddb.UpdateMapFor[Person, string]("Labels")
+//
+//	labels.Key("owner").Set("verner") ⟼ SET labels.owner = :value
+//	labels.Key("owner").Remove()      ⟼ REMOVE labels.owner
+func UpdateMapFor[T dynamo.Thing, V any](attr ...string) UpdateMapExpression[T, V] {
+	ue, t := updateExpressionOf[T, map[string]V](attr)
+	if t.Kind() != reflect.Map || t.Key().Kind() != reflect.String || t.Elem() != reflect.TypeOf(new(V)).Elem() {
+		panic(fmt.Errorf("attribute %s of type %T is %s, map[string]%T is expected", ue.key, *new(T), t, *new(V)))
+	}
+
+	return UpdateMapExpression[T, V]{key: ue.key}
+}
+
+// UpdateMapExpression is builder of update expressions for map entries
+type UpdateMapExpression[T dynamo.Thing, V any] struct {
+	key documentPath
+}
+
+// Key returns builder of update expression for the map entry
+func (me UpdateMapExpression[T, V]) Key(key string) UpdateExpression[T, V] {
+	path := make(documentPath, 0, len(me.key)+1)
+	path = append(path, me.key...)
+	path = append(path, pathElement{name: key})
+
+	return UpdateExpression[T, V]{key: path}
+}
+
+// UpdateListFor declares builder of update expressions for elements of
+// the list attribute, V is type of list elements. The list is referred by
+// the path as UpdateFor does, the element is defined by the index.
+//
+//	var tags = ddb.UpdateListFor[Person, string]("Tags")
+//
+//	tags.At(3).Set("professor") ⟼ SET tags[3] = :value
+//	tags.At(3).Remove()         ⟼ REMOVE tags[3]
+func UpdateListFor[T dynamo.Thing, V any](attr ...string) UpdateListExpression[T, V] {
+	ue, t := updateExpressionOf[T, []V](attr)
+	if (t.Kind() != reflect.Slice && t.Kind() != reflect.Array) || t.Elem() != reflect.TypeOf(new(V)).Elem() {
+		panic(fmt.Errorf("attribute %s of type %T is %s, []%T is expected", ue.key, *new(T), t, *new(V)))
+	}
+
+	return UpdateListExpression[T, V]{key: ue.key}
+}
+
+// UpdateListExpression is builder of update expressions for list elements
+type UpdateListExpression[T dynamo.Thing, V any] struct {
+	key documentPath
+}
+
+// At returns builder of update expression for the list element
+func (le UpdateListExpression[T, V]) At(index int) UpdateExpression[T, V] {
+	if index < 0 {
+		panic(fmt.Errorf("invalid index %d of list %s", index, le.key))
+	}
+
+	path := make(documentPath, 0, len(le.key)+1)
+	path = append(path, le.key...)
+	path = append(path, pathElement{index: index})
+
+	return UpdateExpression[T, V]{key: path}
+}
+
+// resolves the path against the type, returns builder of update expression
+// and type of the attribute
+func updateExpressionOf[T dynamo.Thing, A any](attr []string) (UpdateExpression[T, A], reflect.Type) {
 	var seq hseq.Seq[T]
 
 	if len(attr) == 0 {
@@ -33,32 +110,230 @@ func UpdateFor[T dynamo.Thing, A any](attr ...string) UpdateExpression[T, A] {
 		seq = hseq.New[T](attr[0])
 	}
 
-	return hseq.FMap1(seq, newUpdateExpression[T, A])
+	ue := hseq.FMap1(seq, newUpdateExpression[T, A])
+	t := seq[0].Type
+	if len(attr) > 1 {
+		path, leaf, tag := nestedPathOf(seq[0].Type, attr[1:])
+		ue.key = append(ue.key, path...)
+		ue.setOf = setOfTag(string(tag))
+		t = leaf
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return ue, t
 }
 
 type UpdateItemExpression[T dynamo.Thing] struct {
 	entity  T
+	clauses *updateClauses
 	request *dynamodb.UpdateItemInput
+	err     error
 }
 
 func Updater[T dynamo.Thing](entity T, opts ...interface{ UpdateExpression(T) }) UpdateItemExpression[T] {
-	request := &dynamodb.UpdateItemInput{
-		ExpressionAttributeNames:  map[string]string{},
-		ExpressionAttributeValues: map[string]types.AttributeValue{},
-	}
+	clauses := newUpdateClauses()
 	for _, opt := range opts {
-		if ap, ok := opt.(interface {
-			Apply(*dynamodb.UpdateItemInput)
-		}); ok {
-			ap.Apply(request)
+		if ap, ok := opt.(interface{ Apply(*updateClauses) }); ok {
+			ap.Apply(clauses)
+		}
+	}
+
+	expr := UpdateItemExpression[T]{entity: entity, clauses: clauses, request: clauses.request()}
+	if len(clauses.errs) > 0 {
+		expr.err = errInvalidExpression(clauses.errs...)
+	}
+
+	return expr
+}
+
+// updateClauses collects clauses of update expression grouped by action.
+// Each clause refers attributes and values via unique placeholders so that
+// same attribute might be used by multiple clauses. Errors of clauses are
+// collected, the expression is not sent to DynamoDB if any.
+//
+//	https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/Expressions.UpdateExpressions.html
+type updateClauses struct {
+	set    []string
+	remove []string
+	add    []string
+	delete []string
+	names  map[string]string
+	values map[string]types.AttributeValue
+	paths  []documentPath
+	errs   []error
+
+	// SET clauses of SetNotExists and Inc, Dec are combined per attribute
+	// (e.g. #c = if_not_exists(#c,:z) + :i)
+	counters map[string]*updateCounter
+}
+
+// updateCounter is SET clause that combines initial value and increments
+type updateCounter struct {
+	at      int
+	key     string
+	initial string
+	incs    []string
+}
+
+func (c *updateCounter) expr() string {
+	base := c.key
+	if c.initial != "" {
+		base = c.initial
+	}
+
+	return c.key + " = " + base + strings.Join(c.incs, "")
+}
+
+func newUpdateClauses() *updateClauses {
+	return &updateClauses{
+		names:    map[string]string{},
+		values:   map[string]types.AttributeValue{},
+		counters: map[string]*updateCounter{},
+	}
+}
+
+// clone copies clauses so that the expression is reusable across requests
+func (u *updateClauses) clone() *updateClauses {
+	c := newUpdateClauses()
+	if u == nil {
+		return c
+	}
+
+	c.set = append(c.set, u.set...)
+	c.remove = append(c.remove, u.remove...)
+	c.add = append(c.add, u.add...)
+	c.delete = append(c.delete, u.delete...)
+	c.paths = append(c.paths, u.paths...)
+	c.errs = append(c.errs, u.errs...)
+	for k, v := range u.names {
+		c.names[k] = v
+	}
+	for k, v := range u.values {
+		c.values[k] = v
+	}
+	for k, v := range u.counters {
+		c.counters[k] = &updateCounter{
+			at:      v.at,
+			key:     v.key,
+			initial: v.initial,
+			incs:    append([]string{}, v.incs...),
+		}
+	}
+
+	return c
+}
+
+// counter combines initial value or increment with SET clause of the
+// attribute built by SetNotExists, Inc or Dec. Attribute cannot be
+// initialized twice.
+func (u *updateClauses) counter(key documentPath, initial func(string) string, inc func(string) string) {
+	c, has := u.counters[key.String()]
+	if has && (initial == nil || c.initial == "") {
+		if initial != nil {
+			c.initial = initial(c.key)
+		}
+		if inc != nil {
+			c.incs = append(c.incs, inc(c.key))
+		}
+		u.set[c.at] = c.expr()
+		return
+	}
+
+	c = &updateCounter{at: len(u.set), key: u.name(key)}
+	if initial != nil {
+		c.initial = initial(c.key)
+	}
+	if inc != nil {
+		c.incs = append(c.incs, inc(c.key))
+	}
+	u.counters[key.String()] = c
+	u.set = append(u.set, c.expr())
+}
+
+// name returns placeholder of document path. DynamoDB rejects expressions
+// that modify overlapping paths (e.g. a and a.b), the error is recorded.
+func (u *updateClauses) name(key documentPath) string {
+	for _, path := range u.paths {
+		if path.overlaps(key) {
+			u.fail(fmt.Errorf("%s: overlaps with %s", key, path))
+			break
+		}
+	}
+	u.paths = append(u.paths, key)
+
+	return key.expr("", u.names)
+}
+
+// fail records error of the clause
+func (u *updateClauses) fail(err error) {
+	u.errs = append(u.errs, err)
+}
+
+// check validates the clause is defined for the attribute and the value
+// matches the type of action, the error is recorded otherwise.
+func (u *updateClauses) check(key documentPath, action string, val types.AttributeValue, accept ...types.AttributeValue) bool {
+	if len(key) == 0 {
+		u.fail(errUndefinedAttribute)
+		return false
+	}
+
+	if val == nil || len(accept) == 0 {
+		return true
+	}
+
+	for _, x := range accept {
+		if reflect.TypeOf(x) == reflect.TypeOf(val) {
+			return true
 		}
 	}
 
-	if len(request.ExpressionAttributeValues) == 0 {
-		request.ExpressionAttributeValues = nil
+	u.fail(fmt.Errorf("%s: %s is not defined for %T", key, action, val))
+	return false
+}
+
+// value binds the value with unique placeholder
+func (u *updateClauses) value(key documentPath, val types.AttributeValue) string {
+	let := uniqueValueOf(u.values, "", key.slug())
+	u.values[let] = val
+	return let
+}
+
+// request renders the combined expression, actions are
+// SET ... REMOVE ... ADD ... DELETE ...
+func (u *updateClauses) request() *dynamodb.UpdateItemInput {
+	seq := make([]string, 0, 4)
+	for _, action := range []struct {
+		verb    string
+		clauses []string
+	}{
+		{"SET", u.set},
+		{"REMOVE", u.remove},
+		{"ADD", u.add},
+		{"DELETE", u.delete},
+	} {
+		if len(action.clauses) > 0 {
+			seq = append(seq, action.verb+" "+strings.Join(action.clauses, ","))
+		}
+	}
+
+	req := &dynamodb.UpdateItemInput{
+		ExpressionAttributeNames:  u.names,
+		ExpressionAttributeValues: u.values,
+	}
+
+	if len(seq) > 0 {
+		req.UpdateExpression = aws.String(strings.Join(seq, " "))
+	}
+
+	// Unfortunately empty maps are not accepted by DynamoDB
+	if len(req.ExpressionAttributeValues) == 0 {
+		req.ExpressionAttributeValues = nil
 	}
 
-	return UpdateItemExpression[T]{entity: entity, request: request}
+	return req
 }
 
 //
@@ -66,7 +341,7 @@ func Updater[T dynamo.Thing](entity T, opts ...interface{ UpdateExpression(T) })
 //
 
 type UpdateExpression[T dynamo.Thing, A any] struct {
-	key   string
+	key   documentPath
 	setOf string
 }
 
@@ -77,17 +352,33 @@ func newUpdateExpression[T dynamo.Thing, A any](t hseq.Type[T]) UpdateExpression
 	}
 
 	seq := strings.Split(tag, ",")
-	setOf := ""
+
+	return UpdateExpression[T, A]{key: attributePath(seq[0]), setOf: setOfTag(tag)}
+}
+
+// types of values accepted by ADD and DELETE actions
+var (
+	set = []types.AttributeValue{
+		&types.AttributeValueMemberSS{},
+		&types.AttributeValueMemberNS{},
+		&types.AttributeValueMemberBS{},
+	}
+
+	numberOrSet = append([]types.AttributeValue{&types.AttributeValueMemberN{}}, set...)
+)
+
+// setOfTag returns type of set declared by dynamodbav tag
+func setOfTag(tag string) string {
 	switch {
 	case strings.Contains(tag, "stringset"):
-		setOf = "string"
+		return "string"
 	case strings.Contains(tag, "numberset"):
-		setOf = "number"
+		return "number"
 	case strings.Contains(tag, "binaryset"):
-		setOf = "binary"
+		return "binary"
+	default:
+		return ""
 	}
-
-	return UpdateExpression[T, A]{key: seq[0], setOf: setOf}
 }
 
 // Set attribute
@@ -106,33 +397,33 @@ func (ue UpdateExpression[T, A]) SetNotExists(val A) interface{ UpdateExpression
 
 type updateSetter[T any, A any] struct {
 	notExists bool
-	key       string
+	key       documentPath
 	val       A
 }
 
 func (op updateSetter[T, A]) UpdateExpression(T) {}
 
-func (op updateSetter[T, A]) Apply(req *dynamodb.UpdateItemInput) {
+func (op updateSetter[T, A]) Apply(u *updateClauses) {
 	val, err := attributevalue.Marshal(op.val)
 	if err != nil {
+		u.fail(fmt.Errorf("%s: %w", op.key, err))
 		return
 	}
 
-	ekey := "#__" + op.key + "__"
-	eval := ":__" + op.key + "__"
+	if !u.check(op.key, "SET", val) {
+		return
+	}
 
-	req.ExpressionAttributeNames[ekey] = op.key
-	req.ExpressionAttributeValues[eval] = val
-	expr := ekey + " = " + eval
 	if op.notExists {
-		expr = ekey + " = if_not_exists(" + ekey + "," + eval + ")"
+		eval := u.value(op.key, val)
+		u.counter(op.key, func(ekey string) string { return "if_not_exists(" + ekey + "," + eval + ")" }, nil)
+		return
 	}
 
-	if req.UpdateExpression == nil {
-		req.UpdateExpression = aws.String("SET " + expr)
-	} else {
-		req.UpdateExpression = aws.String(*req.UpdateExpression + "," + expr)
-	}
+	ekey := u.name(op.key)
+	eval := u.value(op.key, val)
+
+	u.set = append(u.set, ekey+" = "+eval)
 }
 
 // Add new attribute and increment value
@@ -146,30 +437,27 @@ func (ue UpdateExpression[T, A]) Add(val A) interface{ UpdateExpression(T) } {
 }
 
 type updateAdder[T any, A any] struct {
-	key string
+	key documentPath
 	val A
 }
 
 func (op updateAdder[T, A]) UpdateExpression(T) {}
 
-func (op updateAdder[T, A]) Apply(req *dynamodb.UpdateItemInput) {
+func (op updateAdder[T, A]) Apply(u *updateClauses) {
 	val, err := attributevalue.Marshal(op.val)
 	if err != nil {
+		u.fail(fmt.Errorf("%s: %w", op.key, err))
 		return
 	}
 
-	ekey := "#__" + op.key + "__"
-	eval := ":__" + op.key + "__"
+	if !u.check(op.key, "ADD", val, numberOrSet...) {
+		return
+	}
 
-	req.ExpressionAttributeNames[ekey] = op.key
-	req.ExpressionAttributeValues[eval] = val
-	expr := ekey + " " + eval
+	ekey := u.name(op.key)
+	eval := u.value(op.key, val)
 
-	if req.UpdateExpression == nil {
-		req.UpdateExpression = aws.String("ADD " + expr)
-	} else {
-		req.UpdateExpression = aws.String(*req.UpdateExpression + "," + expr)
-	}
+	u.add = append(u.add, ekey+" "+eval)
 }
 
 // Add elements to set
@@ -199,29 +487,31 @@ func (ue UpdateExpression[T, A]) Minus(val A) interface{ UpdateExpression(T) } {
 type updateSetOf[T any, A any] struct {
 	op    string
 	setOf string
-	key   string
+	key   documentPath
 	val   A
 }
 
 func (op updateSetOf[T, A]) UpdateExpression(T) {}
 
-func (op updateSetOf[T, A]) Apply(req *dynamodb.UpdateItemInput) {
+func (op updateSetOf[T, A]) Apply(u *updateClauses) {
 	val, err := op.encodeValue()
 	if err != nil {
+		u.fail(fmt.Errorf("%s: %w", op.key, err))
 		return
 	}
 
-	ekey := "#__" + op.key + "__"
-	eval := ":__" + op.key + "__"
+	if !u.check(op.key, op.op, val, set...) {
+		return
+	}
 
-	req.ExpressionAttributeNames[ekey] = op.key
-	req.ExpressionAttributeValues[eval] = val
-	expr := ekey + " " + eval
+	ekey := u.name(op.key)
+	eval := u.value(op.key, val)
 
-	if req.UpdateExpression == nil {
-		req.UpdateExpression = aws.String(op.op + " " + expr)
-	} else {
-		req.UpdateExpression = aws.String(*req.UpdateExpression + "," + expr)
+	switch op.op {
+	case "DELETE":
+		u.delete = append(u.delete, ekey+" "+eval)
+	default:
+		u.add = append(u.add, ekey+" "+eval)
 	}
 }
 
@@ -309,29 +599,25 @@ func (ue UpdateExpression[T, A]) Dec(val A) interface{ UpdateExpression(T) } {
 
 type updateIncrement[T any, A any] struct {
 	op  string
-	key string
+	key documentPath
 	val A
 }
 
 func (op updateIncrement[T, A]) UpdateExpression(T) {}
 
-func (op updateIncrement[T, A]) Apply(req *dynamodb.UpdateItemInput) {
+func (op updateIncrement[T, A]) Apply(u *updateClauses) {
 	val, err := attributevalue.Marshal(op.val)
 	if err != nil {
+		u.fail(fmt.Errorf("%s: %w", op.key, err))
 		return
 	}
 
-	ekey := "#__" + op.key + "__"
-	eval := ":__" + op.key + "__"
-
-	req.ExpressionAttributeNames[ekey] = op.key
-	req.ExpressionAttributeValues[eval] = val
-
-	if req.UpdateExpression == nil {
-		req.UpdateExpression = aws.String("SET " + ekey + " = " + ekey + op.op + eval)
-	} else {
-		req.UpdateExpression = aws.String(*req.UpdateExpression + "," + ekey + " = " + ekey + op.op + eval)
+	if !u.check(op.key, "SET"+op.op, val, &types.AttributeValueMemberN{}) {
+		return
 	}
+
+	eval := u.value(op.key, val)
+	u.counter(op.key, nil, func(string) string { return op.op + eval })
 }
 
 // Append element to list
@@ -350,23 +636,25 @@ func (ue UpdateExpression[T, A]) Prepend(val A) interface{ UpdateExpression(T) }
 
 type updateAppender[T any, A any] struct {
 	append bool
-	key    string
+	key    documentPath
 	val    A
 }
 
 func (op updateAppender[T, A]) UpdateExpression(T) {}
 
-func (op updateAppender[T, A]) Apply(req *dynamodb.UpdateItemInput) {
+func (op updateAppender[T, A]) Apply(u *updateClauses) {
 	val, err := attributevalue.Marshal(op.val)
 	if err != nil {
+		u.fail(fmt.Errorf("%s: %w", op.key, err))
 		return
 	}
 
-	ekey := "#__" + op.key + "__"
-	eval := ":__" + op.key + "__"
+	if !u.check(op.key, "list_append", val, &types.AttributeValueMemberL{}) {
+		return
+	}
 
-	req.ExpressionAttributeNames[ekey] = op.key
-	req.ExpressionAttributeValues[eval] = val
+	ekey := u.name(op.key)
+	eval := u.value(op.key, val)
 
 	var cmd string
 	if op.append {
@@ -375,11 +663,7 @@ func (op updateAppender[T, A]) Apply(req *dynamodb.UpdateItemInput) {
 		cmd = "list_append(" + eval + "," + ekey + ")"
 	}
 
-	if req.UpdateExpression == nil {
-		req.UpdateExpression = aws.String("SET " + ekey + " = " + cmd)
-	} else {
-		req.UpdateExpression = aws.String(*req.UpdateExpression + "," + ekey + " = " + cmd)
-	}
+	u.set = append(u.set, ekey+" = "+cmd)
 }
 
 // Remove attribute
@@ -390,19 +674,15 @@ func (ue UpdateExpression[T, A]) Remove() interface{ UpdateExpression(T) } {
 }
 
 type updateRemover[T any] struct {
-	key string
+	key documentPath
 }
 
 func (op updateRemover[T]) UpdateExpression(T) {}
 
-func (op updateRemover[T]) Apply(req *dynamodb.UpdateItemInput) {
-	ekey := "#__" + op.key + "__"
-
-	req.ExpressionAttributeNames[ekey] = op.key
-
-	if req.UpdateExpression == nil {
-		req.UpdateExpression = aws.String("REMOVE " + ekey)
-	} else {
-		req.UpdateExpression = aws.String(*req.UpdateExpression + "," + ekey)
+func (op updateRemover[T]) Apply(u *updateClauses) {
+	if !u.check(op.key, "REMOVE", nil) {
+		return
 	}
+
+	u.remove = append(u.remove, u.name(op.key))
 }
@@ -9,6 +9,7 @@
 package ddb
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -233,3 +234,159 @@ func TestUpdateExpressionRemoveAttributeFew(t *testing.T) {
 		Should(it.Map(n).Have("#__anothernone__", "anothernone")).
 		Should(it.Equal(e, "REMOVE #__anothername__,#__anothernone__"))
 }
+
+func TestUpdateExpressionNestedPath(t *testing.T) {
+	val := tNested{}
+	dsl := Updater(val,
+		UpdateFor[tNested, string]("Address", "City").Set("Berne"),
+		UpdateFor[tNested, string]("Labels", "owner").Set("verner"),
+		UpdateFor[tNested, string]("Tags", "3").Set("x"),
+	)
+	n := dsl.request.ExpressionAttributeNames
+	v := dsl.request.ExpressionAttributeValues
+	e := *dsl.request.UpdateExpression
+
+	it.Then(t).
+		Should(it.Map(n).Have("#__address__", "address")).
+		Should(it.Map(n).Have("#__city__", "city")).
+		Should(it.Map(n).Have("#__labels__", "labels")).
+		Should(it.Map(n).Have("#__owner__", "owner")).
+		Should(it.Map(n).Have("#__tags__", "tags")).
+		Should(it.Map(v).Have(":__address_city__", &types.AttributeValueMemberS{Value: "Berne"})).
+		Should(it.Map(v).Have(":__labels_owner__", &types.AttributeValueMemberS{Value: "verner"})).
+		Should(it.Map(v).Have(":__tags_3__", &types.AttributeValueMemberS{Value: "x"})).
+		Should(it.Equal(e, "SET #__address__.#__city__ = :__address_city__,#__labels__.#__owner__ = :__labels_owner__,#__tags__[3] = :__tags_3__"))
+}
+
+func TestUpdateExpressionMixed(t *testing.T) {
+	val := tUpdatable{}
+	dsl := Updater(val,
+		dslSSet.Minus([]string{"a"}),
+		dslNone.Add(1),
+		dslList.Remove(),
+		dslName.Set("some"),
+		dslNSet.Union([]int{1}),
+	)
+	e := *dsl.request.UpdateExpression
+
+	it.Then(t).Should(
+		it.Equal(e, "SET #__anothername__ = :__anothername__ REMOVE #__anotherlist__ ADD #__anothernone__ :__anothernone__,#__anothernset__ :__anothernset__ DELETE #__anothersset__ :__anothersset__"),
+	)
+}
+
+func TestUpdateExpressionUniqueValues(t *testing.T) {
+	val := tUpdatable{}
+	dsl := Updater(val, dslNone.Inc(1), dslNone.SetNotExists(0))
+	v := dsl.request.ExpressionAttributeValues
+	e := *dsl.request.UpdateExpression
+
+	it.Then(t).
+		Should(it.Map(v).Have(":__anothernone__", &types.AttributeValueMemberN{Value: "1"})).
+		Should(it.Map(v).Have(":__anothernone_1__", &types.AttributeValueMemberN{Value: "0"})).
+		Should(it.Equal(e, "SET #__anothernone__ = if_not_exists(#__anothernone__,:__anothernone_1__) + :__anothernone__"))
+}
+
+func TestUpdateExpressionCounter(t *testing.T) {
+	val := tUpdatable{}
+
+	t.Run("Inc", func(t *testing.T) {
+		dsl := Updater(val, dslNone.SetNotExists(0), dslNone.Inc(1), dslNone.Dec(2))
+		it.Then(t).
+			Should(it.Nil(dsl.err)).
+			Should(it.Equal(*dsl.request.UpdateExpression, "SET #__anothernone__ = if_not_exists(#__anothernone__,:__anothernone__) + :__anothernone_1__ - :__anothernone_2__"))
+	})
+
+	t.Run("Overlap", func(t *testing.T) {
+		it.Then(t).
+			Should(it.True(Updater(val, dslNone.SetNotExists(0), dslNone.SetNotExists(1)).err != nil)).
+			Should(it.True(Updater(val, dslNone.Set(0), dslNone.Inc(1)).err != nil)).
+			Should(it.True(Updater(val, dslNone.Inc(1), dslNone.Set(0)).err != nil))
+	})
+}
+
+func TestUpdateExpressionMapEntry(t *testing.T) {
+	labels := UpdateMapFor[tNested, string]("Labels")
+	counters := UpdateMapFor[tNested, int]()
+
+	val := tNested{}
+	dsl := Updater(val,
+		labels.Key("owner").Set("verner"),
+		labels.Key("owner-id").Remove(),
+		counters.Key("hits").Inc(1),
+	)
+	n := dsl.request.ExpressionAttributeNames
+	v := dsl.request.ExpressionAttributeValues
+	e := *dsl.request.UpdateExpression
+
+	it.Then(t).
+		Should(it.Nil(dsl.err)).
+		Should(it.Map(n).Have("#__labels__", "labels")).
+		Should(it.Map(n).Have("#__owner__", "owner")).
+		Should(it.Map(n).Have("#__owner_id__", "owner-id")).
+		Should(it.Map(n).Have("#__counters__", "counters")).
+		Should(it.Map(n).Have("#__hits__", "hits")).
+		Should(it.Map(v).Have(":__labels_owner__", &types.AttributeValueMemberS{Value: "verner"})).
+		Should(it.Map(v).Have(":__counters_hits__", &types.AttributeValueMemberN{Value: "1"})).
+		Should(it.Equal(e, "SET #__labels__.#__owner__ = :__labels_owner__,#__counters__.#__hits__ = #__counters__.#__hits__ + :__counters_hits__ REMOVE #__labels__.#__owner_id__"))
+}
+
+func TestUpdateExpressionListElement(t *testing.T) {
+	tags := UpdateListFor[tNested, string]("Tags")
+
+	val := tNested{}
+	dsl := Updater(val, tags.At(1).Set("agent"), tags.At(3).Remove())
+	v := dsl.request.ExpressionAttributeValues
+	e := *dsl.request.UpdateExpression
+
+	it.Then(t).
+		Should(it.Nil(dsl.err)).
+		Should(it.Map(v).Have(":__tags_1__", &types.AttributeValueMemberS{Value: "agent"})).
+		Should(it.Equal(e, "SET #__tags__[1] = :__tags_1__ REMOVE #__tags__[3]"))
+}
+
+func TestUpdateExpressionNestedAdd(t *testing.T) {
+	counters := UpdateMapFor[tNested, int]("Counters")
+
+	dsl := Updater(tNested{}, counters.Key("hits").Add(1))
+	v := dsl.request.ExpressionAttributeValues
+	e := *dsl.request.UpdateExpression
+
+	it.Then(t).
+		Should(it.Nil(dsl.err)).
+		Should(it.Map(v).Have(":__counters_hits__", &types.AttributeValueMemberN{Value: "1"})).
+		Should(it.Equal(e, "ADD #__counters__.#__hits__ :__counters_hits__"))
+}
+
+func TestUpdateExpressionTypeSafety(t *testing.T) {
+	panics := func(f func()) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%v", r)
+			}
+		}()
+		f()
+		return nil
+	}
+
+	it.Then(t).
+		Should(it.Fail(func() error {
+			return panics(func() { UpdateMapFor[tNested, int]("Labels") })
+		})).
+		Should(it.Fail(func() error {
+			return panics(func() { UpdateListFor[tNested, int]("Tags") })
+		})).
+		Should(it.Fail(func() error {
+			return panics(func() { UpdateFor[tNested, int]("Address", "City") })
+		})).
+		Should(it.Fail(func() error {
+			return panics(func() { UpdateFor[tNested, int]("Labels", "owner") })
+		})).
+		Should(it.Fail(func() error {
+			return panics(func() { ClauseFor[tNested, int]("Address", "City") })
+		})).
+		Should(it.Fail(func() error {
+			return panics(func() { ClauseFor[tNested, int]("Tags", "[1]") })
+		})).
+		Should(it.Nil(panics(func() { UpdateFor[tNested, string]("Address", "City") }))).
+		Should(it.Nil(panics(func() { ClauseFor[tNested, int]("Counters", "hits") })))
+}
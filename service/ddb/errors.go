@@ -11,7 +11,10 @@ package ddb
 import (
 	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/fogfish/dynamo/v3"
 	"github.com/fogfish/faults"
 )
@@ -21,6 +24,7 @@ const (
 	errServiceIO      = faults.Type("service i/o failed")
 	errInvalidKey     = faults.Type("invalid key")
 	errInvalidEntity  = faults.Type("invalid entity")
+	errInvalidRequest = faults.Type("invalid request")
 )
 
 // NotFound is an error to handle unknown elements
@@ -41,6 +45,32 @@ func (e *notFound) Unwrap() error { return e.err }
 
 func (e *notFound) NotFound() string { return e.HashKey().Safe() + " " + e.SortKey().Safe() }
 
+// errNotFoundKeys is an error to handle unknown elements of batch operations
+func errNotFoundKeys(err error, keys []dynamo.Thing) error {
+	return &notFoundKeys{keys: keys, err: err}
+}
+
+type notFoundKeys struct {
+	keys []dynamo.Thing
+	err  error
+}
+
+func (e *notFoundKeys) Error() string {
+	return fmt.Sprintf("Not Found %d items", len(e.keys))
+}
+
+func (e *notFoundKeys) Unwrap() error { return e.err }
+
+func (e *notFoundKeys) NotFound() string {
+	seq := make([]string, len(e.keys))
+	for i, key := range e.keys {
+		seq[i] = key.HashKey().Safe() + " " + key.SortKey().Safe()
+	}
+	return strings.Join(seq, ", ")
+}
+
+func (e *notFoundKeys) NotFoundKeys() []dynamo.Thing { return e.keys }
+
 // errPreConditionFailed
 func errPreConditionFailed(err error, thing dynamo.Thing, conflict bool, gone bool) error {
 	return &preConditionFailed{Thing: thing, conflict: conflict, gone: gone, err: err}
@@ -48,6 +78,7 @@ func errPreConditionFailed(err error, thing dynamo.Thing, conflict bool, gone bo
 
 type preConditionFailed struct {
 	dynamo.Thing
+	item     dynamo.Thing
 	conflict bool
 	gone     bool
 	err      error
@@ -65,6 +96,85 @@ func (e *preConditionFailed) Gone() bool { return e.gone }
 
 func (e *preConditionFailed) Unwrap() error { return e.err }
 
+// Item returns the current item at storage if the write demands
+// ReturnValuesOnConditionCheckFailure, nil otherwise.
+func (e *preConditionFailed) Item() dynamo.Thing { return e.item }
+
+// errConditionalCheckFailed builds errPreConditionFailed from the condition expression
+func errConditionalCheckFailed(err error, thing dynamo.Thing, conditionExpression *string) error {
+	expr := aws.ToString(conditionExpression)
+
+	return errPreConditionFailed(err, thing,
+		strings.Contains(expr, "attribute_not_exists") || strings.Contains(expr, "="),
+		strings.Contains(expr, "attribute_exists") || strings.Contains(expr, "<>"),
+	)
+}
+
+// conditionalCheckFailed builds errPreConditionFailed, the error carries
+// the current item if the request demands ReturnValuesOnConditionCheckFailure
+func (db *Storage[T]) conditionalCheckFailed(err error, thing dynamo.Thing, conditionExpression *string) error {
+	failure := errConditionalCheckFailed(err, thing, conditionExpression)
+
+	if gen := recoverConditionalCheckFailedItem(err); len(gen) != 0 {
+		if item, err := db.codec.Decode(gen); err == nil {
+			failure.(*preConditionFailed).item = item
+		}
+	}
+
+	return failure
+}
+
+// errUnprocessed is an error to handle items left unprocessed by batch operations
+func errUnprocessed(err error, keys []dynamo.Thing) error {
+	return &unprocessed{keys: keys, err: err}
+}
+
+type unprocessed struct {
+	keys []dynamo.Thing
+	err  error
+}
+
+func (e *unprocessed) Error() string {
+	return fmt.Sprintf("Unprocessed %d items", len(e.keys))
+}
+
+func (e *unprocessed) Unwrap() error { return e.err }
+
+func (e *unprocessed) Unprocessed() []dynamo.Thing { return e.keys }
+
+// errInvalidExpression is an error to handle condition, filter or update
+// expressions that cannot be built. It is returned before any request is
+// sent to DynamoDB.
+func errInvalidExpression(errs ...error) error {
+	return &invalidExpression{errs: errs}
+}
+
+type invalidExpression struct {
+	errs []error
+}
+
+func (e *invalidExpression) Error() string {
+	seq := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		seq[i] = err.Error()
+	}
+	return fmt.Sprintf("Invalid Expression (%s)", strings.Join(seq, "; "))
+}
+
+func (e *invalidExpression) Unwrap() []error { return e.errs }
+
+func (e *invalidExpression) InvalidExpression() bool { return true }
+
+// errConsistentReadIndex is an error of consistent read from global secondary index
+func errConsistentReadIndex(index string) error {
+	return fmt.Errorf("consistent read is not supported by global secondary index %s", index)
+}
+
+// errTotalSegments is an error of invalid number of segments for parallel scan
+func errTotalSegments(totalSegments int32) error {
+	return fmt.Errorf("total segments %d is out of range [1, %d]", totalSegments, maxTotalSegments)
+}
+
 // recover AWS ErrorCode
 func recoverConditionalCheckFailedException(err error) bool {
 	var e interface{ ErrorCode() string }
@@ -72,3 +182,32 @@ func recoverConditionalCheckFailedException(err error) bool {
 	ok := errors.As(err, &e)
 	return ok && e.ErrorCode() == "ConditionalCheckFailedException"
 }
+
+// recover item returned by failed condition check
+func recoverConditionalCheckFailedItem(err error) map[string]types.AttributeValue {
+	var e *types.ConditionalCheckFailedException
+
+	if !errors.As(err, &e) {
+		return nil
+	}
+
+	return e.Item
+}
+
+// recover index and current item of element that cancels transaction due to
+// failed condition
+func recoverTransactionCanceledException(err error) (int, map[string]types.AttributeValue, bool) {
+	var e *types.TransactionCanceledException
+
+	if !errors.As(err, &e) {
+		return -1, nil, false
+	}
+
+	for i, reason := range e.CancellationReasons {
+		if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+			return i, reason.Item, true
+		}
+	}
+
+	return -1, nil, false
+}
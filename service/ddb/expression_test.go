@@ -0,0 +1,1037 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package ddb_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/curie"
+	"github.com/fogfish/dynamo/v3"
+	"github.com/fogfish/dynamo/v3/service/ddb"
+	"github.com/fogfish/dynamo/v3/service/ddb/ddbfake"
+	"github.com/fogfish/it"
+)
+
+type profile struct {
+	Prefix curie.IRI `dynamodbav:"prefix,omitempty"`
+	Suffix curie.IRI `dynamodbav:"suffix,omitempty"`
+	Name   string    `dynamodbav:"name,omitempty"`
+	Age    int       `dynamodbav:"age,omitempty"`
+	Tags   []string  `dynamodbav:"tags,omitempty"`
+	Labels []string  `dynamodbav:"labels,omitempty,stringset"`
+	Scores []int     `dynamodbav:"scores,omitempty,numberset"`
+	Blobs  [][]byte  `dynamodbav:"blobs,omitempty,binaryset"`
+}
+
+func (p profile) HashKey() curie.IRI { return p.Prefix }
+func (p profile) SortKey() curie.IRI { return p.Suffix }
+
+func profileKey() profile {
+	return profile{Prefix: curie.New("dead:beef"), Suffix: curie.New("1")}
+}
+
+func profileFixture() profile {
+	return profile{
+		Prefix: curie.New("dead:beef"),
+		Suffix: curie.New("1"),
+		Name:   "Verner Pleishner",
+		Age:    64,
+		Tags:   []string{"spy", "professor"},
+		Labels: []string{"a", "b"},
+		Scores: []int{1, 2},
+		Blobs:  [][]byte{[]byte("a"), []byte("b")},
+	}
+}
+
+func fake[T dynamo.Thing](t *testing.T, opts ...ddb.Option) *ddb.Storage[T] {
+	t.Helper()
+
+	service := ddbfake.New(
+		ddbfake.WithTable("test", "prefix", "suffix"),
+	)
+
+	db, err := ddb.New[T](append([]ddb.Option{ddb.WithTable("test"), ddb.WithService(service)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestExpressionCondition(t *testing.T) {
+	var (
+		name = ddb.ClauseFor[profile, string]("Name")
+		age  = ddb.ClauseFor[profile, int]("Age")
+		tags = ddb.ClauseFor[profile, string]("Tags")
+		none = ddb.ClauseFor[profile, string]("Name")
+	)
+
+	for spec, tc := range map[string]struct {
+		cond interface{ WriterOpt(profile) }
+		pass bool
+	}{
+		"Eq":           {name.Eq("Verner Pleishner"), true},
+		"Eq/Fail":      {name.Eq("Eduard"), false},
+		"Ne":           {name.Ne("Eduard"), true},
+		"Ne/Fail":      {name.Ne("Verner Pleishner"), false},
+		"Lt":           {age.Lt(65), true},
+		"Lt/Fail":      {age.Lt(64), false},
+		"Le":           {age.Le(64), true},
+		"Le/Fail":      {age.Le(63), false},
+		"Gt":           {age.Gt(63), true},
+		"Gt/Fail":      {age.Gt(64), false},
+		"Ge":           {age.Ge(64), true},
+		"Ge/Fail":      {age.Ge(65), false},
+		"Exists":       {name.Exists(), true},
+		"NotExists":    {none.NotExists(), false},
+		"Is":           {name.Is("Verner Pleishner"), true},
+		"Is/Fail":      {name.Is("_"), false},
+		"Between":      {age.Between(60, 70), true},
+		"Between/Fail": {age.Between(65, 70), false},
+		"In":           {age.In(63, 64, 65), true},
+		"In/Fail":      {age.In(63, 65), false},
+		"HasPrefix":    {name.HasPrefix("Verner"), true},
+		"HasPrefix/No": {name.HasPrefix("Pleishner"), false},
+		"Contains":     {tags.Contains("spy"), true},
+		"Contains/No":  {tags.Contains("agent"), false},
+		"Or":           {ddb.Or(name.Eq("Eduard"), age.Eq(64)), true},
+		"Or/Fail":      {ddb.Or(name.Eq("Eduard"), age.Eq(65)), false},
+		"And":          {ddb.And(age.Gt(60), age.Lt(70)), true},
+		"And/Fail":     {ddb.And(age.Gt(60), age.Lt(64)), false},
+		"Not":          {ddb.Not(name.Eq("Eduard")), true},
+		"Not/Fail":     {ddb.Not(age.Between(60, 70)), false},
+		"Nested":       {ddb.Or(ddb.And(name.Eq("Eduard"), age.Eq(64)), ddb.Not(none.NotExists())), true},
+		"Nested/Fail":  {ddb.Not(ddb.Or(ddb.And(name.Exists(), age.In(64)), name.Eq("Eduard"))), false},
+	} {
+		t.Run(spec, func(t *testing.T) {
+			db := fake[profile](t)
+			it.Ok(t).If(db.Put(context.Background(), profileFixture())).Should().Equal(nil)
+
+			err := db.Put(context.Background(), profileFixture(), tc.cond)
+			if tc.pass {
+				it.Ok(t).If(err).Should().Equal(nil)
+			} else {
+				_, ok := err.(interface{ PreConditionFailed() bool })
+				it.Ok(t).IfTrue(ok)
+			}
+		})
+	}
+
+	t.Run("SameAttribute", func(t *testing.T) {
+		db := fake[profile](t)
+		it.Ok(t).If(db.Put(context.Background(), profileFixture())).Should().Equal(nil)
+
+		err := db.Put(context.Background(), profileFixture(), age.Gt(63), age.Lt(64))
+		_, ok := err.(interface{ PreConditionFailed() bool })
+		it.Ok(t).IfTrue(ok)
+
+		err = db.Put(context.Background(), profileFixture(), age.Gt(63), age.Lt(65), age.Ne(0))
+		it.Ok(t).If(err).Should().Equal(nil)
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		db := fake[profile](t)
+
+		success := db.Put(context.Background(), profileFixture(), name.NotExists())
+		failure := db.Put(context.Background(), profileFixture(), name.NotExists())
+		e, ok := failure.(interface{ Conflict() bool })
+
+		it.Ok(t).
+			If(success).Should().Equal(nil).
+			IfTrue(ok).
+			IfTrue(e.Conflict())
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		db := fake[profile](t)
+		it.Ok(t).If(db.Put(context.Background(), profileFixture())).Should().Equal(nil)
+
+		_, failure := db.Remove(context.Background(), profileKey(), name.Eq("Eduard"))
+		_, success := db.Remove(context.Background(), profileKey(), name.Eq("Verner Pleishner"))
+		_, notfound := db.Get(context.Background(), profileKey())
+		_, isnfe := notfound.(interface{ NotFound() string })
+
+		it.Ok(t).
+			IfNotNil(failure).
+			If(success).Should().Equal(nil).
+			IfTrue(isnfe)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		db := fake[profile](t)
+		it.Ok(t).If(db.Put(context.Background(), profileFixture())).Should().Equal(nil)
+
+		patch := profileKey()
+		patch.Age = 65
+
+		val, success := db.Update(context.Background(), patch, age.Eq(64))
+		_, failure := db.Update(context.Background(), patch, age.Eq(64))
+
+		it.Ok(t).
+			If(success).Should().Equal(nil).
+			If(val.Age).Should().Equal(65).
+			If(val.Name).Should().Equal("Verner Pleishner").
+			IfNotNil(failure)
+	})
+}
+
+func TestExpressionUpdate(t *testing.T) {
+	var (
+		name   = ddb.UpdateFor[profile, string]("Name")
+		age    = ddb.UpdateFor[profile, int]("Age")
+		tags   = ddb.UpdateFor[profile, []string]("Tags")
+		labels = ddb.UpdateFor[profile, []string]("Labels")
+		scores = ddb.UpdateFor[profile, []int]("Scores")
+		blobs  = ddb.UpdateFor[profile, [][]byte]("Blobs")
+	)
+
+	for spec, tc := range map[string]struct {
+		expr   interface{ UpdateExpression(profile) }
+		expect func(profile) profile
+	}{
+		"Set": {name.Set("Eduard"), func(p profile) profile {
+			p.Name = "Eduard"
+			return p
+		}},
+		"SetNotExists": {name.SetNotExists("Eduard"), func(p profile) profile {
+			return p
+		}},
+		"Add": {age.Add(1), func(p profile) profile {
+			p.Age = 65
+			return p
+		}},
+		"Inc": {age.Inc(2), func(p profile) profile {
+			p.Age = 66
+			return p
+		}},
+		"Dec": {age.Dec(2), func(p profile) profile {
+			p.Age = 62
+			return p
+		}},
+		"Append": {tags.Append([]string{"agent"}), func(p profile) profile {
+			p.Tags = []string{"spy", "professor", "agent"}
+			return p
+		}},
+		"Prepend": {tags.Prepend([]string{"agent"}), func(p profile) profile {
+			p.Tags = []string{"agent", "spy", "professor"}
+			return p
+		}},
+		"Remove": {name.Remove(), func(p profile) profile {
+			p.Name = ""
+			return p
+		}},
+		"Union/SS": {labels.Union([]string{"b", "c"}), func(p profile) profile {
+			p.Labels = []string{"a", "b", "c"}
+			return p
+		}},
+		"Minus/SS": {labels.Minus([]string{"a"}), func(p profile) profile {
+			p.Labels = []string{"b"}
+			return p
+		}},
+		"Union/NS": {scores.Union([]int{3}), func(p profile) profile {
+			p.Scores = []int{1, 2, 3}
+			return p
+		}},
+		"Minus/NS": {scores.Minus([]int{1, 2}), func(p profile) profile {
+			p.Scores = nil
+			return p
+		}},
+		"Union/BS": {blobs.Union([][]byte{[]byte("c")}), func(p profile) profile {
+			p.Blobs = [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+			return p
+		}},
+		"Minus/BS": {blobs.Minus([][]byte{[]byte("b")}), func(p profile) profile {
+			p.Blobs = [][]byte{[]byte("a")}
+			return p
+		}},
+	} {
+		t.Run(spec, func(t *testing.T) {
+			db := fake[profile](t)
+			it.Ok(t).If(db.Put(context.Background(), profileFixture())).Should().Equal(nil)
+
+			val, err := db.UpdateWith(context.Background(), ddb.Updater(profileKey(), tc.expr))
+			it.Ok(t).
+				If(err).Should().Equal(nil).
+				If(val).Should().Equal(tc.expect(profileFixture()))
+
+			obj, err := db.Get(context.Background(), profileKey())
+			it.Ok(t).
+				If(err).Should().Equal(nil).
+				If(obj).Should().Equal(val)
+		})
+	}
+
+	t.Run("SetNotExists/Undefined", func(t *testing.T) {
+		db := fake[profile](t)
+
+		val, err := db.UpdateWith(context.Background(), ddb.Updater(profileKey(), name.SetNotExists("Eduard")))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val.Name).Should().Equal("Eduard")
+	})
+
+	t.Run("Add/Undefined", func(t *testing.T) {
+		db := fake[profile](t)
+
+		val, err := db.UpdateWith(context.Background(), ddb.Updater(profileKey(), age.Add(1)))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val.Age).Should().Equal(1)
+	})
+
+	t.Run("Inc/Undefined", func(t *testing.T) {
+		db := fake[profile](t)
+
+		_, err := db.UpdateWith(context.Background(), ddb.Updater(profileKey(), age.Inc(1)))
+		it.Ok(t).IfNotNil(err)
+	})
+
+	t.Run("Mixed", func(t *testing.T) {
+		db := fake[profile](t)
+		it.Ok(t).If(db.Put(context.Background(), profileFixture())).Should().Equal(nil)
+
+		val, err := db.UpdateWith(context.Background(),
+			ddb.Updater(profileKey(),
+				labels.Minus([]string{"a"}),
+				tags.Remove(),
+				age.Add(1),
+				name.Set("Eduard"),
+				scores.Union([]int{3}),
+			),
+		)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val.Name).Should().Equal("Eduard").
+			If(val.Age).Should().Equal(65).
+			If(val.Tags).Should().Equal([]string(nil)).
+			If(val.Labels).Should().Equal([]string{"b"}).
+			If(val.Scores).Should().Equal([]int{1, 2, 3})
+	})
+
+	t.Run("Reuse", func(t *testing.T) {
+		db := fake[profile](t)
+		it.Ok(t).If(db.Put(context.Background(), profileFixture())).Should().Equal(nil)
+
+		expr := ddb.Updater(profileKey(), name.Remove())
+		_, err := db.UpdateWith(context.Background(), expr,
+			ddb.ClauseFor[profile, string]("Name").Eq("Verner Pleishner"),
+		)
+		it.Ok(t).If(err).Should().Equal(nil)
+
+		// condition of previous request is not applied
+		_, err = db.UpdateWith(context.Background(), expr)
+		it.Ok(t).If(err).Should().Equal(nil)
+	})
+
+	t.Run("Few", func(t *testing.T) {
+		db := fake[profile](t)
+		it.Ok(t).If(db.Put(context.Background(), profileFixture())).Should().Equal(nil)
+
+		val, err := db.UpdateWith(context.Background(),
+			ddb.Updater(profileKey(), name.Set("Eduard"), age.Inc(1)),
+			ddb.ClauseFor[profile, int]("Age").Eq(64),
+		)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val.Name).Should().Equal("Eduard").
+			If(val.Age).Should().Equal(65)
+	})
+}
+
+type resident struct {
+	Prefix   curie.IRI          `dynamodbav:"prefix,omitempty"`
+	Suffix   curie.IRI          `dynamodbav:"suffix,omitempty"`
+	Address  address            `dynamodbav:"address,omitempty"`
+	Labels   map[string]string  `dynamodbav:"labels,omitempty"`
+	Tags     []string           `dynamodbav:"tags,omitempty"`
+	Counters map[string]int     `dynamodbav:"counters,omitempty"`
+	Groups   map[string]members `dynamodbav:"groups,omitempty"`
+}
+
+// members is a string set, the type is required to encode sets as map values
+type members []string
+
+func (seq members) MarshalDynamoDBAttributeValue() (types.AttributeValue, error) {
+	return &types.AttributeValueMemberSS{Value: seq}, nil
+}
+
+type address struct {
+	City   string `dynamodbav:"city,omitempty"`
+	Street string `dynamodbav:"street,omitempty"`
+}
+
+func (p resident) HashKey() curie.IRI { return p.Prefix }
+func (p resident) SortKey() curie.IRI { return p.Suffix }
+
+func TestExpressionNestedPath(t *testing.T) {
+	var (
+		cityIs = ddb.ClauseFor[resident, string]("Address", "City")
+		city   = ddb.UpdateFor[resident, string]("Address", "City")
+		label  = ddb.UpdateFor[resident, string]("Labels", "owner")
+		tag    = ddb.UpdateFor[resident, string]("Tags", "1")
+	)
+
+	key := resident{Prefix: curie.New("dead:beef"), Suffix: curie.New("1")}
+	fixture := resident{
+		Prefix:  curie.New("dead:beef"),
+		Suffix:  curie.New("1"),
+		Address: address{City: "Berne", Street: "Blumenstrasse 14"},
+		Labels:  map[string]string{"owner": "verner"},
+		Tags:    []string{"spy", "professor"},
+	}
+
+	db := fake[resident](t)
+	it.Ok(t).If(db.Put(context.Background(), fixture)).Should().Equal(nil)
+
+	val, err := db.UpdateWith(context.Background(),
+		ddb.Updater(key, city.Set("Zurich"), label.Set("eduard"), tag.Set("agent")),
+		cityIs.Eq("Berne"),
+	)
+	it.Ok(t).
+		If(err).Should().Equal(nil).
+		If(val.Address).Should().Equal(address{City: "Zurich", Street: "Blumenstrasse 14"}).
+		If(val.Labels).Should().Equal(map[string]string{"owner": "eduard"}).
+		If(val.Tags).Should().Equal([]string{"spy", "agent"})
+
+	_, err = db.UpdateWith(context.Background(),
+		ddb.Updater(key, city.Set("Berne")),
+		cityIs.Eq("Berne"),
+	)
+	_, ok := err.(interface{ PreConditionFailed() bool })
+	it.Ok(t).IfTrue(ok)
+}
+
+func TestExpressionMapAndList(t *testing.T) {
+	var (
+		labels   = ddb.UpdateMapFor[resident, string]("Labels")
+		counters = ddb.UpdateMapFor[resident, int]("Counters")
+		groups   = ddb.UpdateMapFor[resident, members]("Groups")
+		tags     = ddb.UpdateListFor[resident, string]("Tags")
+	)
+
+	key := resident{Prefix: curie.New("dead:beef"), Suffix: curie.New("1")}
+	fixture := func() resident {
+		return resident{
+			Prefix:   curie.New("dead:beef"),
+			Suffix:   curie.New("1"),
+			Labels:   map[string]string{"owner": "verner", "role": "spy"},
+			Tags:     []string{"spy", "professor", "agent"},
+			Counters: map[string]int{"hits": 10},
+			Groups:   map[string]members{"staff": {"verner", "eduard"}},
+		}
+	}
+
+	for spec, tc := range map[string]struct {
+		expr   interface{ UpdateExpression(resident) }
+		expect func(resident) resident
+	}{
+		"Map/Set": {labels.Key("city").Set("Berne"), func(p resident) resident {
+			p.Labels["city"] = "Berne"
+			return p
+		}},
+		"Map/Remove": {labels.Key("role").Remove(), func(p resident) resident {
+			delete(p.Labels, "role")
+			return p
+		}},
+		"Map/Inc": {counters.Key("hits").Inc(5), func(p resident) resident {
+			p.Counters["hits"] = 15
+			return p
+		}},
+		"Map/SetNotExists": {counters.Key("views").SetNotExists(1), func(p resident) resident {
+			p.Counters["views"] = 1
+			return p
+		}},
+		"Map/Add": {counters.Key("hits").Add(5), func(p resident) resident {
+			p.Counters["hits"] = 15
+			return p
+		}},
+		"Map/AddNotExists": {counters.Key("views").Add(1), func(p resident) resident {
+			p.Counters["views"] = 1
+			return p
+		}},
+		"Map/Union": {groups.Key("staff").Union(members{"ilse"}), func(p resident) resident {
+			p.Groups["staff"] = members{"verner", "eduard", "ilse"}
+			return p
+		}},
+		"Map/Minus": {groups.Key("staff").Minus(members{"verner"}), func(p resident) resident {
+			p.Groups["staff"] = members{"eduard"}
+			return p
+		}},
+		"List/Set": {tags.At(1).Set("teacher"), func(p resident) resident {
+			p.Tags[1] = "teacher"
+			return p
+		}},
+		"List/Remove": {tags.At(0).Remove(), func(p resident) resident {
+			p.Tags = p.Tags[1:]
+			return p
+		}},
+	} {
+		t.Run(spec, func(t *testing.T) {
+			db := fake[resident](t)
+			it.Ok(t).If(db.Put(context.Background(), fixture())).Should().Equal(nil)
+
+			val, err := db.UpdateWith(context.Background(), ddb.Updater(key, tc.expr))
+			it.Ok(t).
+				If(err).Should().Equal(nil).
+				If(val).Should().Equal(tc.expect(fixture()))
+		})
+	}
+}
+
+func TestExpressionFilter(t *testing.T) {
+	var (
+		name = ddb.FilterFor[profile, string]("Name")
+		age  = ddb.FilterFor[profile, int]("Age")
+		tags = ddb.FilterFor[profile, string]("Tags")
+
+		clause = ddb.ClauseFor[profile, string]("Name")
+	)
+
+	db := fake[profile](t)
+	for i, x := range []string{"Verner Pleishner", "Eduard", "Max Otto von Stierlitz"} {
+		p := profileFixture()
+		p.Suffix = curie.New("%d", i)
+		p.Name = x
+		p.Age = 60 + i
+		if i == 2 {
+			p.Tags = []string{"agent"}
+		}
+		it.Ok(t).If(db.Put(context.Background(), p)).Should().Equal(nil)
+	}
+
+	for spec, tc := range map[string]struct {
+		filter interface{ MatcherOpt(profile) }
+		expect []string
+	}{
+		"Eq":        {name.Eq("Eduard"), []string{"Eduard"}},
+		"Ne":        {name.Ne("Eduard"), []string{"Verner Pleishner", "Max Otto von Stierlitz"}},
+		"Lt":        {age.Lt(61), []string{"Verner Pleishner"}},
+		"Le":        {age.Le(61), []string{"Verner Pleishner", "Eduard"}},
+		"Gt":        {age.Gt(61), []string{"Max Otto von Stierlitz"}},
+		"Ge":        {age.Ge(61), []string{"Eduard", "Max Otto von Stierlitz"}},
+		"Exists":    {name.Exists(), []string{"Verner Pleishner", "Eduard", "Max Otto von Stierlitz"}},
+		"NotExists": {name.NotExists(), []string{}},
+		"Between":   {age.Between(61, 62), []string{"Eduard", "Max Otto von Stierlitz"}},
+		"In":        {age.In(60, 62), []string{"Verner Pleishner", "Max Otto von Stierlitz"}},
+		"HasPrefix": {name.HasPrefix("Max"), []string{"Max Otto von Stierlitz"}},
+		"Contains":  {tags.Contains("agent"), []string{"Max Otto von Stierlitz"}},
+		"Clause":    {ddb.Filter(clause.Eq("Eduard")), []string{"Eduard"}},
+		"Or":        {ddb.Filter(ddb.Or(clause.Eq("Eduard"), clause.HasPrefix("Max"))), []string{"Eduard", "Max Otto von Stierlitz"}},
+	} {
+		t.Run(spec, func(t *testing.T) {
+			seq, _, err := db.Match(context.Background(), profile{Prefix: curie.New("dead:beef")}, tc.filter)
+			names := make([]string, len(seq))
+			for i, x := range seq {
+				names[i] = x.Name
+			}
+
+			it.Ok(t).
+				If(err).Should().Equal(nil).
+				If(names).Should().Equal(tc.expect)
+		})
+	}
+}
+
+func TestExpressionMatch(t *testing.T) {
+	db := fake[profile](t)
+	for _, suffix := range []string{"a/1", "a/2", "b/1", "b/2", "c/1"} {
+		p := profileFixture()
+		p.Suffix = curie.IRI(suffix)
+		it.Ok(t).If(db.Put(context.Background(), p)).Should().Equal(nil)
+	}
+
+	suffixes := func(seq []profile) []curie.IRI {
+		keys := make([]curie.IRI, len(seq))
+		for i, x := range seq {
+			keys[i] = x.Suffix
+		}
+		return keys
+	}
+
+	t.Run("Prefix", func(t *testing.T) {
+		seq, _, err := db.Match(context.Background(), profile{Prefix: curie.New("dead:beef"), Suffix: "b/"})
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(suffixes(seq)).Should().Equal([]curie.IRI{"b/1", "b/2"})
+	})
+
+	t.Run("SortKeyCondition", func(t *testing.T) {
+		for op, tc := range map[string]struct {
+			opt    interface{ MatcherOpt(profile) }
+			expect []curie.IRI
+		}{
+			"Lt":      {dynamo.SortKeyLt[profile]("b/1"), []curie.IRI{"a/1", "a/2"}},
+			"Le":      {dynamo.SortKeyLe[profile]("b/1"), []curie.IRI{"a/1", "a/2", "b/1"}},
+			"Gt":      {dynamo.SortKeyGt[profile]("b/1"), []curie.IRI{"b/2", "c/1"}},
+			"Ge":      {dynamo.SortKeyGe[profile]("b/1"), []curie.IRI{"b/1", "b/2", "c/1"}},
+			"Between": {dynamo.SortKeyBetween[profile]("a/2", "b/2"), []curie.IRI{"a/2", "b/1", "b/2"}},
+		} {
+			t.Run(op, func(t *testing.T) {
+				seq, _, err := db.Match(context.Background(), profile{Prefix: curie.New("dead:beef")}, tc.opt)
+				it.Ok(t).
+					If(err).Should().Equal(nil).
+					If(suffixes(seq)).Should().Equal(tc.expect)
+			})
+		}
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		seq := []curie.IRI{}
+		opts := []interface{ MatcherOpt(profile) }{dynamo.Limit[profile](2), dynamo.Descending[profile]()}
+		for {
+			page, cur, err := db.Match(context.Background(), profile{Prefix: curie.New("dead:beef")}, opts...)
+			it.Ok(t).If(err).Should().Equal(nil)
+
+			seq = append(seq, suffixes(page)...)
+			if cur == nil {
+				break
+			}
+			opts = []interface{ MatcherOpt(profile) }{dynamo.Limit[profile](2), dynamo.Descending[profile](), cur}
+		}
+
+		it.Ok(t).If(seq).Should().Equal([]curie.IRI{"c/1", "b/2", "b/1", "a/2", "a/1"})
+	})
+}
+
+func TestExpressionTransactWrite(t *testing.T) {
+	name := ddb.ClauseFor[profile, string]("Name")
+	db := fake[profile](t)
+	it.Ok(t).If(db.Put(context.Background(), profileFixture())).Should().Equal(nil)
+
+	other := profileFixture()
+	other.Suffix = curie.New("2")
+
+	failure := ddb.TransactWrite(context.Background(),
+		db.TxPut(other),
+		db.TxCheck(profileKey(), name.Eq("Eduard")),
+	)
+	_, ispcf := failure.(interface{ PreConditionFailed() bool })
+	_, notfound := db.Get(context.Background(), other)
+
+	success := ddb.TransactWrite(context.Background(),
+		db.TxPut(other),
+		db.TxCheck(profileKey(), name.Eq("Verner Pleishner")),
+	)
+	_, found := db.Get(context.Background(), other)
+
+	it.Ok(t).
+		IfTrue(ispcf).
+		IfNotNil(notfound).
+		If(success).Should().Equal(nil).
+		If(found).Should().Equal(nil)
+}
+
+func TestExpressionTransactWriteOnConditionCheckFailure(t *testing.T) {
+	name := ddb.ClauseFor[profile, string]("Name")
+	onFailure := ddb.ReturnValuesOnConditionCheckFailure[profile](types.ReturnValuesOnConditionCheckFailureAllOld)
+
+	item := func(err error) dynamo.Thing {
+		e, ok := err.(interface{ Item() dynamo.Thing })
+		if !ok {
+			return nil
+		}
+		return e.Item()
+	}
+
+	db := fake[profile](t)
+	it.Ok(t).If(db.Put(context.Background(), profileFixture())).Should().Equal(nil)
+
+	patch := profileKey()
+	patch.Age = 65
+
+	errPut := ddb.TransactWrite(context.Background(), db.TxPut(profileFixture(), name.NotExists(), onFailure))
+	errUpdate := ddb.TransactWrite(context.Background(), db.TxUpdate(patch, name.Eq("Eduard"), onFailure))
+	errRemove := ddb.TransactWrite(context.Background(), db.TxRemove(profileKey(), name.Eq("Eduard"), onFailure))
+	errCheck := ddb.TransactWrite(context.Background(), db.TxCheck(profileKey(), name.Eq("Eduard"), onFailure))
+	errNone := ddb.TransactWrite(context.Background(), db.TxCheck(profileKey(), name.Eq("Eduard")))
+
+	it.Ok(t).
+		If(item(errPut)).Should().Equal(profileFixture()).
+		If(item(errUpdate)).Should().Equal(profileFixture()).
+		If(item(errRemove)).Should().Equal(profileFixture()).
+		If(item(errCheck)).Should().Equal(profileFixture()).
+		If(item(errNone)).Should().Equal(nil)
+}
+
+func TestExpressionVersion(t *testing.T) {
+	db := fake[document](t, ddb.WithVersion("Version"))
+	doc := document{Prefix: curie.New("dead:beef"), Suffix: curie.New("1")}
+
+	err := db.Put(context.Background(), doc)
+	it.Ok(t).If(err).Should().Equal(nil)
+
+	obj, err := db.Get(context.Background(), doc)
+	it.Ok(t).
+		If(err).Should().Equal(nil).
+		If(obj.Version).Should().Equal(1)
+
+	conflict := db.Put(context.Background(), doc)
+	_, ispcf := conflict.(interface{ PreConditionFailed() bool })
+
+	obj, err = db.Update(context.Background(), obj)
+	it.Ok(t).
+		IfTrue(ispcf).
+		If(err).Should().Equal(nil).
+		If(obj.Version).Should().Equal(2)
+}
+
+func TestExpressionVersionUpdateWith(t *testing.T) {
+	db := fake[document](t, ddb.WithVersion("Version"))
+	doc := document{Prefix: curie.New("dead:beef"), Suffix: curie.New("1")}
+	it.Ok(t).If(db.Put(context.Background(), doc)).Should().Equal(nil)
+
+	version := ddb.UpdateFor[document, int]("Version")
+
+	doc.Version = 1
+	obj, err := db.UpdateWith(context.Background(), ddb.Updater(doc))
+	it.Ok(t).
+		If(err).Should().Equal(nil).
+		If(obj.Version).Should().Equal(2)
+
+	_, conflict := db.UpdateWith(context.Background(), ddb.Updater(doc))
+	e, ok := conflict.(interface{ Conflict() bool })
+	it.Ok(t).
+		IfTrue(ok).
+		IfTrue(e.Conflict())
+
+	doc.Version = 2
+	_, invalid := db.UpdateWith(context.Background(), ddb.Updater(doc, version.Set(10)))
+	_, isie := invalid.(interface{ InvalidExpression() bool })
+	it.Ok(t).IfTrue(isie)
+}
+
+// faulty value fails marshalling
+type faulty string
+
+func (faulty) MarshalDynamoDBAttributeValue() (types.AttributeValue, error) {
+	return nil, fmt.Errorf("faulty value")
+}
+
+func TestExpressionInvalid(t *testing.T) {
+	var (
+		name    = ddb.ClauseFor[profile, string]("Name")
+		invalid = ddb.ClauseFor[profile, faulty]("Name")
+		age     = ddb.UpdateFor[profile, int]("Age")
+		tags    = ddb.UpdateFor[profile, []string]("Tags")
+	)
+
+	isInvalidExpression := func(err error) bool {
+		e, ok := err.(interface{ InvalidExpression() bool })
+		return ok && e.InvalidExpression()
+	}
+
+	t.Run("Put", func(t *testing.T) {
+		db := fake[profile](t)
+
+		err := db.Put(context.Background(), profileFixture(), invalid.Eq("x"))
+		_, notfound := db.Get(context.Background(), profileKey())
+		it.Ok(t).
+			IfTrue(isInvalidExpression(err)).
+			IfNotNil(notfound)
+	})
+
+	t.Run("Logical", func(t *testing.T) {
+		db := fake[profile](t)
+
+		err := db.Put(context.Background(), profileFixture(),
+			ddb.Or(name.NotExists(), ddb.Not(invalid.Eq("x"))),
+		)
+		it.Ok(t).IfTrue(isInvalidExpression(err))
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		db := fake[profile](t)
+		it.Ok(t).If(db.Put(context.Background(), profileFixture())).Should().Equal(nil)
+
+		_, err := db.Remove(context.Background(), profileKey(), ddb.ConditionExpression[profile, string]{}.Exists())
+		_, found := db.Get(context.Background(), profileKey())
+		it.Ok(t).
+			IfTrue(isInvalidExpression(err)).
+			If(found).Should().Equal(nil)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		db := fake[profile](t)
+
+		_, err := db.Update(context.Background(), profileFixture(), name.In())
+		it.Ok(t).IfTrue(isInvalidExpression(err))
+	})
+
+	t.Run("UpdateWith", func(t *testing.T) {
+		db := fake[profile](t)
+		it.Ok(t).If(db.Put(context.Background(), profileFixture())).Should().Equal(nil)
+
+		for _, expr := range []ddb.UpdateItemExpression[profile]{
+			ddb.Updater(profileKey(), age.Set(1), age.Inc(1)),
+			ddb.Updater(profileKey(), tags.Union([]string{"a"})),
+			ddb.Updater(profileKey(), tags.Inc([]string{"a"})),
+			ddb.Updater(profileKey(), ddb.UpdateFor[profile, faulty]("Name").Set("x")),
+		} {
+			_, err := db.UpdateWith(context.Background(), expr)
+			it.Ok(t).IfTrue(isInvalidExpression(err))
+		}
+
+		_, err := db.UpdateWith(context.Background(), ddb.Updater(profileKey(), age.Inc(1)), invalid.Eq("x"))
+		val, _ := db.Get(context.Background(), profileKey())
+		it.Ok(t).
+			IfTrue(isInvalidExpression(err)).
+			If(val).Should().Equal(profileFixture())
+	})
+
+	t.Run("Match", func(t *testing.T) {
+		db := fake[profile](t)
+
+		_, _, err := db.Match(context.Background(), profileKey(), ddb.FilterFor[profile, string]("Name").In())
+		it.Ok(t).IfTrue(isInvalidExpression(err))
+	})
+}
+
+func TestExpressionReturnValues(t *testing.T) {
+	var (
+		name = ddb.ClauseFor[profile, string]("Name")
+		age  = ddb.UpdateFor[profile, int]("Age")
+	)
+
+	t.Run("Update/AllOld", func(t *testing.T) {
+		db := fake[profile](t)
+		it.Ok(t).If(db.Put(context.Background(), profileFixture())).Should().Equal(nil)
+
+		patch := profileKey()
+		patch.Age = 65
+		val, err := db.Update(context.Background(), patch, ddb.ReturnValues[profile](types.ReturnValueAllOld))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(profileFixture())
+	})
+
+	t.Run("UpdateWith/UpdatedNew", func(t *testing.T) {
+		db := fake[profile](t)
+		it.Ok(t).If(db.Put(context.Background(), profileFixture())).Should().Equal(nil)
+
+		val, err := db.UpdateWith(context.Background(),
+			ddb.Updater(profileKey(), age.Inc(1)),
+			ddb.ReturnValues[profile](types.ReturnValueUpdatedNew),
+		)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(profile{Prefix: curie.New("dead:beef"), Suffix: curie.New("1"), Age: 65})
+	})
+
+	t.Run("UpdateWith/None", func(t *testing.T) {
+		db := fake[profile](t)
+		it.Ok(t).If(db.Put(context.Background(), profileFixture())).Should().Equal(nil)
+
+		val, err := db.UpdateWith(context.Background(),
+			ddb.Updater(profileKey(), age.Inc(1)),
+			ddb.ReturnValues[profile](types.ReturnValueNone),
+		)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(profile{})
+	})
+
+	t.Run("Remove/None", func(t *testing.T) {
+		db := fake[profile](t)
+
+		val, err := db.Remove(context.Background(), profileKey(), ddb.ReturnValues[profile](types.ReturnValueNone))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(profile{})
+	})
+
+	t.Run("Remove/AllNew", func(t *testing.T) {
+		db := fake[profile](t)
+
+		_, err := db.Remove(context.Background(), profileKey(), ddb.ReturnValues[profile](types.ReturnValueAllNew))
+		it.Ok(t).IfNotNil(err)
+	})
+
+	t.Run("Put/AllOld", func(t *testing.T) {
+		db := fake[profile](t)
+
+		var val profile
+		err := db.Put(context.Background(), profileFixture(), ddb.ReturnValues(types.ReturnValueAllOld, &val))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(profile{})
+
+		next := profileFixture()
+		next.Name = "Eduard"
+		err = db.Put(context.Background(), next, ddb.ReturnValues(types.ReturnValueAllOld, &val))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(profileFixture())
+	})
+
+	t.Run("Put/Invalid", func(t *testing.T) {
+		db := fake[profile](t)
+
+		errNoVar := db.Put(context.Background(), profileFixture(), ddb.ReturnValues[profile](types.ReturnValueAllOld))
+		errAllNew := db.Put(context.Background(), profileFixture(), ddb.ReturnValues[profile](types.ReturnValueAllNew))
+		it.Ok(t).
+			IfNotNil(errNoVar).
+			IfNotNil(errAllNew)
+	})
+
+	t.Run("OnConditionCheckFailure", func(t *testing.T) {
+		db := fake[profile](t)
+		it.Ok(t).If(db.Put(context.Background(), profileFixture())).Should().Equal(nil)
+
+		item := func(err error) dynamo.Thing {
+			e, ok := err.(interface{ Item() dynamo.Thing })
+			if !ok {
+				return nil
+			}
+			return e.Item()
+		}
+
+		onFailure := ddb.ReturnValuesOnConditionCheckFailure[profile](types.ReturnValuesOnConditionCheckFailureAllOld)
+
+		errPut := db.Put(context.Background(), profileFixture(), name.NotExists(), onFailure)
+		_, errUpdate := db.UpdateWith(context.Background(), ddb.Updater(profileKey(), age.Inc(1)), name.Eq("Eduard"), onFailure)
+		_, errRemove := db.Remove(context.Background(), profileKey(), name.Eq("Eduard"), onFailure)
+		errNone := db.Put(context.Background(), profileFixture(), name.NotExists())
+
+		it.Ok(t).
+			If(item(errPut)).Should().Equal(profileFixture()).
+			If(item(errUpdate)).Should().Equal(profileFixture()).
+			If(item(errRemove)).Should().Equal(profileFixture()).
+			If(item(errNone)).Should().Equal(nil)
+	})
+}
+
+type account struct {
+	Prefix curie.IRI `dynamodbav:"prefix,omitempty"`
+	Suffix curie.IRI `dynamodbav:"suffix,omitempty"`
+	Name   *string   `dynamodbav:"name,omitempty"`
+	Age    *int      `dynamodbav:"age,omitempty"`
+	Email  *string   `dynamodbav:"email,omitempty"`
+	Tags   []string  `dynamodbav:"tags,omitempty"`
+	Score  int       `dynamodbav:"score"`
+}
+
+func (p account) HashKey() curie.IRI { return p.Prefix }
+func (p account) SortKey() curie.IRI { return p.Suffix }
+
+func ptr[A any](v A) *A { return &v }
+
+func TestExpressionPartialUpdate(t *testing.T) {
+	key := account{Prefix: curie.New("dead:beef"), Suffix: curie.New("1")}
+	fixture := account{
+		Prefix: curie.New("dead:beef"),
+		Suffix: curie.New("1"),
+		Name:   ptr("Verner Pleishner"),
+		Age:    ptr(64),
+		Email:  ptr("verner@example.com"),
+		Tags:   []string{"spy"},
+		Score:  10,
+	}
+
+	t.Run("Diff", func(t *testing.T) {
+		db := fake[account](t)
+		it.Ok(t).If(db.Put(context.Background(), fixture)).Should().Equal(nil)
+
+		patch := key
+		patch.Age = ptr(0)
+		patch.Email = dynamo.Null[string]()
+		patch.Tags = []string{}
+		val, err := db.Update(context.Background(), patch, dynamo.PartialUpdate[account]())
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(account{
+			Prefix: curie.New("dead:beef"),
+			Suffix: curie.New("1"),
+			Name:   ptr("Verner Pleishner"),
+			Age:    ptr(0),
+			Score:  10,
+		})
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		db := fake[account](t)
+		it.Ok(t).If(db.Put(context.Background(), fixture)).Should().Equal(nil)
+
+		_, err := db.Update(context.Background(), key, dynamo.PartialUpdate[account]())
+		_, ok := err.(interface{ InvalidExpression() bool })
+		it.Ok(t).IfTrue(ok)
+	})
+
+	t.Run("Set", func(t *testing.T) {
+		db := fake[account](t)
+		it.Ok(t).If(db.Put(context.Background(), fixture)).Should().Equal(nil)
+
+		patch := key
+		patch.Name = ptr("Eduard")
+		patch.Score = 20
+		val, err := db.Update(context.Background(), patch, dynamo.PartialUpdate[account]())
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(*val.Name).Should().Equal("Eduard").
+			If(*val.Age).Should().Equal(64).
+			If(*val.Email).Should().Equal("verner@example.com").
+			If(val.Tags).Should().Equal([]string{"spy"}).
+			If(val.Score).Should().Equal(20)
+	})
+
+	t.Run("Condition", func(t *testing.T) {
+		db := fake[account](t)
+		it.Ok(t).If(db.Put(context.Background(), fixture)).Should().Equal(nil)
+
+		name := ddb.ClauseFor[account, string]("Name")
+		patch := key
+		patch.Email = dynamo.Null[string]()
+		_, err := db.Update(context.Background(), patch, name.Eq("Eduard"), dynamo.PartialUpdate[account]())
+		_, ok := err.(interface{ PreConditionFailed() bool })
+		it.Ok(t).IfTrue(ok)
+
+		val, err := db.Update(context.Background(), patch, name.Eq("Verner Pleishner"), dynamo.PartialUpdate[account]())
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val.Email == nil).Should().Equal(true)
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		db := fake[document](t, ddb.WithVersion("Version"))
+		doc := document{Prefix: curie.New("dead:beef"), Suffix: curie.New("1")}
+		it.Ok(t).If(db.Put(context.Background(), doc)).Should().Equal(nil)
+
+		doc.Version = 1
+		obj, err := db.Update(context.Background(), doc, dynamo.PartialUpdate[document]())
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(obj.Version).Should().Equal(2)
+	})
+}
+
+func TestExpressionBatchWriteDuplicates(t *testing.T) {
+	db := fake[profile](t)
+
+	seq := []profile{
+		{Prefix: curie.New("dead:beef"), Suffix: curie.New("1"), Name: "Eduard", Age: 60},
+		{Prefix: curie.New("dead:beef"), Suffix: curie.New("2"), Name: "Kurt", Age: 50},
+		{Prefix: curie.New("dead:beef"), Suffix: curie.New("1"), Name: "Verner Pleishner", Age: 64},
+	}
+	success := db.BatchPut(context.Background(), seq)
+	val, err := db.Get(context.Background(), profileKey())
+	it.Ok(t).
+		If(success).Should().Equal(nil).
+		If(err).Should().Equal(nil).
+		If(val.Name).Should().Equal("Verner Pleishner").
+		If(val.Age).Should().Equal(64)
+
+	removed := db.BatchRemove(context.Background(), []profile{profileKey(), profileKey()})
+	_, notfound := db.Get(context.Background(), profileKey())
+	_, isnfe := notfound.(interface{ NotFound() string })
+	it.Ok(t).
+		If(removed).Should().Equal(nil).
+		IfTrue(isnfe)
+}
@@ -0,0 +1,172 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+//
+// The file implements dynamodb specific filter expressions
+//
+
+package ddb
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/dynamo/v3"
+)
+
+// See DynamoDB Filter Expressions
+//
+//	https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/Query.FilterExpression.html
+//
+// FilterFor is a variant of ClauseFor, it declares type descriptor to express
+// filter expressions of Match. The filter expression is applied after items
+// are read, it do not reduce consumed capacity.
+//
+//	var category = ddb.FilterFor[Article, string]("Category")
+//
+//	db.Match(ctx, Article{Author: "author:neumann"}, category.Eq("Math"))
+func FilterFor[T dynamo.Thing, A any](attr ...string) FilterExpression[T, A] {
+	return FilterExpression[T, A]{ce: ClauseFor[T, A](attr...)}
+}
+
+// FilterExpression wraps ConditionExpression, conditions are used as
+// filter expression of Match.
+type FilterExpression[T dynamo.Thing, A any] struct{ ce ConditionExpression[T, A] }
+
+// Filter uses condition expression (e.g. built by ClauseFor, Or, And, Not)
+// as filter expression of Match.
+//
+//	db.Match(ctx, Article{Author: "author:neumann"},
+//	  ddb.Filter(ddb.Or(category.Eq("Math"), category.Eq("Physics"))),
+//	)
+func Filter[T dynamo.Thing](cond interface{ WriterOpt(T) }) interface{ MatcherOpt(T) } {
+	if opt, ok := cond.(interface{ MatcherOpt(T) }); ok {
+		return opt
+	}
+	return invalidFilter[T]{}
+}
+
+// invalidFilter is a condition that cannot be used as filter expression
+type invalidFilter[T any] struct{}
+
+func (invalidFilter[T]) MatcherOpt(T) {}
+
+func (invalidFilter[T]) Apply(**string, map[string]string, map[string]types.AttributeValue) error {
+	return errors.New("condition is not supported by filter expression")
+}
+
+// Eq is equal condition
+//
+//	name.Eq(x) ⟼ Field = :value
+func (fe FilterExpression[T, A]) Eq(val A) interface{ MatcherOpt(T) } {
+	return Filter(fe.ce.Eq(val))
+}
+
+// Ne is non equal condition
+//
+//	name.Ne(x) ⟼ Field <> :value
+func (fe FilterExpression[T, A]) Ne(val A) interface{ MatcherOpt(T) } {
+	return Filter(fe.ce.Ne(val))
+}
+
+// Lt is less than condition
+//
+//	name.Lt(x) ⟼ Field < :value
+func (fe FilterExpression[T, A]) Lt(val A) interface{ MatcherOpt(T) } {
+	return Filter(fe.ce.Lt(val))
+}
+
+// Le is less or equal condition
+//
+//	name.Le(x) ⟼ Field <= :value
+func (fe FilterExpression[T, A]) Le(val A) interface{ MatcherOpt(T) } {
+	return Filter(fe.ce.Le(val))
+}
+
+// Gt is greater than condition
+//
+//	name.Gt(x) ⟼ Field > :value
+func (fe FilterExpression[T, A]) Gt(val A) interface{ MatcherOpt(T) } {
+	return Filter(fe.ce.Gt(val))
+}
+
+// Ge is greater or equal condition
+//
+//	name.Ge(x) ⟼ Field >= :value
+func (fe FilterExpression[T, A]) Ge(val A) interface{ MatcherOpt(T) } {
+	return Filter(fe.ce.Ge(val))
+}
+
+// Exists attribute condition
+//
+//	name.Exists(x) ⟼ attribute_exists(name)
+func (fe FilterExpression[T, A]) Exists() interface{ MatcherOpt(T) } {
+	return Filter(fe.ce.Exists())
+}
+
+// NotExists attribute condition
+//
+//	name.NotExists(x) ⟼ attribute_not_exists(name)
+func (fe FilterExpression[T, A]) NotExists() interface{ MatcherOpt(T) } {
+	return Filter(fe.ce.NotExists())
+}
+
+// Between attribute condition
+//
+//	name.Between(a, b) ⟼ Field BETWEEN :a AND :b
+func (fe FilterExpression[T, A]) Between(a, b A) interface{ MatcherOpt(T) } {
+	return Filter(fe.ce.Between(a, b))
+}
+
+// In attribute condition
+//
+//	name.In(a, b, c) ⟼ Field IN (:a, :b, :c)
+func (fe FilterExpression[T, A]) In(seq ...A) interface{ MatcherOpt(T) } {
+	return Filter(fe.ce.In(seq...))
+}
+
+// HasPrefix attribute condition
+//
+//	name.HasPrefix(x) ⟼ begins_with(Field, :value)
+func (fe FilterExpression[T, A]) HasPrefix(val A) interface{ MatcherOpt(T) } {
+	return Filter(fe.ce.HasPrefix(val))
+}
+
+// Contains attribute condition
+//
+//	name.Contains(x) ⟼ contains(Field, :value)
+func (fe FilterExpression[T, A]) Contains(val A) interface{ MatcherOpt(T) } {
+	return Filter(fe.ce.Contains(val))
+}
+
+/*
+Internal implementation of filter expressions for dynamo db
+*/
+func maybeFilterExpression[T dynamo.Thing](
+	filterExpression **string,
+	expressionAttributeNames map[string]string,
+	expressionAttributeValues map[string]types.AttributeValue,
+	opts []interface{ MatcherOpt(T) },
+) error {
+	errs := make([]error, 0)
+	for _, opt := range opts {
+		if ap, ok := opt.(interface {
+			Apply(**string, map[string]string, map[string]types.AttributeValue) error
+		}); ok {
+			if err := ap.Apply(filterExpression, expressionAttributeNames, expressionAttributeValues); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errInvalidExpression(errs...)
+	}
+
+	return nil
+}
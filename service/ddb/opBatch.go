@@ -0,0 +1,173 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package ddb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/curie"
+	"github.com/fogfish/dynamo/v3"
+)
+
+// DynamoDB limits number of items in single BatchWriteItem request
+const batchWriteSize = 25
+
+// BatchPut writes entities using batch write.
+//
+// The input is split into chunks supported by DynamoDB, the last write wins if
+// the input contains the same key multiple times. Items unprocessed by
+// DynamoDB are retried with backoff. Keys of items that are still unprocessed
+// are reported by the error with behavior
+//
+//	interface{ Unprocessed() []dynamo.Thing }
+//
+// If a chunk fails, the error reports keys of the failed chunk and of the rest
+// of input by the same behavior.
+//
+// Note: conditional expressions are not supported by batch write, the request
+// is rejected with an error. Same applies to storages with optimistic locking
+// (WithVersion).
+func (db *Storage[T]) BatchPut(ctx context.Context, entities []T, opts ...interface{ WriterOpt(T) }) error {
+	if err := batchWriterOpts(opts); err != nil {
+		return err
+	}
+
+	if db.version != "" {
+		return errInvalidRequest.New(fmt.Errorf("optimistic locking is not supported by batch write"))
+	}
+
+	seq := make([]types.WriteRequest, len(entities))
+	for i := 0; i < len(entities); i++ {
+		gen, err := db.codec.Encode(entities[i])
+		if err != nil {
+			return errInvalidEntity.New(err)
+		}
+		seq[i] = types.WriteRequest{PutRequest: &types.PutRequest{Item: gen}}
+	}
+
+	return db.batchWrite(ctx, seq)
+}
+
+// BatchRemove discards entities from the table using batch write.
+// It follows same semantic as BatchPut.
+func (db *Storage[T]) BatchRemove(ctx context.Context, keys []T, opts ...interface{ WriterOpt(T) }) error {
+	if err := batchWriterOpts(opts); err != nil {
+		return err
+	}
+
+	seq := make([]types.WriteRequest, len(keys))
+	for i := 0; i < len(keys); i++ {
+		gen, err := db.codec.EncodeKey(keys[i])
+		if err != nil {
+			return errInvalidKey.New(err)
+		}
+		seq[i] = types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: gen}}
+	}
+
+	return db.batchWrite(ctx, seq)
+}
+
+// batch write cannot carry conditions, they are rejected
+func batchWriterOpts[T dynamo.Thing](opts []interface{ WriterOpt(T) }) error {
+	for _, opt := range opts {
+		if _, ok := opt.(interface {
+			Apply(**string, map[string]string, map[string]types.AttributeValue) error
+		}); ok {
+			return errInvalidRequest.New(fmt.Errorf("conditional expression is not supported by batch write"))
+		}
+	}
+	return nil
+}
+
+func (db *Storage[T]) batchWrite(ctx context.Context, seq []types.WriteRequest) error {
+	failed := make([]dynamo.Thing, 0)
+	seq = db.batchUnique(seq)
+
+	for i := 0; i < len(seq); i += batchWriteSize {
+		j := i + batchWriteSize
+		if j > len(seq) {
+			j = len(seq)
+		}
+
+		unprocessed, err := db.batchWriteChunk(ctx, seq[i:j])
+		if err != nil {
+			// the failed chunk and the rest of input are not written
+			return errUnprocessed(err, append(failed, db.keysOf(seq[i:])...))
+		}
+
+		failed = append(failed, db.keysOf(unprocessed)...)
+	}
+
+	if len(failed) != 0 {
+		return errUnprocessed(nil, failed)
+	}
+
+	return nil
+}
+
+// DynamoDB rejects batch that writes the same item multiple times,
+// the last write of the key is kept
+func (db *Storage[T]) batchUnique(seq []types.WriteRequest) []types.WriteRequest {
+	at := make(map[[2]curie.IRI]int, len(seq))
+	out := make([]types.WriteRequest, 0, len(seq))
+	for i, key := range db.keysOf(seq) {
+		k := [2]curie.IRI{key.HashKey(), key.SortKey()}
+		if j, has := at[k]; has {
+			out[j] = seq[i]
+			continue
+		}
+		at[k] = len(out)
+		out = append(out, seq[i])
+	}
+	return out
+}
+
+// keys of write requests
+func (db *Storage[T]) keysOf(seq []types.WriteRequest) []dynamo.Thing {
+	keys := make([]dynamo.Thing, 0, len(seq))
+	for _, req := range seq {
+		switch {
+		case req.PutRequest != nil:
+			keys = append(keys, db.codec.DecodeKey(req.PutRequest.Item))
+		case req.DeleteRequest != nil:
+			keys = append(keys, db.codec.DecodeKey(req.DeleteRequest.Key))
+		}
+	}
+	return keys
+}
+
+// writes chunk of items, returns items that are not processed after all retries
+func (db *Storage[T]) batchWriteChunk(ctx context.Context, chunk []types.WriteRequest) ([]types.WriteRequest, error) {
+	for attempt := 0; ; attempt++ {
+		req := &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{*db.table: chunk},
+		}
+
+		val, err := db.service.BatchWriteItem(ctx, req)
+		if err != nil {
+			return nil, errServiceIO.New(err)
+		}
+
+		chunk = val.UnprocessedItems[*db.table]
+		if len(chunk) == 0 {
+			return nil, nil
+		}
+
+		retry, err := db.backoff.Wait(ctx, attempt)
+		if err != nil {
+			return nil, errServiceIO.New(err)
+		}
+		if !retry {
+			return chunk, nil
+		}
+	}
+}
@@ -10,9 +10,12 @@ package ddb
 
 import (
 	"context"
+	"sync"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/dynamo/v3"
 )
 
 // Get item from storage
@@ -27,6 +30,7 @@ func (db *Storage[T]) Get(ctx context.Context, key T, opts ...interface{ GetterO
 		TableName:                db.table,
 		ProjectionExpression:     db.schema.Projection,
 		ExpressionAttributeNames: db.schema.ExpectedAttributeNames,
+		ConsistentRead:           consistentReadOf(opts),
 	}
 
 	val, err := db.service.GetItem(ctx, req)
@@ -46,44 +50,223 @@ func (db *Storage[T]) Get(ctx context.Context, key T, opts ...interface{ GetterO
 	return obj, nil
 }
 
+// DynamoDB limits number of keys in single BatchGetItem request
+const batchGetSize = 100
+
+// ReportNotFound option for BatchGet, it demands error if any of keys is not found.
+// The error has behavior
+//
+//	interface{ NotFoundKeys() []dynamo.Thing }
+//
+// BatchGet returns found items together with the error.
+func ReportNotFound[T dynamo.Thing]() interface{ GetterOpt(T) } { return reportNotFound[T]{} }
+
+type reportNotFound[T dynamo.Thing] struct{}
+
+func (reportNotFound[T]) GetterOpt(T) {}
+
+func (reportNotFound[T]) ReportNotFound() bool { return true }
+
+// BatchGet items from storage, the items are returned in the same order as keys.
+// Keys are split into pages supported by DynamoDB, pages are fetched concurrently.
+// Keys unprocessed by DynamoDB are retried with backoff.
 func (db *Storage[T]) BatchGet(ctx context.Context, keys []T, opts ...interface{ GetterOpt(T) }) ([]T, error) {
-	seq := make([]map[string]types.AttributeValue, len(keys))
+	withNotFound := reportNotFoundOf(opts)
+
+	ids := make([]string, len(keys))
+	seq := make([]map[string]types.AttributeValue, 0, len(keys))
+	unique := make(map[string]struct{}, len(keys))
 	for i := 0; i < len(keys); i++ {
 		gen, err := db.codec.EncodeKey(keys[i])
 		if err != nil {
 			return nil, errInvalidKey.New(err)
 		}
-		seq[i] = gen
-	}
 
-	req := &dynamodb.BatchGetItemInput{
-		RequestItems: map[string]types.KeysAndAttributes{
-			*db.table: {
-				Keys:                     seq,
-				ProjectionExpression:     db.schema.Projection,
-				ExpressionAttributeNames: db.schema.ExpectedAttributeNames,
-			},
-		},
+		ids[i] = idOf(db.codec.DecodeKey(gen))
+		if _, exists := unique[ids[i]]; !exists {
+			unique[ids[i]] = struct{}{}
+			seq = append(seq, gen)
+		}
 	}
 
-	val, err := db.service.BatchGetItem(ctx, req)
+	found, unprocessed, err := db.batchGet(ctx, seq, consistentReadOf(opts))
 	if err != nil {
-		return nil, errServiceIO.New(err)
+		return nil, err
 	}
 
-	rsp, exists := val.Responses[*db.table]
-	if !exists {
-		return make([]T, 0), nil
+	if len(unprocessed) != 0 {
+		failed := make([]dynamo.Thing, 0, len(unprocessed))
+		for i := 0; i < len(keys); i++ {
+			if _, exists := unprocessed[ids[i]]; exists {
+				failed = append(failed, keys[i])
+			}
+		}
+		return nil, errUnprocessed(nil, failed)
 	}
 
-	items := make([]T, len(rsp))
-	for i := 0; i < len(rsp); i++ {
-		obj, err := db.codec.Decode(rsp[i])
+	items := make([]T, 0, len(found))
+	notFound := make([]dynamo.Thing, 0)
+	for i := 0; i < len(keys); i++ {
+		gen, exists := found[ids[i]]
+		if !exists {
+			notFound = append(notFound, keys[i])
+			continue
+		}
+
+		obj, err := db.codec.Decode(gen)
 		if err != nil {
 			return nil, errInvalidEntity.New(err)
 		}
-		items[i] = obj
+		items = append(items, obj)
+	}
+
+	if withNotFound && len(notFound) != 0 {
+		return items, errNotFoundKeys(nil, notFound)
 	}
 
 	return items, nil
 }
+
+// fetches pages of keys concurrently, returns found items and unprocessed keys
+func (db *Storage[T]) batchGet(ctx context.Context, seq []map[string]types.AttributeValue, consistentRead *bool) (
+	map[string]map[string]types.AttributeValue,
+	map[string]struct{},
+	error,
+) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg          sync.WaitGroup
+		mu          sync.Mutex
+		fail        error
+		slots       = make(chan struct{}, db.concurrency)
+		found       = make(map[string]map[string]types.AttributeValue, len(seq))
+		unprocessed = make(map[string]struct{})
+	)
+
+dispatch:
+	for i := 0; i < len(seq); i += batchGetSize {
+		j := i + batchGetSize
+		if j > len(seq) {
+			j = len(seq)
+		}
+
+		// context is cancelled either by caller or by the first failure
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case slots <- struct{}{}:
+		}
+
+		if ctx.Err() != nil {
+			<-slots
+			break
+		}
+
+		wg.Add(1)
+		go func(chunk []map[string]types.AttributeValue) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			items, keys, err := db.batchGetChunk(ctx, chunk, consistentRead)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				if fail == nil {
+					fail = err
+					cancel()
+				}
+				return
+			}
+
+			for _, gen := range items {
+				found[idOf(db.codec.DecodeKey(gen))] = gen
+			}
+			for _, gen := range keys {
+				unprocessed[idOf(db.codec.DecodeKey(gen))] = struct{}{}
+			}
+		}(seq[i:j])
+	}
+
+	wg.Wait()
+
+	if fail != nil {
+		return nil, nil, fail
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, errServiceIO.New(err)
+	}
+
+	return found, unprocessed, nil
+}
+
+// fetches page of keys, returns keys that are not processed after all retries
+func (db *Storage[T]) batchGetChunk(ctx context.Context, chunk []map[string]types.AttributeValue, consistentRead *bool) (
+	[]map[string]types.AttributeValue,
+	[]map[string]types.AttributeValue,
+	error,
+) {
+	items := make([]map[string]types.AttributeValue, 0, len(chunk))
+
+	for attempt := 0; ; attempt++ {
+		req := &dynamodb.BatchGetItemInput{
+			RequestItems: map[string]types.KeysAndAttributes{
+				*db.table: {
+					Keys:                     chunk,
+					ProjectionExpression:     db.schema.Projection,
+					ExpressionAttributeNames: db.schema.ExpectedAttributeNames,
+					ConsistentRead:           consistentRead,
+				},
+			},
+		}
+
+		val, err := db.service.BatchGetItem(ctx, req)
+		if err != nil {
+			return nil, nil, errServiceIO.New(err)
+		}
+
+		items = append(items, val.Responses[*db.table]...)
+
+		chunk = val.UnprocessedKeys[*db.table].Keys
+		if len(chunk) == 0 {
+			return items, nil, nil
+		}
+
+		retry, err := db.backoff.Wait(ctx, attempt)
+		if err != nil {
+			return nil, nil, errServiceIO.New(err)
+		}
+		if !retry {
+			return items, chunk, nil
+		}
+	}
+}
+
+// ConsistentRead option of the request
+func consistentReadOf[O any](opts []O) *bool {
+	for _, opt := range opts {
+		if v, ok := any(opt).(interface{ ConsistentRead() bool }); ok {
+			return aws.Bool(v.ConsistentRead())
+		}
+	}
+	return nil
+}
+
+// ReportNotFound option of the request
+func reportNotFoundOf[O any](opts []O) bool {
+	for _, opt := range opts {
+		if v, ok := any(opt).(interface{ ReportNotFound() bool }); ok && v.ReportNotFound() {
+			return true
+		}
+	}
+	return false
+}
+
+// unique identity of the key
+func idOf(key dynamo.Thing) string {
+	return string(key.HashKey()) + "\x00" + string(key.SortKey())
+}
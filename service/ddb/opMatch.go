@@ -10,6 +10,8 @@ package ddb
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
@@ -51,68 +53,126 @@ func (db *Storage[T]) match(ctx context.Context, gen map[string]types.AttributeV
 	if isSuffix {
 		expr = expr + " and begins_with(" + db.codec.skSuffix + ", :__" + db.codec.skSuffix + "__)"
 	}
+	values := exprOf(gen)
 
-	q := db.reqQuery(gen, expr, opts)
-	val, err := db.service.Query(ctx, q)
+	hasCondition := false
+	for _, opt := range opts {
+		switch v := opt.(type) {
+		case interface {
+			SortKeyCondition() (string, []curie.IRI)
+		}:
+			if isSuffix {
+				return nil, nil, errInvalidKey.New(fmt.Errorf("sort key prefix and sort key condition are exclusive"))
+			}
+			if hasCondition {
+				return nil, nil, errInvalidKey.New(fmt.Errorf("sort key conditions are exclusive, use SortKeyBetween for range"))
+			}
+			hasCondition = true
+
+			op, seq := v.SortKeyCondition()
+			expr = expr + " and " + sortKeyConditionOf(db.codec.skSuffix, op, seq, values)
+		}
+	}
+
+	q, err := db.reqQuery(expr, values, opts)
 	if err != nil {
-		return nil, nil, errServiceIO.New(err)
+		return nil, nil, err
 	}
+	if q.ConsistentRead != nil && q.IndexName != nil {
+		return nil, nil, errInvalidRequest.New(errConsistentReadIndex(*q.IndexName))
+	}
+	seq := make([]T, 0)
 
-	seq := make([]T, val.Count)
-	for i := 0; i < int(val.Count); i++ {
-		obj, err := db.codec.Decode(val.Items[i])
+	for {
+		val, err := db.service.Query(ctx, q)
 		if err != nil {
-			return nil, nil, errInvalidEntity.New(err)
+			return nil, nil, errServiceIO.New(err)
+		}
+
+		for i := 0; i < int(val.Count); i++ {
+			obj, err := db.codec.Decode(val.Items[i])
+			if err != nil {
+				return nil, nil, errInvalidEntity.New(err)
+			}
+			seq = append(seq, obj)
+		}
+
+		// Limit is applied before filter expression, the page is continued
+		// until limit is reached so that cursor remains correct.
+		if q.FilterExpression == nil || q.Limit == nil || val.LastEvaluatedKey == nil || val.Count >= *q.Limit {
+			return seq, lastKeyToCursor(db.codec, val.LastEvaluatedKey), nil
 		}
-		seq[i] = obj
-	}
 
-	return seq, lastKeyToCursor(db.codec, val), nil
+		q.Limit = aws.Int32(*q.Limit - val.Count)
+		q.ExclusiveStartKey = val.LastEvaluatedKey
+	}
 }
 
 func (db *Storage[T]) reqQuery(
-	gen map[string]types.AttributeValue,
 	expr string,
+	values map[string]types.AttributeValue,
 	opts []interface{ MatcherOpt(T) },
-) *dynamodb.QueryInput {
+) (*dynamodb.QueryInput, error) {
 	var (
 		limit             *int32                          = nil
+		scanIndexForward  *bool                           = nil
 		exclusiveStartKey map[string]types.AttributeValue = nil
 	)
 	for _, opt := range opts {
 		switch v := opt.(type) {
 		case interface{ Limit() int32 }:
 			limit = aws.Int32(v.Limit())
+		case interface{ Descending() bool }:
+			scanIndexForward = aws.Bool(!v.Descending())
 		case dynamo.Thing:
-			prefix := v.HashKey()
-			suffix := v.SortKey()
-
-			if prefix != "" {
-				key := map[string]types.AttributeValue{}
-
-				key[db.codec.pkPrefix] = &types.AttributeValueMemberS{Value: string(prefix)}
-				if suffix != "" {
-					key[db.codec.skSuffix] = &types.AttributeValueMemberS{Value: string(suffix)}
-				} else {
-					key[db.codec.skSuffix] = &types.AttributeValueMemberS{Value: "_"}
-				}
+			if key := cursorToLastKey(db.codec, v); key != nil {
 				exclusiveStartKey = key
 			}
 		}
 	}
 
+	filter, names, err := db.reqFilter(values, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	req := &dynamodb.QueryInput{
 		KeyConditionExpression:    aws.String(expr),
-		ExpressionAttributeValues: exprOf(gen),
+		FilterExpression:          filter,
+		ExpressionAttributeValues: values,
 		ProjectionExpression:      db.schema.Projection,
-		ExpressionAttributeNames:  db.schema.ExpectedAttributeNames,
+		ExpressionAttributeNames:  names,
 		TableName:                 db.table,
 		IndexName:                 db.index,
 		Limit:                     limit,
+		ScanIndexForward:          scanIndexForward,
+		ConsistentRead:            consistentReadOf(opts),
 		ExclusiveStartKey:         exclusiveStartKey,
 	}
 
-	return req
+	return req, nil
+}
+
+func (db *Storage[T]) reqFilter(
+	values map[string]types.AttributeValue,
+	opts []interface{ MatcherOpt(T) },
+) (*string, map[string]string, error) {
+	names := map[string]string{}
+	for k, v := range db.schema.ExpectedAttributeNames {
+		names[k] = v
+	}
+
+	var filter *string
+	if err := maybeFilterExpression(&filter, names, values, opts); err != nil {
+		return nil, nil, err
+	}
+
+	// Unfortunately empty maps are not accepted by DynamoDB
+	if len(names) == 0 {
+		names = nil
+	}
+
+	return filter, names, nil
 }
 
 func exprOf(gen map[string]types.AttributeValue) (val map[string]types.AttributeValue) {
@@ -130,34 +190,121 @@ func exprOf(gen map[string]types.AttributeValue) (val map[string]types.Attribute
 	return
 }
 
-type cursor struct{ hashKey, sortKey string }
+func sortKeyConditionOf(key string, op string, seq []curie.IRI, values map[string]types.AttributeValue) string {
+	if op == "BETWEEN" && len(seq) == 2 {
+		values[":__"+key+"_a__"] = &types.AttributeValueMemberS{Value: string(seq[0])}
+		values[":__"+key+"_b__"] = &types.AttributeValueMemberS{Value: string(seq[1])}
+		return key + " BETWEEN :__" + key + "_a__ AND :__" + key + "_b__"
+	}
+
+	values[":__"+key+"__"] = &types.AttributeValueMemberS{Value: string(seq[0])}
+	return key + " " + op + " :__" + key + "__"
+}
+
+// cursor is a position of the sequence, attributes keeps complete
+// LastEvaluatedKey (e.g. keys of global secondary index) in typed form
+type cursor struct {
+	hashKey, sortKey string
+	attrs            map[string]string
+}
+
+func (c cursor) HashKey() curie.IRI            { return curie.IRI(c.hashKey) }
+func (c cursor) SortKey() curie.IRI            { return curie.IRI(c.sortKey) }
+func (c cursor) Attributes() map[string]string { return c.attrs }
+
+func cursorToLastKey[T dynamo.Thing](codec *codec[T], cursor dynamo.Thing) map[string]types.AttributeValue {
+	if c, ok := cursor.(interface{ Attributes() map[string]string }); ok {
+		if key := decodeCursorAttributes(c.Attributes()); key != nil {
+			return key
+		}
+	}
+
+	// cursor is element of sequence, its position is defined by keys of
+	// both index and table
+	if c, ok := cursor.(interface{ Unwrap() dynamo.Thing }); ok {
+		if item, ok := c.Unwrap().(T); ok {
+			if gen, err := codec.Encode(item); err == nil {
+				if key := codec.LastKey(gen); key != nil {
+					return key
+				}
+			}
+		}
+	}
+
+	prefix := cursor.HashKey()
+	suffix := cursor.SortKey()
+
+	if prefix == "" {
+		return nil
+	}
+
+	key := map[string]types.AttributeValue{}
+	key[codec.pkPrefix] = &types.AttributeValueMemberS{Value: string(prefix)}
+	if suffix != "" {
+		key[codec.skSuffix] = &types.AttributeValueMemberS{Value: string(suffix)}
+	} else {
+		key[codec.skSuffix] = &types.AttributeValueMemberS{Value: "_"}
+	}
 
-func (c cursor) HashKey() curie.IRI { return curie.IRI(c.hashKey) }
-func (c cursor) SortKey() curie.IRI { return curie.IRI(c.sortKey) }
+	return key
+}
 
-func lastKeyToCursor[T dynamo.Thing](codec *codec[T], val *dynamodb.QueryOutput) interface{ MatcherOpt(T) } {
-	if val.LastEvaluatedKey == nil {
+func lastKeyToCursor[T dynamo.Thing](codec *codec[T], lastEvaluatedKey map[string]types.AttributeValue) interface{ MatcherOpt(T) } {
+	if lastEvaluatedKey == nil {
 		return nil
 	}
 
-	var hkey, skey string
+	key := codec.DecodeKey(lastEvaluatedKey)
 
-	key := val.LastEvaluatedKey
-	prefix, isPrefix := key[codec.pkPrefix]
-	if isPrefix {
-		switch v := prefix.(type) {
+	return dynamo.Cursor[T](&cursor{
+		hashKey: string(key.HashKey()),
+		sortKey: string(key.SortKey()),
+		attrs:   encodeCursorAttributes(lastEvaluatedKey),
+	})
+}
+
+// key attributes are scalars: string, number or binary
+func encodeCursorAttributes(key map[string]types.AttributeValue) map[string]string {
+	attrs := make(map[string]string, len(key))
+	for k, v := range key {
+		switch v := v.(type) {
 		case *types.AttributeValueMemberS:
-			hkey = v.Value
+			attrs[k] = "S:" + v.Value
+		case *types.AttributeValueMemberN:
+			attrs[k] = "N:" + v.Value
+		case *types.AttributeValueMemberB:
+			attrs[k] = "B:" + base64.StdEncoding.EncodeToString(v.Value)
 		}
 	}
+	return attrs
+}
 
-	suffix, isSuffix := key[codec.skSuffix]
-	if isSuffix {
-		switch v := suffix.(type) {
-		case *types.AttributeValueMemberS:
-			skey = v.Value
+func decodeCursorAttributes(attrs map[string]string) map[string]types.AttributeValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	key := make(map[string]types.AttributeValue, len(attrs))
+	for k, v := range attrs {
+		if len(v) < 2 || v[1] != ':' {
+			return nil
+		}
+
+		switch v[0] {
+		case 'S':
+			key[k] = &types.AttributeValueMemberS{Value: v[2:]}
+		case 'N':
+			key[k] = &types.AttributeValueMemberN{Value: v[2:]}
+		case 'B':
+			b, err := base64.StdEncoding.DecodeString(v[2:])
+			if err != nil {
+				return nil
+			}
+			key[k] = &types.AttributeValueMemberB{Value: b}
+		default:
+			return nil
 		}
 	}
 
-	return dynamo.Cursor[T](&cursor{hashKey: hkey, sortKey: skey})
+	return key
 }
@@ -10,37 +10,79 @@ package ddb
 
 import (
 	"context"
-	"strings"
+	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
-// Put writes entity
+// Put writes entity. Use ReturnValues option to get the item it has replaced.
 func (db *Storage[T]) Put(ctx context.Context, entity T, opts ...interface{ WriterOpt(T) }) error {
-	gen, err := db.codec.Encode(entity)
+	req, err := db.reqPut(entity, opts)
 	if err != nil {
-		return errInvalidEntity.New(err)
-	}
-
-	req := &dynamodb.PutItemInput{
-		Item:      gen,
-		TableName: db.table,
+		return err
 	}
 
-	names, values := maybeConditionExpression(&req.ConditionExpression, opts)
-	req.ExpressionAttributeValues = values
-	req.ExpressionAttributeNames = names
-
-	_, err = db.service.PutItem(ctx, req)
+	val, err := db.service.PutItem(ctx, req)
 	if err != nil {
 		if recoverConditionalCheckFailedException(err) {
-			return errPreConditionFailed(err, entity,
-				strings.Contains(*req.ConditionExpression, "attribute_not_exists") || strings.Contains(*req.ConditionExpression, "="),
-				strings.Contains(*req.ConditionExpression, "attribute_exists") || strings.Contains(*req.ConditionExpression, "<>"),
-			)
+			return db.conditionalCheckFailed(err, entity, req.ConditionExpression)
 		}
 		return errServiceIO.New(err)
 	}
 
+	if req.ReturnValues == types.ReturnValueNone {
+		return nil
+	}
+
+	into := returnValuesIntoOf(opts)
+	if len(val.Attributes) == 0 {
+		*into = db.undefined
+		return nil
+	}
+
+	obj, err := db.codec.Decode(val.Attributes)
+	if err != nil {
+		return errInvalidEntity.New(err)
+	}
+	*into = obj
+
 	return nil
 }
+
+func (db *Storage[T]) reqPut(entity T, opts []interface{ WriterOpt(T) }) (*dynamodb.PutItemInput, error) {
+	gen, err := db.codec.Encode(entity)
+	if err != nil {
+		return nil, errInvalidEntity.New(err)
+	}
+
+	opts, err = db.withVersion(gen, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	returnValues := returnValuesOf(opts, types.ReturnValueNone)
+	if returnValues != types.ReturnValueAllOld && returnValues != types.ReturnValueNone {
+		return nil, errInvalidRequest.New(fmt.Errorf("put does not support ReturnValues %s", returnValues))
+	}
+	if returnValues == types.ReturnValueAllOld && returnValuesIntoOf(opts) == nil {
+		return nil, errInvalidRequest.New(fmt.Errorf("put requires variable for ReturnValues %s", returnValues))
+	}
+
+	req := &dynamodb.PutItemInput{
+		Item:         gen,
+		TableName:    db.table,
+		ReturnValues: returnValues,
+
+		ReturnValuesOnConditionCheckFailure: returnValuesOnConditionCheckFailureOf(opts),
+	}
+
+	names, values, err := maybeConditionExpression(&req.ConditionExpression, opts)
+	if err != nil {
+		return nil, err
+	}
+	req.ExpressionAttributeValues = values
+	req.ExpressionAttributeNames = names
+
+	return req, nil
+}
@@ -10,38 +10,31 @@ package ddb
 
 import (
 	"context"
-	"strings"
+	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 // Remove discards the entity from the table
 func (db *Storage[T]) Remove(ctx context.Context, key T, opts ...interface{ WriterOpt(T) }) (T, error) {
-	gen, err := db.codec.EncodeKey(key)
+	req, err := db.reqRemove(key, opts)
 	if err != nil {
-		return db.undefined, errInvalidKey.New(err)
+		return db.undefined, err
 	}
 
-	req := &dynamodb.DeleteItemInput{
-		Key:          gen,
-		TableName:    db.table,
-		ReturnValues: "ALL_OLD",
-	}
-	names, values := maybeConditionExpression(&req.ConditionExpression, opts)
-	req.ExpressionAttributeValues = values
-	req.ExpressionAttributeNames = names
-
 	val, err := db.service.DeleteItem(ctx, req)
 	if err != nil {
 		if recoverConditionalCheckFailedException(err) {
-			return db.undefined, errPreConditionFailed(err, key,
-				strings.Contains(*req.ConditionExpression, "attribute_not_exists") || strings.Contains(*req.ConditionExpression, "="),
-				strings.Contains(*req.ConditionExpression, "attribute_exists") || strings.Contains(*req.ConditionExpression, "<>"),
-			)
+			return db.undefined, db.conditionalCheckFailed(err, key, req.ConditionExpression)
 		}
 		return db.undefined, errServiceIO.New(err)
 	}
 
+	if req.ReturnValues == types.ReturnValueNone {
+		return db.undefined, nil
+	}
+
 	obj, err := db.codec.Decode(val.Attributes)
 	if err != nil {
 		return db.undefined, errInvalidEntity.New(err)
@@ -49,3 +42,31 @@ func (db *Storage[T]) Remove(ctx context.Context, key T, opts ...interface{ Writ
 
 	return obj, nil
 }
+
+func (db *Storage[T]) reqRemove(key T, opts []interface{ WriterOpt(T) }) (*dynamodb.DeleteItemInput, error) {
+	gen, err := db.codec.EncodeKey(key)
+	if err != nil {
+		return nil, errInvalidKey.New(err)
+	}
+
+	returnValues := returnValuesOf(opts, types.ReturnValueAllOld)
+	if returnValues != types.ReturnValueAllOld && returnValues != types.ReturnValueNone {
+		return nil, errInvalidRequest.New(fmt.Errorf("remove does not support ReturnValues %s", returnValues))
+	}
+
+	req := &dynamodb.DeleteItemInput{
+		Key:          gen,
+		TableName:    db.table,
+		ReturnValues: returnValues,
+
+		ReturnValuesOnConditionCheckFailure: returnValuesOnConditionCheckFailureOf(opts),
+	}
+	names, values, err := maybeConditionExpression(&req.ConditionExpression, opts)
+	if err != nil {
+		return nil, err
+	}
+	req.ExpressionAttributeValues = values
+	req.ExpressionAttributeNames = names
+
+	return req, nil
+}
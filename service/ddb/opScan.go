@@ -0,0 +1,224 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package ddb
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/dynamo/v3"
+)
+
+// Segment option for Scan, reads the segment of the table. Use the option to
+// distribute parallel scan across workers.
+func Segment[T dynamo.Thing](segment, totalSegments int32) interface{ MatcherOpt(T) } {
+	return segmentOf[T]{segment: segment, totalSegments: totalSegments}
+}
+
+type segmentOf[T dynamo.Thing] struct{ segment, totalSegments int32 }
+
+func (segmentOf[T]) MatcherOpt(T) {}
+
+// DynamoDB limits the number of segments of parallel scan
+const maxTotalSegments = 1000000
+
+func (s segmentOf[T]) Segment() (int32, int32) { return s.segment, s.totalSegments }
+
+// Scan reads every element of the table (or index). It supports Limit, Cursor,
+// Segment and filter expressions options.
+func (db *Storage[T]) Scan(ctx context.Context, opts ...interface{ MatcherOpt(T) }) ([]T, interface{ MatcherOpt(T) }, error) {
+	q, err := db.reqScan(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if q.ConsistentRead != nil && q.IndexName != nil {
+		return nil, nil, errInvalidRequest.New(errConsistentReadIndex(*q.IndexName))
+	}
+
+	seq := make([]T, 0)
+
+	for {
+		val, err := db.service.Scan(ctx, q)
+		if err != nil {
+			return nil, nil, errServiceIO.New(err)
+		}
+
+		for i := 0; i < int(val.Count); i++ {
+			obj, err := db.codec.Decode(val.Items[i])
+			if err != nil {
+				return nil, nil, errInvalidEntity.New(err)
+			}
+			seq = append(seq, obj)
+		}
+
+		// Limit is applied before filter expression, the page is continued
+		// until limit is reached so that cursor remains correct.
+		if q.FilterExpression == nil || q.Limit == nil || val.LastEvaluatedKey == nil || val.Count >= *q.Limit {
+			return seq, lastKeyToCursor(db.codec, val.LastEvaluatedKey), nil
+		}
+
+		q.Limit = aws.Int32(*q.Limit - val.Count)
+		q.ExclusiveStartKey = val.LastEvaluatedKey
+	}
+}
+
+// ScanParallel reads every element of the table (or index) using parallel scan.
+// The scan is fanned out to totalSegments goroutines, decoded elements are
+// streamed to the channel. The error channel is closed after the stream,
+// it emits the first error if any. Use context to cancel the scan.
+//
+//	seq, errs := db.ScanParallel(ctx, 4)
+//	for x := range seq {
+//	  // ...
+//	}
+//	if err := <-errs; err != nil {
+//	  // ...
+//	}
+//
+// The Limit option defines the page size of requests. The number of segments
+// is limited by DynamoDB to 1 .. 1000000, the scan fails otherwise.
+//
+// Segments block until the element is consumed, the consumer must either
+// drain the channel or cancel the context. Every send is cancelled with the
+// context, abandoning the channel without cancellation leaks the goroutines.
+func (db *Storage[T]) ScanParallel(ctx context.Context, totalSegments int32, opts ...interface{ MatcherOpt(T) }) (<-chan T, <-chan error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		seq  = make(chan T)
+		errs = make(chan error, 1)
+	)
+
+	fail := func(err error) {
+		once.Do(func() {
+			errs <- err
+			cancel()
+		})
+	}
+
+	q, err := db.reqScan(opts)
+	if err == nil && q.ConsistentRead != nil && q.IndexName != nil {
+		err = errInvalidRequest.New(errConsistentReadIndex(*q.IndexName))
+	}
+	if err == nil && (totalSegments <= 0 || totalSegments > maxTotalSegments) {
+		err = errInvalidRequest.New(errTotalSegments(totalSegments))
+	}
+	if err != nil {
+		errs <- err
+		cancel()
+		close(seq)
+		close(errs)
+		return seq, errs
+	}
+
+	for segment := int32(0); segment < totalSegments; segment++ {
+		q, _ := db.reqScan(opts)
+		q.Segment = aws.Int32(segment)
+		q.TotalSegments = aws.Int32(totalSegments)
+		q.ExclusiveStartKey = nil
+
+		wg.Add(1)
+		go func(q *dynamodb.ScanInput) {
+			defer wg.Done()
+
+			if err := db.scanSegment(ctx, q, seq); err != nil {
+				fail(err)
+			}
+		}(q)
+	}
+
+	go func() {
+		wg.Wait()
+		cancel()
+		close(seq)
+		close(errs)
+	}()
+
+	return seq, errs
+}
+
+// reads all pages of the segment
+func (db *Storage[T]) scanSegment(ctx context.Context, q *dynamodb.ScanInput, seq chan<- T) error {
+	for {
+		val, err := db.service.Scan(ctx, q)
+		if err != nil {
+			return errServiceIO.New(err)
+		}
+
+		for i := 0; i < int(val.Count); i++ {
+			obj, err := db.codec.Decode(val.Items[i])
+			if err != nil {
+				return errInvalidEntity.New(err)
+			}
+
+			select {
+			case seq <- obj:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if val.LastEvaluatedKey == nil {
+			return nil
+		}
+		q.ExclusiveStartKey = val.LastEvaluatedKey
+	}
+}
+
+func (db *Storage[T]) reqScan(opts []interface{ MatcherOpt(T) }) (*dynamodb.ScanInput, error) {
+	var (
+		limit             *int32                          = nil
+		segment           *int32                          = nil
+		totalSegments     *int32                          = nil
+		exclusiveStartKey map[string]types.AttributeValue = nil
+	)
+	for _, opt := range opts {
+		switch v := opt.(type) {
+		case interface{ Limit() int32 }:
+			limit = aws.Int32(v.Limit())
+		case interface{ Segment() (int32, int32) }:
+			s, n := v.Segment()
+			segment, totalSegments = aws.Int32(s), aws.Int32(n)
+		case dynamo.Thing:
+			if key := cursorToLastKey(db.codec, v); key != nil {
+				exclusiveStartKey = key
+			}
+		}
+	}
+
+	values := map[string]types.AttributeValue{}
+	filter, names, err := db.reqFilter(values, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unfortunately empty maps are not accepted by DynamoDB
+	if len(values) == 0 {
+		values = nil
+	}
+
+	return &dynamodb.ScanInput{
+		FilterExpression:          filter,
+		ExpressionAttributeValues: values,
+		ProjectionExpression:      db.schema.Projection,
+		ExpressionAttributeNames:  names,
+		TableName:                 db.table,
+		IndexName:                 db.index,
+		Limit:                     limit,
+		Segment:                   segment,
+		TotalSegments:             totalSegments,
+		ConsistentRead:            consistentReadOf(opts),
+		ExclusiveStartKey:         exclusiveStartKey,
+	}, nil
+}
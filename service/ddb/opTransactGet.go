@@ -0,0 +1,132 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package ddb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/dynamo/v3"
+)
+
+// DynamoDB limits number of items in single TransactGetItems request
+const transactGetSize = 100
+
+// TxReader is an element of read transaction. Use TxGet of Storage to build
+// the element.
+type TxReader interface {
+	txGetItem() (DynamoDB, types.TransactGetItem, error)
+	txDecode(map[string]types.AttributeValue)
+}
+
+// TransactGet reads items of different types and tables as consistent snapshot.
+//
+//	author := authors.TxGet(Author{ID: "author:neumann"})
+//	article := articles.TxGet(Article{Author: "author:neumann", ID: "article:theory_of_automata"})
+//
+//	if err := ddb.TransactGet(ctx, author, article); err != nil { ... }
+//
+//	val, err := author.Value()
+//
+// The transaction is executed using service of the first element. DynamoDB
+// limits the transaction to 100 elements, larger transactions are rejected
+// with an error. The function fails on I/O errors only, the missing item is
+// reported by the element.
+func TransactGet(ctx context.Context, seq ...TxReader) error {
+	if len(seq) == 0 {
+		return nil
+	}
+
+	if len(seq) > transactGetSize {
+		return errInvalidRequest.New(fmt.Errorf("transaction of %d elements exceeds limit of %d", len(seq), transactGetSize))
+	}
+
+	var service DynamoDB
+	items := make([]types.TransactGetItem, len(seq))
+	for i := 0; i < len(seq); i++ {
+		api, item, err := seq[i].txGetItem()
+		if err != nil {
+			return err
+		}
+		if service == nil {
+			service = api
+		}
+		items[i] = item
+	}
+
+	req := &dynamodb.TransactGetItemsInput{TransactItems: items}
+
+	val, err := service.TransactGetItems(ctx, req)
+	if err != nil {
+		return errServiceIO.New(err)
+	}
+
+	for i := 0; i < len(seq); i++ {
+		var gen map[string]types.AttributeValue
+		if i < len(val.Responses) {
+			gen = val.Responses[i].Item
+		}
+		seq[i].txDecode(gen)
+	}
+
+	return nil
+}
+
+// TxGetter is an element of read transaction, it holds the item read by
+// the transaction.
+type TxGetter[T dynamo.Thing] struct {
+	db  *Storage[T]
+	key T
+	val T
+	err error
+}
+
+// TxGet builds transaction element that reads the entity
+func (db *Storage[T]) TxGet(key T) *TxGetter[T] {
+	return &TxGetter[T]{db: db, key: key}
+}
+
+// Value returns the entity read by transaction. The entity is undefined
+// before the transaction is executed.
+func (tx *TxGetter[T]) Value() (T, error) { return tx.val, tx.err }
+
+func (tx *TxGetter[T]) txGetItem() (DynamoDB, types.TransactGetItem, error) {
+	gen, err := tx.db.codec.EncodeKey(tx.key)
+	if err != nil {
+		return nil, types.TransactGetItem{}, errInvalidKey.New(err)
+	}
+
+	item := types.TransactGetItem{
+		Get: &types.Get{
+			Key:                      gen,
+			TableName:                tx.db.table,
+			ProjectionExpression:     tx.db.schema.Projection,
+			ExpressionAttributeNames: tx.db.schema.ExpectedAttributeNames,
+		},
+	}
+
+	return tx.db.service, item, nil
+}
+
+func (tx *TxGetter[T]) txDecode(gen map[string]types.AttributeValue) {
+	if gen == nil {
+		tx.val, tx.err = tx.db.undefined, errNotFound(nil, tx.key)
+		return
+	}
+
+	obj, err := tx.db.codec.Decode(gen)
+	if err != nil {
+		tx.val, tx.err = tx.db.undefined, errInvalidEntity.New(err)
+		return
+	}
+
+	tx.val, tx.err = obj, nil
+}
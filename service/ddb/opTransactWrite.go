@@ -0,0 +1,240 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package ddb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/dynamo/v3"
+)
+
+// DynamoDB limits number of actions in single TransactWriteItems request
+const transactWriteSize = 100
+
+// TxWriter is an element of write transaction. Use TxPut, TxUpdate,
+// TxUpdateWith, TxRemove and TxCheck of Storage to build the element.
+type TxWriter struct {
+	service             DynamoDB
+	thing               dynamo.Thing
+	conditionExpression *string
+	item                types.TransactWriteItem
+	decode              func(map[string]types.AttributeValue) (dynamo.Thing, error)
+	err                 error
+}
+
+// TransactWrite applies write operations to items of different types and
+// tables as all-or-nothing transaction.
+//
+//	err := ddb.TransactWrite(ctx,
+//	  keywords.TxPut(keyword),
+//	  keywords.TxPut(inverse),
+//	  articles.TxCheck(article, Title.Exists()),
+//	)
+//
+// The transaction is executed using service of the first element, all elements
+// must be built by storages that share the same DynamoDB service (account and
+// region). DynamoDB limits the transaction to 100 elements, larger transactions
+// are rejected with an error.
+// When the transaction is cancelled due to condition failure, the error
+// has behavior PreConditionFailed and refers to the item which condition failed.
+// The error carries the current item if the element demands
+// ReturnValuesOnConditionCheckFailure.
+func TransactWrite(ctx context.Context, seq ...TxWriter) error {
+	if len(seq) == 0 {
+		return nil
+	}
+
+	if len(seq) > transactWriteSize {
+		return errInvalidRequest.New(fmt.Errorf("transaction of %d elements exceeds limit of %d", len(seq), transactWriteSize))
+	}
+
+	items := make([]types.TransactWriteItem, len(seq))
+	for i := 0; i < len(seq); i++ {
+		if seq[i].err != nil {
+			return seq[i].err
+		}
+		items[i] = seq[i].item
+	}
+
+	req := &dynamodb.TransactWriteItemsInput{TransactItems: items}
+
+	_, err := seq[0].service.TransactWriteItems(ctx, req)
+	if err != nil {
+		if at, gen, ok := recoverTransactionCanceledException(err); ok && at < len(seq) {
+			failure := errConditionalCheckFailed(err, seq[at].thing, seq[at].conditionExpression)
+			if len(gen) != 0 && seq[at].decode != nil {
+				if item, err := seq[at].decode(gen); err == nil {
+					failure.(*preConditionFailed).item = item
+				}
+			}
+			return failure
+		}
+		return errServiceIO.New(err)
+	}
+
+	return nil
+}
+
+// TxPut builds transaction element that writes entity
+func (db *Storage[T]) TxPut(entity T, opts ...interface{ WriterOpt(T) }) TxWriter {
+	if err := txReturnValues(opts); err != nil {
+		return TxWriter{err: err}
+	}
+
+	req, err := db.reqPut(entity, opts)
+	if err != nil {
+		return TxWriter{err: err}
+	}
+
+	return TxWriter{
+		service:             db.service,
+		thing:               entity,
+		conditionExpression: req.ConditionExpression,
+		decode:              db.decode,
+		item: types.TransactWriteItem{
+			Put: &types.Put{
+				Item:                                req.Item,
+				TableName:                           req.TableName,
+				ConditionExpression:                 req.ConditionExpression,
+				ExpressionAttributeNames:            req.ExpressionAttributeNames,
+				ExpressionAttributeValues:           req.ExpressionAttributeValues,
+				ReturnValuesOnConditionCheckFailure: req.ReturnValuesOnConditionCheckFailure,
+			},
+		},
+	}
+}
+
+// TxRemove builds transaction element that discards the entity from the table
+func (db *Storage[T]) TxRemove(key T, opts ...interface{ WriterOpt(T) }) TxWriter {
+	if err := txReturnValues(opts); err != nil {
+		return TxWriter{err: err}
+	}
+
+	req, err := db.reqRemove(key, opts)
+	if err != nil {
+		return TxWriter{err: err}
+	}
+
+	return TxWriter{
+		service:             db.service,
+		thing:               key,
+		conditionExpression: req.ConditionExpression,
+		decode:              db.decode,
+		item: types.TransactWriteItem{
+			Delete: &types.Delete{
+				Key:                                 req.Key,
+				TableName:                           req.TableName,
+				ConditionExpression:                 req.ConditionExpression,
+				ExpressionAttributeNames:            req.ExpressionAttributeNames,
+				ExpressionAttributeValues:           req.ExpressionAttributeValues,
+				ReturnValuesOnConditionCheckFailure: req.ReturnValuesOnConditionCheckFailure,
+			},
+		},
+	}
+}
+
+// TxUpdate builds transaction element that applies a partial patch to entity
+func (db *Storage[T]) TxUpdate(entity T, opts ...interface{ WriterOpt(T) }) TxWriter {
+	if err := txReturnValues(opts); err != nil {
+		return TxWriter{err: err}
+	}
+
+	req, err := db.reqUpdate(entity, opts)
+	if err != nil {
+		return TxWriter{err: err}
+	}
+
+	return db.txUpdate(entity, req)
+}
+
+// TxUpdateWith builds transaction element that applies a partial patch to
+// entity using update expression abstraction
+func (db *Storage[T]) TxUpdateWith(expression UpdateItemExpression[T], opts ...interface{ WriterOpt(T) }) TxWriter {
+	if err := txReturnValues(opts); err != nil {
+		return TxWriter{err: err}
+	}
+
+	req, err := db.reqUpdateWith(expression, opts)
+	if err != nil {
+		return TxWriter{err: err}
+	}
+
+	return db.txUpdate(expression.entity, req)
+}
+
+func (db *Storage[T]) txUpdate(entity T, req *dynamodb.UpdateItemInput) TxWriter {
+	return TxWriter{
+		service:             db.service,
+		thing:               entity,
+		conditionExpression: req.ConditionExpression,
+		decode:              db.decode,
+		item: types.TransactWriteItem{
+			Update: &types.Update{
+				Key:                                 req.Key,
+				TableName:                           req.TableName,
+				UpdateExpression:                    req.UpdateExpression,
+				ConditionExpression:                 req.ConditionExpression,
+				ExpressionAttributeNames:            req.ExpressionAttributeNames,
+				ExpressionAttributeValues:           req.ExpressionAttributeValues,
+				ReturnValuesOnConditionCheckFailure: req.ReturnValuesOnConditionCheckFailure,
+			},
+		},
+	}
+}
+
+// transaction elements do not return values, only
+// ReturnValuesOnConditionCheckFailure is supported
+func txReturnValues[O any](opts []O) error {
+	if v := returnValuesOf(opts, types.ReturnValueNone); v != types.ReturnValueNone {
+		return errInvalidRequest.New(fmt.Errorf("transaction does not support ReturnValues %s", v))
+	}
+	return nil
+}
+
+// TxCheck builds transaction element that checks conditions on the entity
+// without modifying it (ConditionCheck).
+func (db *Storage[T]) TxCheck(key T, opts ...interface{ WriterOpt(T) }) TxWriter {
+	gen, err := db.codec.EncodeKey(key)
+	if err != nil {
+		return TxWriter{err: errInvalidKey.New(err)}
+	}
+
+	check := &types.ConditionCheck{
+		Key:       gen,
+		TableName: db.table,
+
+		ReturnValuesOnConditionCheckFailure: returnValuesOnConditionCheckFailureOf(opts),
+	}
+	names, values, err := maybeConditionExpression(&check.ConditionExpression, opts)
+	if err != nil {
+		return TxWriter{err: err}
+	}
+	check.ExpressionAttributeNames = names
+	check.ExpressionAttributeValues = values
+
+	if check.ConditionExpression == nil {
+		return TxWriter{err: errInvalidRequest.New(fmt.Errorf("condition check of %T requires condition expression", key))}
+	}
+
+	return TxWriter{
+		service:             db.service,
+		thing:               key,
+		conditionExpression: check.ConditionExpression,
+		decode:              db.decode,
+		item:                types.TransactWriteItem{ConditionCheck: check},
+	}
+}
+
+// decodes item of the transaction element
+func (db *Storage[T]) decode(gen map[string]types.AttributeValue) (dynamo.Thing, error) {
+	return db.codec.Decode(gen)
+}
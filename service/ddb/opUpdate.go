@@ -10,89 +10,294 @@ package ddb
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/fogfish/dynamo/v3"
+	"github.com/fogfish/dynamo/v3/internal/partial"
 )
 
+// ReturnValues option defines attributes returned by the write operation.
+// Update and UpdateWith return ALL_NEW attributes by default, use ALL_OLD,
+// UPDATED_OLD or UPDATED_NEW to get previous item or updated attributes only.
+// Remove supports either ALL_OLD (default) or NONE.
+//
+//	db.Update(ctx, entity, ddb.ReturnValues[Person](types.ReturnValueUpdatedNew))
+//
+// Put supports either NONE (default) or ALL_OLD, the previous item is
+// decoded into the variable given to the option, zero value is decoded
+// if the item did not exist.
+//
+//	var prev Person
+//	db.Put(ctx, entity, ddb.ReturnValues(types.ReturnValueAllOld, &prev))
+func ReturnValues[T dynamo.Thing](v types.ReturnValue, into ...*T) interface{ WriterOpt(T) } {
+	opt := returnValues[T]{value: v}
+	if len(into) > 0 {
+		opt.into = into[0]
+	}
+	return opt
+}
+
+type returnValues[T dynamo.Thing] struct {
+	value types.ReturnValue
+	into  *T
+}
+
+func (returnValues[T]) WriterOpt(T) {}
+
+func (v returnValues[T]) ReturnValues() types.ReturnValue { return v.value }
+
+func (v returnValues[T]) ReturnValuesInto() *T { return v.into }
+
+// ReturnValuesOnConditionCheckFailure option demands the current item at
+// storage if the condition of write operation fails. The item is carried by
+// the error:
+//
+//	var e interface{ Item() dynamo.Thing }
+//	if errors.As(err, &e) && e.Item() != nil {
+//	  current := e.Item().(Person)
+//	}
+func ReturnValuesOnConditionCheckFailure[T dynamo.Thing](v types.ReturnValuesOnConditionCheckFailure) interface{ WriterOpt(T) } {
+	return returnValuesOnConditionCheckFailure[T](v)
+}
+
+type returnValuesOnConditionCheckFailure[T dynamo.Thing] types.ReturnValuesOnConditionCheckFailure
+
+func (returnValuesOnConditionCheckFailure[T]) WriterOpt(T) {}
+
+func (v returnValuesOnConditionCheckFailure[T]) ReturnValuesOnConditionCheckFailure() types.ReturnValuesOnConditionCheckFailure {
+	return types.ReturnValuesOnConditionCheckFailure(v)
+}
+
+// ReturnValues option of the request
+func returnValuesOf[O any](opts []O, value types.ReturnValue) types.ReturnValue {
+	for _, opt := range opts {
+		if v, ok := any(opt).(interface{ ReturnValues() types.ReturnValue }); ok {
+			value = v.ReturnValues()
+		}
+	}
+	return value
+}
+
+// target of ReturnValues option of the request
+func returnValuesIntoOf[T any](opts []interface{ WriterOpt(T) }) *T {
+	var into *T
+	for _, opt := range opts {
+		if v, ok := opt.(interface{ ReturnValuesInto() *T }); ok {
+			into = v.ReturnValuesInto()
+		}
+	}
+	return into
+}
+
+// ReturnValuesOnConditionCheckFailure option of the request
+func returnValuesOnConditionCheckFailureOf[O any](opts []O) types.ReturnValuesOnConditionCheckFailure {
+	var value types.ReturnValuesOnConditionCheckFailure
+	for _, opt := range opts {
+		if v, ok := any(opt).(interface {
+			ReturnValuesOnConditionCheckFailure() types.ReturnValuesOnConditionCheckFailure
+		}); ok {
+			value = v.ReturnValuesOnConditionCheckFailure()
+		}
+	}
+	return value
+}
+
 // Update applies a partial patch to entity using update expression abstraction
 func (db *Storage[T]) UpdateWith(ctx context.Context, expression UpdateItemExpression[T], opts ...interface{ WriterOpt(T) }) (T, error) {
-	gen, err := db.codec.Encode(expression.entity)
+	req, err := db.reqUpdateWith(expression, opts)
+	if err != nil {
+		return db.undefined, err
+	}
+
+	return db.update(ctx, expression.entity, req)
+}
+
+// Update applies a partial patch to entity and returns new values
+func (db *Storage[T]) Update(ctx context.Context, entity T, opts ...interface{ WriterOpt(T) }) (T, error) {
+	req, err := db.reqUpdate(entity, opts)
+	if err != nil {
+		return db.undefined, err
+	}
+
+	return db.update(ctx, entity, req)
+}
+
+func (db *Storage[T]) update(ctx context.Context, key dynamo.Thing, req *dynamodb.UpdateItemInput) (T, error) {
+	val, err := db.service.UpdateItem(ctx, req)
+	if err != nil {
+		if recoverConditionalCheckFailedException(err) {
+			return db.undefined, db.conditionalCheckFailed(err, key, req.ConditionExpression)
+		}
+		return db.undefined, errServiceIO.New(err)
+	}
+
+	// Nothing is returned either for NONE or ALL_OLD of new item
+	if len(val.Attributes) == 0 {
+		return db.undefined, nil
+	}
+
+	// UPDATED_OLD and UPDATED_NEW return updated attributes only,
+	// the key is required to decode the item.
+	for k, v := range req.Key {
+		if _, has := val.Attributes[k]; !has {
+			val.Attributes[k] = v
+		}
+	}
+
+	obj, err := db.codec.Decode(val.Attributes)
 	if err != nil {
 		return db.undefined, errInvalidEntity.New(err)
 	}
-	req := expression.request
+
+	return obj, nil
+}
+
+func (db *Storage[T]) reqUpdateWith(expression UpdateItemExpression[T], opts []interface{ WriterOpt(T) }) (*dynamodb.UpdateItemInput, error) {
+	if expression.err != nil {
+		return nil, expression.err
+	}
+
+	gen, err := db.codec.Encode(expression.entity)
+	if err != nil {
+		return nil, errInvalidEntity.New(err)
+	}
+
+	opts, err = db.withVersion(gen, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// expression is reusable, the request is built from its copy
+	u := expression.clauses.clone()
+	db.setVersion(u, gen)
+	if len(u.errs) != 0 {
+		return nil, errInvalidExpression(u.errs...)
+	}
+
+	req := u.request()
 	req.Key = db.codec.KeyOnly(gen)
 	req.TableName = db.table
-	req.ReturnValues = "ALL_NEW"
+	req.ReturnValues = returnValuesOf(opts, types.ReturnValueAllNew)
+	req.ReturnValuesOnConditionCheckFailure = returnValuesOnConditionCheckFailureOf(opts)
+	if req.ExpressionAttributeValues == nil {
+		req.ExpressionAttributeValues = map[string]types.AttributeValue{}
+	}
 
-	maybeUpdateConditionExpression(
+	err = maybeUpdateConditionExpression(
 		&req.ConditionExpression,
 		req.ExpressionAttributeNames,
 		req.ExpressionAttributeValues,
 		opts,
 	)
+	if err != nil {
+		return nil, err
+	}
 
-	return db.update(ctx, expression.entity, req)
+	// Unfortunately empty maps are not accepted by DynamoDB
+	if len(req.ExpressionAttributeValues) == 0 {
+		req.ExpressionAttributeValues = nil
+	}
+
+	return req, nil
 }
 
-// Update applies a partial patch to entity and returns new values
-func (db *Storage[T]) Update(ctx context.Context, entity T, opts ...interface{ WriterOpt(T) }) (T, error) {
+func (db *Storage[T]) reqUpdate(entity T, opts []interface{ WriterOpt(T) }) (*dynamodb.UpdateItemInput, error) {
 	gen, err := db.codec.Encode(entity)
 	if err != nil {
-		return db.undefined, errInvalidEntity.New(err)
+		return nil, errInvalidEntity.New(err)
 	}
 
-	names := map[string]string{}
-	values := map[string]types.AttributeValue{}
-	update := make([]string, 0)
-	for k, v := range gen {
-		if k != db.codec.pkPrefix && k != db.codec.skSuffix && k != "id" {
-			names["#__"+k+"__"] = k
-			values[":__"+k+"__"] = v
-			update = append(update, "#__"+k+"__="+":__"+k+"__")
-		}
+	opts, err = db.withVersion(gen, opts)
+	if err != nil {
+		return nil, err
 	}
-	expression := aws.String("SET " + strings.Join(update, ","))
 
-	req := &dynamodb.UpdateItemInput{
-		Key:                       db.codec.KeyOnly(gen),
-		ExpressionAttributeNames:  names,
-		ExpressionAttributeValues: values,
-		UpdateExpression:          expression,
-		TableName:                 db.table,
-		ReturnValues:              "ALL_NEW",
+	var req *dynamodb.UpdateItemInput
+	if partial.Requested(opts) {
+		req, err = db.reqPartialUpdate(entity, gen)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		names := map[string]string{}
+		values := map[string]types.AttributeValue{}
+		update := make([]string, 0)
+		for k, v := range gen {
+			if k != db.codec.pkPrefix && k != db.codec.skSuffix && k != "id" {
+				names["#__"+k+"__"] = k
+				values[":__"+k+"__"] = v
+				update = append(update, "#__"+k+"__="+":__"+k+"__")
+			}
+		}
+
+		req = &dynamodb.UpdateItemInput{
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+			UpdateExpression:          aws.String("SET " + strings.Join(update, ",")),
+		}
 	}
 
-	maybeUpdateConditionExpression(
+	req.Key = db.codec.KeyOnly(gen)
+	req.TableName = db.table
+	req.ReturnValues = returnValuesOf(opts, types.ReturnValueAllNew)
+	req.ReturnValuesOnConditionCheckFailure = returnValuesOnConditionCheckFailureOf(opts)
+
+	err = maybeUpdateConditionExpression(
 		&req.ConditionExpression,
 		req.ExpressionAttributeNames,
 		req.ExpressionAttributeValues,
 		opts,
 	)
+	if err != nil {
+		return nil, err
+	}
 
-	return db.update(ctx, entity, req)
+	// Unfortunately empty maps are not accepted by DynamoDB
+	if len(req.ExpressionAttributeValues) == 0 {
+		req.ExpressionAttributeValues = nil
+	}
+
+	return req, nil
 }
 
-func (db *Storage[T]) update(ctx context.Context, key dynamo.Thing, req *dynamodb.UpdateItemInput) (T, error) {
-	val, err := db.service.UpdateItem(ctx, req)
-	if err != nil {
-		if recoverConditionalCheckFailedException(err) {
-			return db.undefined, errPreConditionFailed(err, key,
-				strings.Contains(*req.ConditionExpression, "attribute_not_exists") || strings.Contains(*req.ConditionExpression, "="),
-				strings.Contains(*req.ConditionExpression, "attribute_exists") || strings.Contains(*req.ConditionExpression, "<>"),
-			)
+// reqPartialUpdate builds the update expression from the diff of set fields,
+// nil fields are untouched, Null sentinel and empty collections are removed.
+func (db *Storage[T]) reqPartialUpdate(entity T, gen map[string]types.AttributeValue) (*dynamodb.UpdateItemInput, error) {
+	u := newUpdateClauses()
+	for _, field := range partial.Fields(entity) {
+		if field.Name == db.codec.pkPrefix || field.Name == db.codec.skSuffix || field.Name == db.version {
+			continue
+		}
+
+		path := attributePath(field.Name)
+		switch field.Action {
+		case partial.Set:
+			if val, has := gen[field.Name]; has {
+				u.set = append(u.set, u.name(path)+" = "+u.value(path, val))
+			}
+		case partial.Remove:
+			u.remove = append(u.remove, u.name(path))
 		}
-		return db.undefined, errServiceIO.New(err)
 	}
 
-	obj, err := db.codec.Decode(val.Attributes)
-	if err != nil {
-		return db.undefined, errInvalidEntity.New(err)
+	db.setVersion(u, gen)
+
+	if len(u.set) == 0 && len(u.remove) == 0 {
+		u.fail(fmt.Errorf("partial update of %T does not define any attribute", entity))
 	}
 
-	return obj, nil
+	if len(u.errs) != 0 {
+		return nil, errInvalidExpression(u.errs...)
+	}
+
+	req := u.request()
+	if req.ExpressionAttributeValues == nil {
+		req.ExpressionAttributeValues = map[string]types.AttributeValue{}
+	}
+
+	return req, nil
 }
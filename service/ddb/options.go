@@ -10,6 +10,7 @@ package ddb
 
 import (
 	"context"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/fogfish/curie"
@@ -22,7 +23,11 @@ type DynamoDB interface {
 	DeleteItem(context.Context, *dynamodb.DeleteItemInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
 	UpdateItem(context.Context, *dynamodb.UpdateItemInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
 	Query(context.Context, *dynamodb.QueryInput, ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	Scan(context.Context, *dynamodb.ScanInput, ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
 	BatchGetItem(context.Context, *dynamodb.BatchGetItemInput, ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error)
+	BatchWriteItem(context.Context, *dynamodb.BatchWriteItemInput, ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+	TransactGetItems(context.Context, *dynamodb.TransactGetItemsInput, ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error)
+	TransactWriteItems(context.Context, *dynamodb.TransactWriteItemsInput, ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
 }
 
 // Option type to configure the S3
@@ -35,7 +40,11 @@ type Options struct {
 	index         string
 	hashKey       string
 	sortKey       string
+	primaryKey    []string
 	useStrictType bool
+	backoff       backoff
+	concurrency   int
+	version       string
 	service       DynamoDB
 }
 
@@ -45,6 +54,12 @@ func defaultOptions() *Options {
 		prefixes: curie.Namespaces{},
 		hashKey:  "prefix",
 		sortKey:  "suffix",
+		backoff: backoff{
+			attempts: 8,
+			delay:    50 * time.Millisecond,
+			maxDelay: 5 * time.Second,
+		},
+		concurrency: 4,
 	}
 }
 
@@ -83,6 +98,18 @@ func WithSortKey(sortKey string) Option {
 	}
 }
 
+// WithPrimaryKey defines names of table's primary key attributes, it is
+// required when storage queries the index (see WithGlobalSecondaryIndex) of
+// the table with custom key names. Items returned by the index carry keys of
+// both index and table, the storage uses them to build cursors. By default,
+// the primary key is defined by WithHashKey and WithSortKey if storage
+// queries the table, and it is ("prefix", "suffix") for indexes.
+func WithPrimaryKey(hashKey, sortKey string) Option {
+	return func(c *Options) {
+		c.primaryKey = []string{hashKey, sortKey}
+	}
+}
+
 // WithTypeSchema demand that storage schema "knows" all type attributes
 func WithStrictType(strict bool) Option {
 	return func(c *Options) {
@@ -90,6 +117,34 @@ func WithStrictType(strict bool) Option {
 	}
 }
 
+// WithBackoff defines retry policy for items unprocessed by batch operations.
+// The delay is doubled at each attempt.
+func WithBackoff(attempts int, delay time.Duration) Option {
+	return func(c *Options) {
+		c.backoff.attempts = attempts
+		c.backoff.delay = delay
+	}
+}
+
+// WithConcurrency defines number of concurrent requests made by batch operations
+func WithConcurrency(n int) Option {
+	return func(c *Options) {
+		if n > 0 {
+			c.concurrency = n
+		}
+	}
+}
+
+// WithVersion enables optimistic locking using the numeric struct field as
+// the version of entity. Put, Update and UpdateWith expect that the storage
+// holds the version defined by entity (zero version means that entity is not
+// created yet) and increment it. BatchPut is rejected by the storage.
+func WithVersion(field string) Option {
+	return func(c *Options) {
+		c.version = field
+	}
+}
+
 // Configure AWS Service for broker instance
 func WithService(service DynamoDB) Option {
 	return func(c *Options) {
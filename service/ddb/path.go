@@ -0,0 +1,195 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+//
+// The file implements document paths to nested attributes
+//
+
+package ddb
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// See DynamoDB Document Paths
+//
+//	https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/Expressions.Attributes.html#Expressions.Attributes.NestedElements.DocumentPathExamples
+//
+// documentPath is a sequence of attribute names, map keys and list indexes
+// that refers nested attribute (e.g. Address.City, Tags[3]).
+type documentPath []pathElement
+
+// element of document path is either name or index
+type pathElement struct {
+	name  string
+	index int
+}
+
+// attributePath builds document path to top-level attribute
+func attributePath(name string) documentPath {
+	if name == "" {
+		return nil
+	}
+	return documentPath{{name: name}}
+}
+
+// nestedPathOf resolves path segments against the type, it returns the path,
+// type and tag of the field referred by last segment. Struct fields are
+// referred by their names, map keys as-is and list indexes by numbers.
+func nestedPathOf(t reflect.Type, attr []string) (documentPath, reflect.Type, reflect.StructTag) {
+	var (
+		path documentPath
+		tag  reflect.StructTag
+	)
+
+	for _, seg := range attr {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+
+		tag = ""
+		switch t.Kind() {
+		case reflect.Struct:
+			f, ok := t.FieldByName(seg)
+			if !ok {
+				panic(fmt.Errorf("type %s do not have field %s", t, seg))
+			}
+			name := strings.Split(f.Tag.Get("dynamodbav"), ",")[0]
+			if name == "" {
+				name = f.Name
+			}
+			path = append(path, pathElement{name: name})
+			tag = f.Tag
+			t = f.Type
+		case reflect.Map:
+			path = append(path, pathElement{name: seg})
+			t = t.Elem()
+		case reflect.Slice, reflect.Array:
+			index, err := strconv.Atoi(strings.Trim(seg, "[]"))
+			if err != nil || index < 0 {
+				panic(fmt.Errorf("invalid index %s of list %s", seg, t))
+			}
+			path = append(path, pathElement{index: index})
+			t = t.Elem()
+		default:
+			panic(fmt.Errorf("%s is not a document, cannot resolve %s", t, seg))
+		}
+	}
+
+	return path, t, tag
+}
+
+// leafOf checks that type of nested attribute is A, pointers are ignored
+func leafOf[T, A any](path documentPath, leaf reflect.Type) {
+	expect := reflect.TypeOf(new(A)).Elem()
+	for expect.Kind() == reflect.Pointer {
+		expect = expect.Elem()
+	}
+	for leaf.Kind() == reflect.Pointer {
+		leaf = leaf.Elem()
+	}
+
+	if leaf != expect {
+		panic(fmt.Errorf("attribute %s of type %T is %s, %s is expected", path, *new(T), leaf, expect))
+	}
+}
+
+// overlaps checks if either path is a prefix of another one
+func (path documentPath) overlaps(other documentPath) bool {
+	n := len(path)
+	if len(other) < n {
+		n = len(other)
+	}
+
+	for i := 0; i < n; i++ {
+		if path[i] != other[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String returns human readable path
+func (path documentPath) String() string {
+	sb := strings.Builder{}
+	for i, el := range path {
+		switch {
+		case el.name == "":
+			sb.WriteString("[" + strconv.Itoa(el.index) + "]")
+		case i == 0:
+			sb.WriteString(el.name)
+		default:
+			sb.WriteString("." + el.name)
+		}
+	}
+	return sb.String()
+}
+
+// slug returns identifier of path usable as part of placeholder
+func (path documentPath) slug() string {
+	seq := make([]string, len(path))
+	for i, el := range path {
+		if el.name == "" {
+			seq[i] = strconv.Itoa(el.index)
+		} else {
+			seq[i] = safeName(el.name)
+		}
+	}
+	return strings.Join(seq, "_")
+}
+
+// expr renders document path, attribute names are substituted with
+// placeholders (e.g. #__c_address__.#__c_city__), which are registered
+// in the names.
+func (path documentPath) expr(prefix string, names map[string]string) string {
+	sb := strings.Builder{}
+	for i, el := range path {
+		switch {
+		case el.name == "":
+			sb.WriteString("[" + strconv.Itoa(el.index) + "]")
+		default:
+			if i > 0 {
+				sb.WriteString(".")
+			}
+			sb.WriteString(uniqueNameOf(names, prefix, el.name))
+		}
+	}
+	return sb.String()
+}
+
+// uniqueNameOf returns placeholder for the attribute name. The placeholder
+// is shared by all occurrences of the name.
+func uniqueNameOf(names map[string]string, prefix, name string) string {
+	key := "#__" + prefix + safeName(name) + "__"
+	for i := 1; ; i++ {
+		if v, has := names[key]; !has || v == name {
+			names[key] = name
+			return key
+		}
+		key = "#__" + prefix + safeName(name) + "_" + strconv.Itoa(i) + "__"
+	}
+}
+
+// placeholders are restricted to alphanumeric characters
+func safeName(name string) string {
+	return strings.Map(
+		func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+				return r
+			default:
+				return '_'
+			}
+		},
+		name,
+	)
+}
@@ -0,0 +1,102 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+//
+// The file implements optimistic locking using version attribute
+//
+
+package ddb
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/dynamo/v3"
+)
+
+// resolves name of version attribute from the struct field
+func versionAttribute[T dynamo.Thing](field string) (string, error) {
+	if field == "" {
+		return "", nil
+	}
+
+	seq := genCodec[T](field)
+	if len(seq) == 0 {
+		return "", errInvalidEntity.New(fmt.Errorf("version field %s is not defined by %T", field, *new(T)))
+	}
+
+	switch seq[0].PureType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return "", errInvalidEntity.New(fmt.Errorf("version field %s of %T is not a number", field, *new(T)))
+	}
+
+	tag := seq[0].Tag.Get("dynamodbav")
+	if tag == "" {
+		return field, nil
+	}
+
+	return strings.Split(tag, ",")[0], nil
+}
+
+// versionOf increments version of entity in the generic representation and
+// returns condition expression that expects the original version at storage.
+func (db *Storage[T]) versionOf(gen map[string]types.AttributeValue) (interface{ WriterOpt(T) }, error) {
+	expected := int64(0)
+
+	if val, has := gen[db.version]; has {
+		num, ok := val.(*types.AttributeValueMemberN)
+		if !ok {
+			return nil, errInvalidEntity.New(fmt.Errorf("version attribute %s is not a number", db.version))
+		}
+
+		v, err := strconv.ParseInt(num.Value, 10, 64)
+		if err != nil {
+			return nil, errInvalidEntity.New(err)
+		}
+		expected = v
+	}
+
+	gen[db.version] = &types.AttributeValueMemberN{Value: strconv.FormatInt(expected+1, 10)}
+
+	if expected == 0 {
+		return &unaryCondition[T]{op: "attribute_not_exists", key: attributePath(db.version)}, nil
+	}
+
+	return &dyadicCondition[T, int64]{op: "=", key: attributePath(db.version), val: expected}, nil
+}
+
+// withVersion appends version condition to writer options
+func (db *Storage[T]) withVersion(gen map[string]types.AttributeValue, opts []interface{ WriterOpt(T) }) ([]interface{ WriterOpt(T) }, error) {
+	if db.version == "" {
+		return opts, nil
+	}
+
+	cond, err := db.versionOf(gen)
+	if err != nil {
+		return nil, err
+	}
+
+	// the copy protects options declared by the caller
+	seq := make([]interface{ WriterOpt(T) }, 0, len(opts)+1)
+	seq = append(seq, opts...)
+	return append(seq, cond), nil
+}
+
+// setVersion appends incremented version to update clauses, the update fails
+// if the expression modifies the version attribute by itself.
+func (db *Storage[T]) setVersion(u *updateClauses, gen map[string]types.AttributeValue) {
+	if val, has := gen[db.version]; has && db.version != "" {
+		path := attributePath(db.version)
+		u.set = append(u.set, u.name(path)+" = "+u.value(path, val))
+	}
+}
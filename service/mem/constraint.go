@@ -0,0 +1,50 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package mem
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/dynamo/v3/internal/ddbexpr"
+)
+
+// expressionOf evaluates options that declares DynamoDB expressions
+// (e.g. ddb.ClauseFor, ddb.FilterFor) into the condition.
+func expressionOf[O any](opts []O) (ddbexpr.Condition, string, error) {
+	var (
+		expr   *string
+		names  = map[string]string{}
+		values = map[string]types.AttributeValue{}
+	)
+
+	errs := make([]error, 0)
+	for _, opt := range opts {
+		if ap, ok := any(opt).(interface {
+			Apply(**string, map[string]string, map[string]types.AttributeValue) error
+		}); ok {
+			if err := ap.Apply(&expr, names, values); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, "", errInvalidExpression(errs...)
+	}
+
+	if expr == nil {
+		return nil, "", nil
+	}
+
+	cond, err := ddbexpr.New(names, values).Condition(*expr)
+	if err != nil {
+		return nil, "", errInvalidRequest.New(err)
+	}
+
+	return cond, *expr, nil
+}
@@ -0,0 +1,99 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package mem
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fogfish/dynamo/v3"
+	"github.com/fogfish/faults"
+)
+
+const (
+	errInvalidKey     = faults.Type("invalid key")
+	errInvalidEntity  = faults.Type("invalid entity")
+	errInvalidRequest = faults.Type("invalid request")
+)
+
+func errEmptyHashKey(key dynamo.Thing) error {
+	return fmt.Errorf("invalid key of %T, hashkey cannot be empty", key)
+}
+
+// NotFound is an error to handle unknown elements
+func errNotFound(err error, key dynamo.Thing) error {
+	return &notFound{err: err, Thing: key}
+}
+
+type notFound struct {
+	dynamo.Thing
+	err error
+}
+
+func (e *notFound) Error() string {
+	return fmt.Sprintf("Not Found (%s, %s)", e.HashKey(), e.SortKey())
+}
+
+func (e *notFound) Unwrap() error { return e.err }
+
+func (e *notFound) NotFound() string { return e.HashKey().Safe() + " " + e.SortKey().Safe() }
+
+// errPreConditionFailed
+func errPreConditionFailed(err error, thing dynamo.Thing, conflict bool, gone bool) error {
+	return &preConditionFailed{Thing: thing, conflict: conflict, gone: gone, err: err}
+}
+
+type preConditionFailed struct {
+	dynamo.Thing
+	conflict bool
+	gone     bool
+	err      error
+}
+
+func (e *preConditionFailed) Error() string {
+	return fmt.Sprintf("Pre Condition Failed (%s, %s)", e.HashKey(), e.SortKey())
+}
+
+func (e *preConditionFailed) PreConditionFailed() bool { return true }
+
+func (e *preConditionFailed) Conflict() bool { return e.conflict }
+
+func (e *preConditionFailed) Gone() bool { return e.gone }
+
+func (e *preConditionFailed) Unwrap() error { return e.err }
+
+// errConditionalCheckFailed builds errPreConditionFailed from the condition expression
+func errConditionalCheckFailed(thing dynamo.Thing, expr string) error {
+	return errPreConditionFailed(nil, thing,
+		strings.Contains(expr, "attribute_not_exists") || strings.Contains(expr, "="),
+		strings.Contains(expr, "attribute_exists") || strings.Contains(expr, "<>"),
+	)
+}
+
+// errInvalidExpression is an error to handle condition or filter expressions
+// that cannot be built.
+func errInvalidExpression(errs ...error) error {
+	return &invalidExpression{errs: errs}
+}
+
+type invalidExpression struct {
+	errs []error
+}
+
+func (e *invalidExpression) Error() string {
+	seq := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		seq[i] = err.Error()
+	}
+	return fmt.Sprintf("Invalid Expression (%s)", strings.Join(seq, "; "))
+}
+
+func (e *invalidExpression) Unwrap() []error { return e.errs }
+
+func (e *invalidExpression) InvalidExpression() bool { return true }
@@ -0,0 +1,99 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+// Package mem implements in-memory key-value storage. The storage follows
+// semantic of DynamoDB, it is designed for unit testing of applications.
+//
+//	db := mem.New[Person]()
+//
+// The storage evaluates conditional and filter expressions declared with
+// ddb.ClauseFor and ddb.FilterFor.
+package mem
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/dynamo/v3"
+)
+
+// Storage type
+type Storage[T dynamo.Thing] struct {
+	mu        sync.RWMutex
+	seq       []entry
+	undefined T
+}
+
+// entry of the storage, the storage orders entries by hash and sort keys
+type entry struct {
+	hashKey string
+	sortKey string
+	item    map[string]types.AttributeValue
+}
+
+// New creates in-memory storage
+func New[T dynamo.Thing]() *Storage[T] {
+	return &Storage[T]{seq: make([]entry, 0)}
+}
+
+// lookup returns position of the key at storage and flag if the key exists
+func (db *Storage[T]) lookup(hashKey, sortKey string) (int, bool) {
+	i := sort.Search(len(db.seq), func(i int) bool {
+		e := db.seq[i]
+		return e.hashKey > hashKey || (e.hashKey == hashKey && e.sortKey >= sortKey)
+	})
+
+	return i, i < len(db.seq) && db.seq[i].hashKey == hashKey && db.seq[i].sortKey == sortKey
+}
+
+func (db *Storage[T]) insert(i int, e entry) {
+	db.seq = append(db.seq, entry{})
+	copy(db.seq[i+1:], db.seq[i:])
+	db.seq[i] = e
+}
+
+func (db *Storage[T]) delete(i int) {
+	copy(db.seq[i:], db.seq[i+1:])
+	db.seq[len(db.seq)-1] = entry{}
+	db.seq = db.seq[:len(db.seq)-1]
+}
+
+// keyOf encodes identity of the thing, the sort key is optional
+func keyOf(key dynamo.Thing) (string, string, error) {
+	hashKey := string(key.HashKey())
+	if hashKey == "" {
+		return "", "", errInvalidKey.New(errEmptyHashKey(key))
+	}
+
+	sortKey := string(key.SortKey())
+	if sortKey == "" {
+		sortKey = "_"
+	}
+
+	return hashKey, sortKey, nil
+}
+
+func (db *Storage[T]) encode(entity T) (map[string]types.AttributeValue, error) {
+	gen, err := attributevalue.MarshalMap(entity)
+	if err != nil {
+		return nil, errInvalidEntity.New(err)
+	}
+
+	return gen, nil
+}
+
+func (db *Storage[T]) decode(gen map[string]types.AttributeValue) (T, error) {
+	var entity T
+	if err := attributevalue.UnmarshalMap(gen, &entity); err != nil {
+		return db.undefined, errInvalidEntity.New(err)
+	}
+
+	return entity, nil
+}
@@ -0,0 +1,294 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package mem_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/fogfish/curie"
+	"github.com/fogfish/dynamo/v3"
+	"github.com/fogfish/dynamo/v3/internal/dynamotest"
+	"github.com/fogfish/dynamo/v3/service/ddb"
+	"github.com/fogfish/dynamo/v3/service/mem"
+	"github.com/fogfish/it"
+)
+
+var _ dynamo.KeyVal[dynamotest.Person] = mem.New[dynamotest.Person]()
+
+func codec(p dynamotest.Person) (dynamotest.Person, error) {
+	return p, nil
+}
+
+func seed(seq ...*dynamotest.Person) dynamo.KeyVal[dynamotest.Person] {
+	db := mem.New[dynamotest.Person]()
+	for _, x := range seq {
+		if x != nil {
+			db.Put(context.Background(), *x)
+		}
+	}
+	return db
+}
+
+func TestMem(t *testing.T) {
+	dynamotest.TestGet(t, codec,
+		func(_, returnVal *dynamotest.Person) dynamo.KeyVal[dynamotest.Person] { return seed(returnVal) },
+	)
+	dynamotest.TestPut(t, codec,
+		func(*dynamotest.Person) dynamo.KeyVal[dynamotest.Person] { return seed() },
+	)
+	dynamotest.TestRemove(t, codec,
+		func(_, returnVal *dynamotest.Person) dynamo.KeyVal[dynamotest.Person] { return seed(returnVal) },
+	)
+	dynamotest.TestUpdate(t, codec,
+		func(_, _, returnVal *dynamotest.Person) dynamo.KeyVal[dynamotest.Person] { return seed(returnVal) },
+	)
+}
+
+func person(suffix string, age int) dynamotest.Person {
+	return dynamotest.Person{
+		Prefix: curie.New("dead:beef"),
+		Suffix: curie.IRI(suffix),
+		Name:   "Verner Pleishner",
+		Age:    age,
+	}
+}
+
+func fixture() *mem.Storage[dynamotest.Person] {
+	db := mem.New[dynamotest.Person]()
+	for i, suffix := range []string{"b/2", "a/1", "b/1", "c/1", "a/2"} {
+		db.Put(context.Background(), person(suffix, 60+i))
+	}
+	db.Put(context.Background(), dynamotest.Person{Prefix: curie.New("dead:bee"), Suffix: "a/1"})
+	return db
+}
+
+func suffixes(seq []dynamotest.Person) []curie.IRI {
+	keys := make([]curie.IRI, len(seq))
+	for i, x := range seq {
+		keys[i] = x.Suffix
+	}
+	return keys
+}
+
+func TestMemMatch(t *testing.T) {
+	db := fixture()
+	key := dynamotest.Person{Prefix: curie.New("dead:beef")}
+
+	t.Run("HashKey", func(t *testing.T) {
+		seq, cur, err := db.Match(context.Background(), key)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			IfNil(cur).
+			If(suffixes(seq)).Should().Equal([]curie.IRI{"a/1", "a/2", "b/1", "b/2", "c/1"})
+	})
+
+	t.Run("SortKeyPrefix", func(t *testing.T) {
+		seq, _, err := db.Match(context.Background(), dynamotest.Person{Prefix: curie.New("dead:beef"), Suffix: "b/"})
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(suffixes(seq)).Should().Equal([]curie.IRI{"b/1", "b/2"})
+	})
+
+	t.Run("SortKeyCondition", func(t *testing.T) {
+		seq, _, err := db.Match(context.Background(), key, dynamo.SortKeyBetween[dynamotest.Person]("a/2", "b/2"))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(suffixes(seq)).Should().Equal([]curie.IRI{"a/2", "b/1", "b/2"})
+	})
+
+	t.Run("SortKeyConditionMultiple", func(t *testing.T) {
+		_, _, err := db.Match(context.Background(), key,
+			dynamo.SortKeyGt[dynamotest.Person]("a/1"),
+			dynamo.SortKeyLt[dynamotest.Person]("c/1"),
+		)
+		it.Ok(t).IfNotNil(err)
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		seq := []curie.IRI{}
+		var cur interface{ MatcherOpt(dynamotest.Person) }
+		for {
+			opts := []interface{ MatcherOpt(dynamotest.Person) }{dynamo.Limit[dynamotest.Person](2)}
+			if cur != nil {
+				opts = append(opts, cur)
+			}
+
+			page, next, err := db.Match(context.Background(), key, opts...)
+			it.Ok(t).If(err).Should().Equal(nil)
+			seq = append(seq, suffixes(page)...)
+
+			if next == nil {
+				break
+			}
+			cur = next
+		}
+
+		it.Ok(t).If(seq).Should().Equal([]curie.IRI{"a/1", "a/2", "b/1", "b/2", "c/1"})
+	})
+
+	t.Run("Descending", func(t *testing.T) {
+		page, cur, err := db.Match(context.Background(), key,
+			dynamo.Descending[dynamotest.Person](),
+			dynamo.Limit[dynamotest.Person](3),
+		)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(suffixes(page)).Should().Equal([]curie.IRI{"c/1", "b/2", "b/1"})
+
+		page, cur, err = db.Match(context.Background(), key,
+			dynamo.Descending[dynamotest.Person](),
+			dynamo.Limit[dynamotest.Person](3),
+			cur,
+		)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			IfNil(cur).
+			If(suffixes(page)).Should().Equal([]curie.IRI{"a/2", "a/1"})
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		age := ddb.FilterFor[dynamotest.Person, int]("Age")
+		seq, _, err := db.Match(context.Background(), key, age.Ge(62), dynamo.Limit[dynamotest.Person](3))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(suffixes(seq)).Should().Equal([]curie.IRI{"a/2", "b/1", "c/1"})
+	})
+
+	t.Run("FilterPagination", func(t *testing.T) {
+		age := ddb.FilterFor[dynamotest.Person, int]("Age")
+		seq, cur, err := db.Match(context.Background(), key, age.Ge(62), dynamo.Limit[dynamotest.Person](2))
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			IfNotNil(cur).
+			If(suffixes(seq)).Should().Equal([]curie.IRI{"a/2", "b/1"})
+
+		seq, cur, err = db.Match(context.Background(), key, age.Ge(62), dynamo.Limit[dynamotest.Person](2), cur)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			IfNil(cur).
+			If(suffixes(seq)).Should().Equal([]curie.IRI{"c/1"})
+	})
+
+	t.Run("InvalidKey", func(t *testing.T) {
+		_, _, err := db.Match(context.Background(), dynamotest.Person{})
+		it.Ok(t).IfNotNil(err)
+	})
+}
+
+func TestMemWithConstrain(t *testing.T) {
+	name := ddb.ClauseFor[dynamotest.Person, string]("Name")
+	db := mem.New[dynamotest.Person]()
+	val := person("1", 64)
+
+	t.Run("Put", func(t *testing.T) {
+		success := db.Put(context.Background(), val, name.NotExists())
+		failure := db.Put(context.Background(), val, name.NotExists())
+		e, ok := failure.(interface{ Conflict() bool })
+		it.Ok(t).
+			If(success).Should().Equal(nil).
+			IfTrue(ok).
+			IfTrue(e.Conflict())
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		patch := dynamotest.Person{Prefix: val.Prefix, Suffix: val.Suffix, Address: "Blumenstrasse 14, Berne, 3013"}
+		obj, success := db.Update(context.Background(), patch, name.Eq("Verner Pleishner"))
+		_, failure := db.Update(context.Background(), patch, name.Eq("Eduard"))
+		_, ok := failure.(interface{ PreConditionFailed() bool })
+		it.Ok(t).
+			If(success).Should().Equal(nil).
+			If(obj.Age).Should().Equal(64).
+			If(obj.Address).Should().Equal("Blumenstrasse 14, Berne, 3013").
+			IfTrue(ok)
+	})
+
+	t.Run("Logical", func(t *testing.T) {
+		patch := dynamotest.Person{Prefix: val.Prefix, Suffix: val.Suffix, Age: 65}
+		_, success := db.Update(context.Background(), patch, ddb.Or(name.Eq("Eduard"), name.Exists()))
+		_, failure := db.Update(context.Background(), patch, ddb.Not(ddb.Or(name.Eq("Eduard"), name.Exists())))
+		_, ok := failure.(interface{ PreConditionFailed() bool })
+		it.Ok(t).
+			If(success).Should().Equal(nil).
+			IfTrue(ok)
+	})
+
+	t.Run("InvalidExpression", func(t *testing.T) {
+		failure := db.Put(context.Background(), val, name.In())
+		e, ok := failure.(interface{ InvalidExpression() bool })
+		it.Ok(t).
+			IfTrue(ok).
+			IfTrue(e.InvalidExpression())
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		_, failure := db.Remove(context.Background(), val, name.Ne("Verner Pleishner"))
+		_, success := db.Remove(context.Background(), val, name.Exists())
+		_, notfound := db.Get(context.Background(), val)
+		e, ok := failure.(interface{ Gone() bool })
+		_, isnfe := notfound.(interface{ NotFound() string })
+		it.Ok(t).
+			IfTrue(ok).
+			IfTrue(e.Gone()).
+			If(success).Should().Equal(nil).
+			IfTrue(isnfe)
+	})
+}
+
+func TestMemConcurrent(t *testing.T) {
+	db := mem.New[dynamotest.Person]()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			db.Put(context.Background(), person(fmt.Sprintf("%03d", i), i))
+		}(i)
+	}
+	wg.Wait()
+
+	seq, _, err := db.Match(context.Background(), dynamotest.Person{Prefix: curie.New("dead:beef")})
+	it.Ok(t).
+		If(err).Should().Equal(nil).
+		If(len(seq)).Should().Equal(100).
+		If(seq[0].Suffix).Should().Equal(curie.IRI("000")).
+		If(seq[99].Suffix).Should().Equal(curie.IRI("099"))
+}
+
+type account struct {
+	Prefix curie.IRI `dynamodbav:"prefix,omitempty"`
+	Suffix curie.IRI `dynamodbav:"suffix,omitempty"`
+	Name   *string   `dynamodbav:"name,omitempty"`
+	Email  *string   `dynamodbav:"email,omitempty"`
+	Score  int       `dynamodbav:"score"`
+}
+
+func (p account) HashKey() curie.IRI { return p.Prefix }
+func (p account) SortKey() curie.IRI { return p.Suffix }
+
+func TestMemPartialUpdate(t *testing.T) {
+	name, email := "Verner Pleishner", "verner@example.com"
+	db := mem.New[account]()
+	val := account{Prefix: curie.New("dead:beef"), Suffix: "1", Name: &name, Email: &email, Score: 10}
+	it.Ok(t).If(db.Put(context.Background(), val)).Should().Equal(nil)
+
+	patch := account{Prefix: val.Prefix, Suffix: val.Suffix, Email: dynamo.Null[string]()}
+	obj, err := db.Update(context.Background(), patch, dynamo.PartialUpdate[account]())
+	it.Ok(t).
+		If(err).Should().Equal(nil).
+		If(*obj.Name).Should().Equal(name).
+		IfNil(obj.Email).
+		If(obj.Score).Should().Equal(10)
+
+	_, err = db.Update(context.Background(), account{Prefix: val.Prefix, Suffix: val.Suffix}, dynamo.PartialUpdate[account]())
+	it.Ok(t).IfNotNil(err)
+}
@@ -0,0 +1,31 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package mem
+
+import (
+	"context"
+)
+
+// Get item from storage
+func (db *Storage[T]) Get(ctx context.Context, key T, opts ...interface{ GetterOpt(T) }) (T, error) {
+	hashKey, sortKey, err := keyOf(key)
+	if err != nil {
+		return db.undefined, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	i, has := db.lookup(hashKey, sortKey)
+	if !has {
+		return db.undefined, errNotFound(nil, key)
+	}
+
+	return db.decode(db.seq[i].item)
+}
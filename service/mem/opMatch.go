@@ -0,0 +1,155 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package mem
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/fogfish/curie"
+	"github.com/fogfish/dynamo/v3"
+)
+
+// MatchKey applies a pattern matching to elements in the storage
+func (db *Storage[T]) MatchKey(ctx context.Context, key dynamo.Thing, opts ...interface{ MatcherOpt(T) }) ([]T, interface{ MatcherOpt(T) }, error) {
+	return db.match(key, opts)
+}
+
+// Match applies a pattern matching to elements in the storage
+func (db *Storage[T]) Match(ctx context.Context, key T, opts ...interface{ MatcherOpt(T) }) ([]T, interface{ MatcherOpt(T) }, error) {
+	return db.match(key, opts)
+}
+
+// match follows DynamoDB semantic: elements shares the hash key and the sort key
+// begins with the prefix defined by the key.
+func (db *Storage[T]) match(key dynamo.Thing, opts []interface{ MatcherOpt(T) }) ([]T, interface{ MatcherOpt(T) }, error) {
+	hashKey := string(key.HashKey())
+	if hashKey == "" {
+		return nil, nil, errInvalidKey.New(errEmptyHashKey(key))
+	}
+
+	var (
+		prefix     = string(key.SortKey())
+		limit      = -1
+		descending = false
+		after      dynamo.Thing
+		sortKeyOp  string
+		sortKeyVal []curie.IRI
+	)
+
+	for _, opt := range opts {
+		switch v := opt.(type) {
+		case interface{ Limit() int32 }:
+			limit = int(v.Limit())
+		case interface{ Descending() bool }:
+			descending = v.Descending()
+		case interface {
+			SortKeyCondition() (string, []curie.IRI)
+		}:
+			if sortKeyOp != "" {
+				return nil, nil, errInvalidKey.New(errors.New("sort key conditions are exclusive, use SortKeyBetween for range"))
+			}
+			sortKeyOp, sortKeyVal = v.SortKeyCondition()
+		case dynamo.Thing:
+			after = v
+		}
+	}
+
+	if prefix != "" && sortKeyOp != "" {
+		return nil, nil, errInvalidKey.New(errors.New("sort key prefix cannot be combined with sort key condition"))
+	}
+
+	filter, _, err := expressionOf(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	lo := sort.Search(len(db.seq), func(i int) bool { return db.seq[i].hashKey >= hashKey })
+	hi := sort.Search(len(db.seq), func(i int) bool { return db.seq[i].hashKey > hashKey })
+
+	seq := make([]T, 0)
+	for n := 0; n < hi-lo; n++ {
+		i := lo + n
+		if descending {
+			i = hi - 1 - n
+		}
+		e := db.seq[i]
+
+		if after != nil {
+			if !descending && e.sortKey <= string(after.SortKey()) {
+				continue
+			}
+			if descending && e.sortKey >= string(after.SortKey()) {
+				continue
+			}
+		}
+
+		if !strings.HasPrefix(e.sortKey, prefix) || !isSortKeyMatch(e.sortKey, sortKeyOp, sortKeyVal) {
+			continue
+		}
+
+		// ddb.Storage semantic, reading continues until the limit of
+		// elements matching the filter is reached
+		if filter != nil && !filter(e.item) {
+			continue
+		}
+
+		obj, err := db.decode(e.item)
+		if err != nil {
+			return nil, nil, err
+		}
+		seq = append(seq, obj)
+
+		if len(seq) == limit {
+			if n == hi-lo-1 {
+				return seq, nil, nil
+			}
+			return seq, dynamo.Cursor[T](&cursor{hashKey: e.hashKey, sortKey: e.sortKey}), nil
+		}
+	}
+
+	return seq, nil, nil
+}
+
+func isSortKeyMatch(sortKey string, op string, seq []curie.IRI) bool {
+	switch op {
+	case "":
+		return true
+	case "BETWEEN":
+		return len(seq) == 2 && sortKey >= string(seq[0]) && sortKey <= string(seq[1])
+	}
+
+	if len(seq) != 1 {
+		return false
+	}
+
+	val := string(seq[0])
+	switch op {
+	case "<":
+		return sortKey < val
+	case "<=":
+		return sortKey <= val
+	case ">":
+		return sortKey > val
+	case ">=":
+		return sortKey >= val
+	}
+
+	return false
+}
+
+type cursor struct{ hashKey, sortKey string }
+
+func (c cursor) HashKey() curie.IRI { return curie.IRI(c.hashKey) }
+func (c cursor) SortKey() curie.IRI { return curie.IRI(c.sortKey) }
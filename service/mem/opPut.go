@@ -0,0 +1,58 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package mem
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Put writes entity
+func (db *Storage[T]) Put(ctx context.Context, entity T, opts ...interface{ WriterOpt(T) }) error {
+	hashKey, sortKey, err := keyOf(entity)
+	if err != nil {
+		return err
+	}
+
+	gen, err := db.encode(entity)
+	if err != nil {
+		return err
+	}
+
+	cond, expr, err := expressionOf(opts)
+	if err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i, has := db.lookup(hashKey, sortKey)
+	if cond != nil && !cond(db.itemAt(i, has)) {
+		return errConditionalCheckFailed(entity, expr)
+	}
+
+	if has {
+		db.seq[i].item = gen
+	} else {
+		db.insert(i, entry{hashKey: hashKey, sortKey: sortKey, item: gen})
+	}
+
+	return nil
+}
+
+// itemAt returns item at position or empty item if it does not exist
+func (db *Storage[T]) itemAt(i int, has bool) map[string]types.AttributeValue {
+	if !has {
+		return map[string]types.AttributeValue{}
+	}
+
+	return db.seq[i].item
+}
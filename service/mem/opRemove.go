@@ -0,0 +1,47 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package mem
+
+import (
+	"context"
+)
+
+// Remove discards the entity from the storage
+func (db *Storage[T]) Remove(ctx context.Context, key T, opts ...interface{ WriterOpt(T) }) (T, error) {
+	hashKey, sortKey, err := keyOf(key)
+	if err != nil {
+		return db.undefined, err
+	}
+
+	cond, expr, err := expressionOf(opts)
+	if err != nil {
+		return db.undefined, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i, has := db.lookup(hashKey, sortKey)
+	if cond != nil && !cond(db.itemAt(i, has)) {
+		return db.undefined, errConditionalCheckFailed(key, expr)
+	}
+
+	if !has {
+		return db.undefined, errNotFound(nil, key)
+	}
+
+	obj, err := db.decode(db.seq[i].item)
+	if err != nil {
+		return db.undefined, err
+	}
+
+	db.delete(i)
+
+	return obj, nil
+}
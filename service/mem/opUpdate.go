@@ -0,0 +1,84 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package mem
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/dynamo/v3/internal/partial"
+)
+
+// Update applies a partial patch to entity and returns new values.
+// Attributes defined by the patch overrides existing one, the entity is
+// created if it does not exist.
+func (db *Storage[T]) Update(ctx context.Context, entity T, opts ...interface{ WriterOpt(T) }) (T, error) {
+	hashKey, sortKey, err := keyOf(entity)
+	if err != nil {
+		return db.undefined, err
+	}
+
+	gen, err := db.encode(entity)
+	if err != nil {
+		return db.undefined, err
+	}
+
+	cond, expr, err := expressionOf(opts)
+	if err != nil {
+		return db.undefined, err
+	}
+
+	if partial.Requested(opts) && partial.Empty(entity) {
+		return db.undefined, errInvalidRequest.New(fmt.Errorf("partial update of %T does not define any attribute", entity))
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i, has := db.lookup(hashKey, sortKey)
+	existing := db.itemAt(i, has)
+	if cond != nil && !cond(existing) {
+		return db.undefined, errConditionalCheckFailed(entity, expr)
+	}
+
+	item := make(map[string]types.AttributeValue, len(existing)+len(gen))
+	for k, v := range existing {
+		item[k] = v
+	}
+	if partial.Requested(opts) {
+		for _, f := range partial.Fields(entity) {
+			switch f.Action {
+			case partial.Set:
+				if v, has := gen[f.Name]; has {
+					item[f.Name] = v
+				}
+			case partial.Remove:
+				delete(item, f.Name)
+			}
+		}
+	} else {
+		for k, v := range gen {
+			item[k] = v
+		}
+	}
+
+	obj, err := db.decode(item)
+	if err != nil {
+		return db.undefined, err
+	}
+
+	if has {
+		db.seq[i].item = item
+	} else {
+		db.insert(i, entry{hashKey: hashKey, sortKey: sortKey, item: item})
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,90 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+//
+// The file implements s3 specific constraints
+//
+
+package s3
+
+import (
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/dynamo/v3"
+	"github.com/fogfish/dynamo/v3/internal/ddbexpr"
+)
+
+// AWS S3 does not support conditional expressions. The storage evaluates
+// DynamoDB condition expressions (e.g. ddb.ClauseFor) against the object read
+// from the bucket and writes the object with ETag preconditions (If-Match,
+// If-None-Match), which guarantees that the object has not been changed since
+// the check. The object is evaluated as DynamoDB item encoded using
+// `dynamodbav` tags, same semantic of attributes is applied (e.g. attribute
+// exists if it is encoded).
+type condition[T dynamo.Thing] struct {
+	eval ddbexpr.Condition
+	expr string
+}
+
+// conditionOf evaluates options that declare DynamoDB condition expressions,
+// it returns nil if the write is not conditional.
+func conditionOf[T dynamo.Thing](opts []interface{ WriterOpt(T) }) (*condition[T], error) {
+	var (
+		expr   *string
+		names  = map[string]string{}
+		values = map[string]types.AttributeValue{}
+	)
+
+	errs := make([]error, 0)
+	for _, opt := range opts {
+		if ap, ok := opt.(interface {
+			Apply(**string, map[string]string, map[string]types.AttributeValue) error
+		}); ok {
+			if err := ap.Apply(&expr, names, values); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errInvalidExpression(errs...)
+	}
+
+	if expr == nil {
+		return nil, nil
+	}
+
+	eval, err := ddbexpr.New(names, values).Condition(*expr)
+	if err != nil {
+		return nil, errInvalidRequest.New(err)
+	}
+
+	return &condition[T]{eval: eval, expr: *expr}, nil
+}
+
+// check the condition against the object, nil object does not exist
+func (c *condition[T]) check(key T, entity *T) error {
+	if c == nil {
+		return nil
+	}
+
+	var item ddbexpr.Item
+	if entity != nil {
+		gen, err := attributevalue.MarshalMap(*entity)
+		if err != nil {
+			return errInvalidEntity.New(err)
+		}
+		item = gen
+	}
+
+	if !c.eval(item) {
+		return errConditionalCheckFailed(key, c.expr)
+	}
+
+	return nil
+}
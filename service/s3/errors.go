@@ -11,6 +11,7 @@ package s3
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/fogfish/dynamo/v3"
 	"github.com/fogfish/faults"
@@ -20,6 +21,7 @@ const (
 	errUndefinedBucket = faults.Type("undefined S3 bucket")
 	errServiceIO       = faults.Type("service i/o failed")
 	errInvalidEntity   = faults.Type("invalid entity")
+	errInvalidRequest  = faults.Type("invalid request")
 )
 
 // NotFound is an error to handle unknown elements
@@ -43,9 +45,80 @@ func (e *notFound) NotFound() string {
 }
 
 // recover
+func errPreConditionFailed(err error, thing dynamo.Thing, conflict bool, gone bool) error {
+	return &preConditionFailed{Thing: thing, conflict: conflict, gone: gone, err: err}
+}
+
+type preConditionFailed struct {
+	dynamo.Thing
+	conflict bool
+	gone     bool
+	err      error
+}
+
+func (e *preConditionFailed) Error() string {
+	return fmt.Sprintf("Pre Condition Failed (%s, %s)", e.HashKey(), e.SortKey())
+}
+
+func (e *preConditionFailed) PreConditionFailed() bool { return true }
+
+func (e *preConditionFailed) Conflict() bool { return e.conflict }
+
+func (e *preConditionFailed) Gone() bool { return e.gone }
+
+func (e *preConditionFailed) Unwrap() error { return e.err }
+
+// errConditionalCheckFailed builds errPreConditionFailed from the condition expression
+func errConditionalCheckFailed(thing dynamo.Thing, expr string) error {
+	return errPreConditionFailed(nil, thing,
+		strings.Contains(expr, "attribute_not_exists") || strings.Contains(expr, "="),
+		strings.Contains(expr, "attribute_exists") || strings.Contains(expr, "<>"),
+	)
+}
+
+// errInvalidExpression is an error to handle condition expressions that
+// cannot be built.
+func errInvalidExpression(errs ...error) error {
+	return &invalidExpression{errs: errs}
+}
+
+type invalidExpression struct {
+	errs []error
+}
+
+func (e *invalidExpression) Error() string {
+	seq := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		seq[i] = err.Error()
+	}
+	return fmt.Sprintf("Invalid Expression (%s)", strings.Join(seq, "; "))
+}
+
+func (e *invalidExpression) Unwrap() []error { return e.errs }
+
+func (e *invalidExpression) InvalidExpression() bool { return true }
+
+func recoverNotFound(err error) bool {
+	var e *notFound
+	return errors.As(err, &e)
+}
+
 func recoverNoSuchKey(err error) bool {
 	var e interface{ ErrorCode() string }
 
 	ok := errors.As(err, &e)
 	return ok && e.ErrorCode() == "NoSuchKey"
 }
+
+func recoverPreconditionFailed(err error) bool {
+	var code interface{ ErrorCode() string }
+	if errors.As(err, &code) {
+		switch code.ErrorCode() {
+		case "PreconditionFailed", "ConditionalRequestConflict":
+			return true
+		}
+	}
+
+	var http interface{ HTTPStatusCode() int }
+	return errors.As(err, &http) && http.HTTPStatusCode() == 412
+}
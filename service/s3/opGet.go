@@ -18,6 +18,12 @@ import (
 
 // Get item from storage
 func (db *Storage[T]) Get(ctx context.Context, key T, opts ...interface{ GetterOpt(T) }) (T, error) {
+	entity, _, err := db.get(ctx, key)
+	return entity, err
+}
+
+// get reads entity and its ETag
+func (db *Storage[T]) get(ctx context.Context, key T) (T, *string, error) {
 	req := &s3.GetObjectInput{
 		Bucket: db.bucket,
 		Key:    aws.String(db.codec.EncodeKey(key)),
@@ -27,17 +33,17 @@ func (db *Storage[T]) Get(ctx context.Context, key T, opts ...interface{ GetterO
 	if err != nil {
 		switch {
 		case recoverNoSuchKey(err):
-			return db.undefined, errNotFound(err, key)
+			return db.undefined, nil, errNotFound(err, key)
 		default:
-			return db.undefined, errServiceIO.New(err)
+			return db.undefined, nil, errServiceIO.New(err)
 		}
 	}
 
 	var entity T
 	err = json.NewDecoder(val.Body).Decode(&entity)
 	if err != nil {
-		return db.undefined, errInvalidEntity.New(err)
+		return db.undefined, nil, errInvalidEntity.New(err)
 	}
 
-	return entity, nil
+	return entity, val.ETag, nil
 }
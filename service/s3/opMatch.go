@@ -11,6 +11,7 @@ package s3
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -19,12 +20,26 @@ import (
 )
 
 func (db *Storage[T]) MatchKey(ctx context.Context, key dynamo.Thing, opts ...interface{ MatcherOpt(T) }) ([]T, interface{ MatcherOpt(T) }, error) {
-	req := db.reqListObjects(key, opts)
+	if isDescending(opts) {
+		return db.matchDescending(ctx, key, opts)
+	}
+
+	req, err := db.reqListObjects(key, opts)
+	if err != nil {
+		return nil, nil, err
+	}
 	return db.match(ctx, req)
 }
 
 func (db *Storage[T]) Match(ctx context.Context, key T, opts ...interface{ MatcherOpt(T) }) ([]T, interface{ MatcherOpt(T) }, error) {
-	req := db.reqListObjects(key, opts)
+	if isDescending(opts) {
+		return db.matchDescending(ctx, key, opts)
+	}
+
+	req, err := db.reqListObjects(key, opts)
+	if err != nil {
+		return nil, nil, err
+	}
 	return db.match(ctx, req)
 }
 
@@ -36,28 +51,91 @@ func (db *Storage[T]) match(ctx context.Context, req *s3.ListObjectsV2Input) ([]
 
 	seq := make([]T, aws.ToInt32(val.KeyCount))
 	for i := 0; i < int(aws.ToInt32(val.KeyCount)); i++ {
-		req := &s3.GetObjectInput{
-			Bucket: db.bucket,
-			Key:    val.Contents[i].Key,
+		head, err := db.getObject(ctx, val.Contents[i].Key)
+		if err != nil {
+			return nil, nil, err
 		}
-		val, err := db.service.GetObject(ctx, req)
+
+		seq[i] = head
+	}
+
+	return seq, lastKeyToCursor[T](val), nil
+}
+
+// S3 lists objects in ascending order only. The descending order is emulated
+// by listing all objects matching the prefix.
+func (db *Storage[T]) matchDescending(ctx context.Context, key dynamo.Thing, opts []interface{ MatcherOpt(T) }) ([]T, interface{ MatcherOpt(T) }, error) {
+	req, err := db.reqListObjects(key, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	limit := int(aws.ToInt32(req.MaxKeys))
+	before := aws.ToString(req.StartAfter)
+	req.StartAfter = nil
+	req.MaxKeys = nil
+
+	keys := make([]*string, 0)
+	for {
+		val, err := db.service.ListObjectsV2(ctx, req)
 		if err != nil {
 			return nil, nil, errServiceIO.New(err)
 		}
 
-		var head T
-		err = json.NewDecoder(val.Body).Decode(&head)
+		for i := 0; i < len(val.Contents); i++ {
+			if before == "" || aws.ToString(val.Contents[i].Key) < before {
+				keys = append(keys, val.Contents[i].Key)
+			}
+		}
+
+		if val.NextContinuationToken == nil {
+			break
+		}
+		req.ContinuationToken = val.NextContinuationToken
+	}
+
+	n := len(keys)
+	if n > limit {
+		n = limit
+	}
+
+	seq := make([]T, n)
+	for i := 0; i < n; i++ {
+		head, err := db.getObject(ctx, keys[len(keys)-1-i])
 		if err != nil {
-			return nil, nil, errInvalidEntity.New(err)
+			return nil, nil, err
 		}
 
 		seq[i] = head
 	}
 
-	return seq, lastKeyToCursor[T](val), nil
+	if n == 0 || n == len(keys) {
+		return seq, nil, nil
+	}
+
+	return seq, dynamo.Cursor[T](&cursor{hashKey: aws.ToString(keys[len(keys)-n])}), nil
 }
 
-func (db *Storage[T]) reqListObjects(key dynamo.Thing, opts []interface{ MatcherOpt(T) }) *s3.ListObjectsV2Input {
+func (db *Storage[T]) getObject(ctx context.Context, key *string) (T, error) {
+	req := &s3.GetObjectInput{
+		Bucket: db.bucket,
+		Key:    key,
+	}
+	val, err := db.service.GetObject(ctx, req)
+	if err != nil {
+		return db.undefined, errServiceIO.New(err)
+	}
+
+	var head T
+	err = json.NewDecoder(val.Body).Decode(&head)
+	if err != nil {
+		return db.undefined, errInvalidEntity.New(err)
+	}
+
+	return head, nil
+}
+
+// S3 lists objects by prefix only, sort key conditions are not supported.
+func (db *Storage[T]) reqListObjects(key dynamo.Thing, opts []interface{ MatcherOpt(T) }) (*s3.ListObjectsV2Input, error) {
 	var (
 		limit  int32   = 1000
 		cursor *string = nil
@@ -66,6 +144,11 @@ func (db *Storage[T]) reqListObjects(key dynamo.Thing, opts []interface{ Matcher
 		switch v := opt.(type) {
 		case interface{ Limit() int32 }:
 			limit = v.Limit()
+		case interface {
+			SortKeyCondition() (string, []curie.IRI)
+		}:
+			op, _ := v.SortKeyCondition()
+			return nil, errInvalidRequest.New(fmt.Errorf("sort key condition %s is not supported by s3", op))
 		case dynamo.Thing:
 			cursor = aws.String(db.codec.EncodeKey(v))
 		}
@@ -76,7 +159,17 @@ func (db *Storage[T]) reqListObjects(key dynamo.Thing, opts []interface{ Matcher
 		MaxKeys:    aws.Int32(limit),
 		Prefix:     aws.String(db.codec.EncodeKey(key)),
 		StartAfter: cursor,
+	}, nil
+}
+
+func isDescending[T dynamo.Thing](opts []interface{ MatcherOpt(T) }) bool {
+	for _, opt := range opts {
+		switch v := opt.(type) {
+		case interface{ Descending() bool }:
+			return v.Descending()
+		}
 	}
+	return false
 }
 
 type cursor struct{ hashKey, sortKey string }
@@ -15,10 +15,56 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 )
 
+// number of attempts to write the object if it is concurrently changed
+const writeAttempts = 5
+
 // Put writes entity
 func (db *Storage[T]) Put(ctx context.Context, entity T, opts ...interface{ WriterOpt(T) }) error {
+	cond, err := conditionOf(opts)
+	if err != nil {
+		return err
+	}
+
+	if db.version == nil && cond == nil {
+		return db.put(ctx, entity)
+	}
+
+	return db.retry(func() error { return db.putIf(ctx, entity, cond) })
+}
+
+// putIf writes entity if conditions are satisfied by the existing object
+func (db *Storage[T]) putIf(ctx context.Context, entity T, cond *condition[T]) error {
+	var expected int64
+	if db.version != nil {
+		expected = db.version.Get(entity)
+	}
+
+	// create-only write does not need to read the object
+	if db.version != nil && expected == 0 && cond == nil {
+		return db.put(ctx, db.version.With(entity, 1), ifNoneMatch())
+	}
+
+	existing, etag, err := db.lookup(ctx, entity)
+	if err != nil {
+		return err
+	}
+
+	if err := db.check(entity, existing, expected, cond); err != nil {
+		return err
+	}
+
+	if db.version != nil {
+		entity = db.version.With(entity, expected+1)
+	}
+
+	return db.put(ctx, entity, preconditionOf(existing != nil, etag)...)
+}
+
+// put writes entity, the write is conditional if preconditions are given
+func (db *Storage[T]) put(ctx context.Context, entity T, preconditions ...func(*s3.Options)) error {
 	gen, err := json.Marshal(entity)
 	if err != nil {
 		return errInvalidEntity.New(err)
@@ -30,10 +76,80 @@ func (db *Storage[T]) Put(ctx context.Context, entity T, opts ...interface{ Writ
 		Body:   bytes.NewReader(gen),
 	}
 
-	_, err = db.service.PutObject(ctx, req)
+	_, err = db.service.PutObject(ctx, req, preconditions...)
 	if err != nil {
+		if recoverPreconditionFailed(err) {
+			return errPreConditionFailed(err, entity, true, false)
+		}
 		return errServiceIO.New(err)
 	}
 
 	return nil
 }
+
+// lookup reads the object and its ETag, nil object does not exist
+func (db *Storage[T]) lookup(ctx context.Context, key T) (*T, *string, error) {
+	existing, etag, err := db.get(ctx, key)
+	if err != nil {
+		if recoverNotFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	return &existing, etag, nil
+}
+
+// check the version and condition against the existing object
+func (db *Storage[T]) check(key T, existing *T, expected int64, cond *condition[T]) error {
+	if db.version != nil {
+		var actual int64
+		if existing != nil {
+			actual = db.version.Get(*existing)
+		}
+
+		if actual != expected {
+			return errPreConditionFailed(nil, key, true, false)
+		}
+	}
+
+	return cond.check(key, existing)
+}
+
+// retry the write if the object is changed by other writer since it was read
+func (db *Storage[T]) retry(f func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt == writeAttempts || !recoverPreconditionFailed(err) {
+			return err
+		}
+	}
+}
+
+// preconditionOf demands that object is not changed since it was read
+func preconditionOf(exists bool, etag *string) []func(*s3.Options) {
+	switch {
+	case !exists:
+		return []func(*s3.Options){ifNoneMatch()}
+	case etag != nil:
+		return []func(*s3.Options){ifMatch(etag)}
+	default:
+		return nil
+	}
+}
+
+// ifMatch precondition demands that object is not changed since it was read
+func ifMatch(etag *string) func(*s3.Options) {
+	return withHeader("If-Match", aws.ToString(etag))
+}
+
+// ifNoneMatch precondition demands that object does not exist
+func ifNoneMatch() func(*s3.Options) {
+	return withHeader("If-None-Match", "*")
+}
+
+func withHeader(header, value string) func(*s3.Options) {
+	return func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, smithyhttp.SetHeaderValue(header, value))
+	}
+}
@@ -17,18 +17,43 @@ import (
 
 // Remove discards the entity from the table
 func (db *Storage[T]) Remove(ctx context.Context, key T, opts ...interface{ WriterOpt(T) }) (T, error) {
-	obj, err := db.Get(ctx, key)
+	cond, err := conditionOf(opts)
 	if err != nil {
 		return db.undefined, err
 	}
 
+	var obj T
+	err = db.retry(func() (err error) {
+		obj, err = db.remove(ctx, key, cond)
+		return
+	})
+	if err != nil {
+		return db.undefined, err
+	}
+
+	return obj, nil
+}
+
+func (db *Storage[T]) remove(ctx context.Context, key T, cond *condition[T]) (T, error) {
+	obj, etag, err := db.get(ctx, key)
+	if err != nil {
+		return db.undefined, err
+	}
+
+	if err := cond.check(key, &obj); err != nil {
+		return db.undefined, err
+	}
+
 	req := &s3.DeleteObjectInput{
 		Bucket: db.bucket,
 		Key:    aws.String(db.codec.EncodeKey(key)),
 	}
 
-	_, err = db.service.DeleteObject(ctx, req)
+	_, err = db.service.DeleteObject(ctx, req, preconditionOf(true, etag)...)
 	if err != nil {
+		if recoverPreconditionFailed(err) {
+			return db.undefined, errPreConditionFailed(err, key, true, false)
+		}
 		return db.undefined, errServiceIO.New(err)
 	}
 
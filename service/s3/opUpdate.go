@@ -10,47 +10,67 @@ package s3
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
+	"fmt"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/fogfish/dynamo/v3/internal/partial"
 )
 
-// Update applies a partial patch to entity and returns new values
+// Update applies a partial patch to entity and returns new values.
+// The object is written only if it is not changed since it was read,
+// the update is repeated otherwise.
 func (db *Storage[T]) Update(ctx context.Context, entity T, opts ...interface{ WriterOpt(T) }) (T, error) {
-	req := &s3.GetObjectInput{
-		Bucket: db.bucket,
-		Key:    aws.String(db.codec.EncodeKey(entity)),
+	cond, err := conditionOf(opts)
+	if err != nil {
+		return db.undefined, err
 	}
 
-	val, err := db.service.GetObject(ctx, req)
-	if err != nil {
-		var nsk *types.NoSuchKey
-		if errors.As(err, &nsk) {
-			err := db.Put(ctx, entity)
-			if err != nil {
-				return db.undefined, err
-			}
-			return entity, nil
+	merge := db.schema.Merge
+	if partial.Requested(opts) {
+		if db.version == nil && partial.Empty(entity) {
+			return db.undefined, errInvalidRequest.New(fmt.Errorf("partial update of %T does not define any attribute", entity))
 		}
-
-		return db.undefined, errServiceIO.New(err)
+		merge = db.schema.Patch
 	}
 
-	var existing T
-	err = json.NewDecoder(val.Body).Decode(&existing)
+	var updated T
+	err = db.retry(func() (err error) {
+		updated, err = db.update(ctx, entity, cond, merge)
+		return
+	})
 	if err != nil {
-		return db.undefined, errInvalidEntity.New(err)
+		return db.undefined, err
 	}
 
-	updated := db.schema.Merge(entity, existing)
+	return updated, nil
+}
 
-	err = db.Put(ctx, updated)
+func (db *Storage[T]) update(ctx context.Context, entity T, cond *condition[T], merge func(a, b T) T) (T, error) {
+	existing, etag, err := db.lookup(ctx, entity)
 	if err != nil {
 		return db.undefined, err
 	}
 
+	var expected int64
+	if db.version != nil {
+		expected = db.version.Get(entity)
+	}
+
+	if err := db.check(entity, existing, expected, cond); err != nil {
+		return db.undefined, err
+	}
+
+	updated := entity
+	if existing != nil {
+		updated = merge(entity, *existing)
+	}
+
+	if db.version != nil {
+		updated = db.version.With(updated, expected+1)
+	}
+
+	if err := db.put(ctx, updated, preconditionOf(existing != nil, etag)...); err != nil {
+		return db.undefined, err
+	}
+
 	return updated, nil
 }
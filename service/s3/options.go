@@ -30,6 +30,7 @@ type Option func(*Options)
 type Options struct {
 	prefixes curie.Prefixes
 	bucket   string
+	version  string
 	service  S3
 }
 
@@ -54,6 +55,14 @@ func WithBucket(bucket string) Option {
 	}
 }
 
+// WithVersion enables optimistic locking using the numeric struct field as
+// the version of entity. The version is enforced with ETag preconditions.
+func WithVersion(field string) Option {
+	return func(c *Options) {
+		c.version = field
+	}
+}
+
 // Configure AWS Service for broker instance
 func WithService(service S3) Option {
 	return func(c *Options) {
@@ -21,6 +21,7 @@ type Storage[T dynamo.Thing] struct {
 	bucket    *string
 	codec     *codec[T]
 	schema    *schema[T]
+	version   *version[T]
 	undefined T
 }
 
@@ -50,11 +51,17 @@ func New[T dynamo.Thing](opts ...Option) (*Storage[T], error) {
 		return nil, errUndefinedBucket.New(nil)
 	}
 
+	version, err := newVersion[T](conf.version)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Storage[T]{
 		service: aws,
 		bucket:  &bucket,
 		codec:   newCodec[T](conf.prefixes),
 		schema:  newSchema[T](),
+		version: version,
 	}, nil
 }
 
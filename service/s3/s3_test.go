@@ -13,8 +13,10 @@ import (
 	"testing"
 
 	"github.com/fogfish/curie"
+	"github.com/fogfish/dynamo/v3"
 	"github.com/fogfish/dynamo/v3/internal/dynamotest"
 	"github.com/fogfish/dynamo/v3/internal/s3test"
+	"github.com/fogfish/dynamo/v3/service/ddb"
 	"github.com/fogfish/dynamo/v3/service/s3"
 	"github.com/fogfish/it"
 )
@@ -94,3 +96,209 @@ func TestSeqS3Update(t *testing.T) {
 		If(err).Should().Equal(nil).
 		If(val).Should().Equal(valS)
 }
+
+func TestS3MatchDescending(t *testing.T) {
+	seq := []dynamotest.Person{}
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		seq = append(seq, dynamotest.Person{Prefix: "dead:beef", Suffix: curie.IRI(id)})
+	}
+	api := s3test.ListObjects(seq)
+	key := dynamotest.Person{Prefix: "dead:beef"}
+
+	page0, cursor0, err := api.Match(context.Background(), key,
+		dynamo.Descending[dynamotest.Person](),
+		dynamo.Limit[dynamotest.Person](3),
+	)
+	it.Ok(t).
+		If(err).Should().Equal(nil).
+		If(len(page0)).Should().Equal(3).
+		If(page0[0].Suffix).Should().Equal(curie.IRI("5")).
+		If(page0[2].Suffix).Should().Equal(curie.IRI("3")).
+		IfNotNil(cursor0)
+
+	page1, cursor1, err := api.Match(context.Background(), key,
+		dynamo.Descending[dynamotest.Person](),
+		dynamo.Limit[dynamotest.Person](3),
+		cursor0,
+	)
+	it.Ok(t).
+		If(err).Should().Equal(nil).
+		If(len(page1)).Should().Equal(2).
+		If(page1[0].Suffix).Should().Equal(curie.IRI("2")).
+		If(page1[1].Suffix).Should().Equal(curie.IRI("1")).
+		IfNil(cursor1)
+}
+
+func TestS3MatchSortKeyCondition(t *testing.T) {
+	seq := []dynamotest.Person{}
+	for _, id := range []string{"a1", "a2", "b1", "b2", "c1"} {
+		seq = append(seq, dynamotest.Person{Prefix: "dead:beef", Suffix: curie.IRI(id)})
+	}
+	api := s3test.ListObjects(seq)
+	key := dynamotest.Person{Prefix: "dead:beef"}
+
+	for _, opt := range []interface{ MatcherOpt(dynamotest.Person) }{
+		dynamo.SortKeyBetween[dynamotest.Person]("a2", "b1"),
+		dynamo.SortKeyLt[dynamotest.Person]("b1"),
+		dynamo.SortKeyGe[dynamotest.Person]("b1"),
+	} {
+		ascending, _, errA := api.Match(context.Background(), key, opt)
+		descending, _, errD := api.Match(context.Background(), key, opt, dynamo.Descending[dynamotest.Person]())
+		it.Ok(t).
+			IfNotNil(errA).
+			If(len(ascending)).Should().Equal(0).
+			IfNotNil(errD).
+			If(len(descending)).Should().Equal(0)
+	}
+}
+
+type document struct {
+	ID      string `json:"id,omitempty" dynamodbav:"id,omitempty"`
+	Text    string `json:"text,omitempty" dynamodbav:"text,omitempty"`
+	Version int    `json:"version,omitempty" dynamodbav:"version,omitempty"`
+}
+
+func (d document) HashKey() curie.IRI { return curie.IRI(d.ID) }
+func (d document) SortKey() curie.IRI { return "" }
+
+func TestS3Version(t *testing.T) {
+	api, bucket := s3test.Objects[document](s3.WithVersion("Version"))
+
+	t.Run("Create", func(t *testing.T) {
+		err := api.Put(context.Background(), document{ID: "doc", Text: "a"})
+		val, _ := api.Get(context.Background(), document{ID: "doc"})
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val.Version).Should().Equal(1)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		val, err := api.Update(context.Background(), document{ID: "doc", Text: "b", Version: 1})
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(document{ID: "doc", Text: "b", Version: 2})
+	})
+
+	t.Run("Put", func(t *testing.T) {
+		err := api.Put(context.Background(), document{ID: "doc", Text: "c", Version: 2})
+		val, _ := api.Get(context.Background(), document{ID: "doc"})
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(document{ID: "doc", Text: "c", Version: 3})
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		_, err := api.Update(context.Background(), document{ID: "doc", Text: "d", Version: 1})
+		for _, err := range []error{
+			err,
+			api.Put(context.Background(), document{ID: "doc", Text: "d"}),
+			api.Put(context.Background(), document{ID: "doc", Text: "d", Version: 2}),
+			api.Put(context.Background(), document{ID: "new", Text: "d", Version: 2}),
+		} {
+			e, ok := err.(interface{ Conflict() bool })
+			it.Ok(t).
+				IfTrue(ok).
+				IfTrue(e.Conflict())
+		}
+
+		val, _ := api.Get(context.Background(), document{ID: "doc"})
+		it.Ok(t).If(val.Version).Should().Equal(3)
+	})
+
+	t.Run("ETag", func(t *testing.T) {
+		bucket.Race("doc", 1)
+		err := api.Put(context.Background(), document{ID: "doc", Text: "e", Version: 3})
+		it.Ok(t).IfNil(err)
+
+		bucket.Race("doc", 100)
+		_, err = api.Update(context.Background(), document{ID: "doc", Text: "f", Version: 4})
+		e, ok := err.(interface{ Conflict() bool })
+		it.Ok(t).
+			IfTrue(ok).
+			IfTrue(e.Conflict())
+	})
+}
+
+func TestS3WithConstrain(t *testing.T) {
+	text := ddb.ClauseFor[document, string]("Text")
+	api, bucket := s3test.Objects[document]()
+
+	t.Run("Create", func(t *testing.T) {
+		success := api.Put(context.Background(), document{ID: "doc", Text: "a"}, text.NotExists())
+		failure := api.Put(context.Background(), document{ID: "doc", Text: "b"}, text.NotExists())
+		e, ok := failure.(interface{ PreConditionFailed() bool })
+		it.Ok(t).
+			If(success).Should().Equal(nil).
+			IfTrue(ok).
+			IfTrue(e.PreConditionFailed())
+	})
+
+	t.Run("Put", func(t *testing.T) {
+		success := api.Put(context.Background(), document{ID: "doc", Text: "b"}, text.Eq("a"))
+		failure := api.Put(context.Background(), document{ID: "doc", Text: "c"}, text.Eq("a"))
+		_, ok := failure.(interface{ PreConditionFailed() bool })
+		it.Ok(t).
+			If(success).Should().Equal(nil).
+			IfTrue(ok)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		bucket.Race("doc", 2)
+		val, success := api.Update(context.Background(), document{ID: "doc", Version: 1}, text.In("a", "b"))
+		_, failure := api.Update(context.Background(), document{ID: "doc", Version: 2}, text.HasPrefix("a"))
+		e, ok := failure.(interface{ Gone() bool })
+		it.Ok(t).
+			If(success).Should().Equal(nil).
+			If(val).Should().Equal(document{ID: "doc", Text: "b", Version: 1}).
+			IfTrue(ok).
+			IfFalse(e.Gone())
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		_, failure := api.Remove(context.Background(), document{ID: "doc"}, text.Ne("b"))
+		val, success := api.Remove(context.Background(), document{ID: "doc"}, text.Exists())
+		e, ok := failure.(interface{ Gone() bool })
+		it.Ok(t).
+			IfTrue(ok).
+			IfTrue(e.Gone()).
+			If(success).Should().Equal(nil).
+			If(val.Text).Should().Equal("b")
+	})
+
+	t.Run("InvalidExpression", func(t *testing.T) {
+		failure := api.Put(context.Background(), document{ID: "doc"}, text.In())
+		e, ok := failure.(interface{ InvalidExpression() bool })
+		it.Ok(t).
+			IfTrue(ok).
+			IfTrue(e.InvalidExpression())
+	})
+}
+
+type counter struct {
+	ID    string `json:"id,omitempty" dynamodbav:"id,omitempty"`
+	Count int    `json:"count" dynamodbav:"count"`
+}
+
+func (c counter) HashKey() curie.IRI { return curie.IRI(c.ID) }
+func (c counter) SortKey() curie.IRI { return "" }
+
+func TestS3WithConstrainZeroValue(t *testing.T) {
+	count := ddb.ClauseFor[counter, int]("Count")
+	api, _ := s3test.Objects[counter]()
+
+	// zero value is encoded, the attribute exists as it does at DynamoDB
+	it.Ok(t).
+		If(api.Put(context.Background(), counter{ID: "c"}, count.NotExists())).Should().Equal(nil).
+		If(api.Put(context.Background(), counter{ID: "c", Count: 1}, count.Exists())).Should().Equal(nil)
+}
+
+func TestS3PartialUpdateEmpty(t *testing.T) {
+	api, _ := s3test.Objects[counter]()
+
+	_, failure := api.Update(context.Background(), counter{ID: "c"}, dynamo.PartialUpdate[counter]())
+	val, success := api.Update(context.Background(), counter{ID: "c", Count: 1}, dynamo.PartialUpdate[counter]())
+	it.Ok(t).
+		IfNotNil(failure).
+		If(success).Should().Equal(nil).
+		If(val.Count).Should().Equal(1)
+}
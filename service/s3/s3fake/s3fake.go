@@ -0,0 +1,369 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+// Package s3fake implements in-process AWS S3 service. Buckets are kept
+// in memory and ordered lexicographically by object key, the service assigns
+// ETag to objects and evaluates conditional headers (If-Match, If-None-Match).
+// It is designed for testing of applications that uses s3.Storage.
+//
+//	db := s3.Must(
+//		s3.New[Person](
+//			s3.WithBucket("test"),
+//			s3.WithService(s3fake.New("test")),
+//		),
+//	)
+package s3fake
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// S3 is in-process implementation of AWS S3 service
+type S3 struct {
+	sync.Mutex
+	buckets map[string]*bucket
+	clock   func() time.Time
+}
+
+// New creates in-process AWS S3 service with given buckets
+func New(buckets ...string) *S3 {
+	service := &S3{
+		buckets: map[string]*bucket{},
+		clock:   time.Now,
+	}
+
+	for _, name := range buckets {
+		service.buckets[name] = &bucket{keys: []string{}, objects: map[string]*object{}}
+	}
+
+	return service
+}
+
+// bucket keeps objects ordered by key
+type bucket struct {
+	keys    []string
+	objects map[string]*object
+}
+
+type object struct {
+	body         []byte
+	etag         string
+	contentType  *string
+	metadata     map[string]string
+	lastModified time.Time
+}
+
+func (b *bucket) put(key string, obj *object) {
+	if _, has := b.objects[key]; !has {
+		i := sort.SearchStrings(b.keys, key)
+		b.keys = append(b.keys, "")
+		copy(b.keys[i+1:], b.keys[i:])
+		b.keys[i] = key
+	}
+	b.objects[key] = obj
+}
+
+func (b *bucket) remove(key string) {
+	if _, has := b.objects[key]; !has {
+		return
+	}
+
+	i := sort.SearchStrings(b.keys, key)
+	b.keys = append(b.keys[:i], b.keys[i+1:]...)
+	delete(b.objects, key)
+}
+
+func (service *S3) bucket(name *string) (*bucket, error) {
+	b, has := service.buckets[aws.ToString(name)]
+	if !has {
+		return nil, errHTTP(http.StatusNotFound, &types.NoSuchBucket{
+			Message: aws.String("The specified bucket does not exist"),
+		})
+	}
+
+	return b, nil
+}
+
+// GetObject implements s3.GetObject
+func (service *S3) GetObject(ctx context.Context, req *s3.GetObjectInput, opts ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	service.Lock()
+	defer service.Unlock()
+
+	b, err := service.bucket(req.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, has := b.objects[aws.ToString(req.Key)]
+	if !has {
+		return nil, errHTTP(http.StatusNotFound, &types.NoSuchKey{
+			Message: aws.String("The specified key does not exist."),
+		})
+	}
+
+	header := headersOf(opts)
+	ifMatch := header.Get("If-Match")
+	if req.IfMatch != nil {
+		ifMatch = *req.IfMatch
+	}
+	ifNoneMatch := header.Get("If-None-Match")
+	if req.IfNoneMatch != nil {
+		ifNoneMatch = *req.IfNoneMatch
+	}
+
+	if ifMatch != "" && !isMatch(ifMatch, obj) {
+		return nil, errPreconditionFailed()
+	}
+
+	if ifNoneMatch != "" && isMatch(ifNoneMatch, obj) {
+		return nil, errHTTP(http.StatusNotModified, &smithy.GenericAPIError{Code: "NotModified", Message: "Not Modified"})
+	}
+
+	return &s3.GetObjectOutput{
+		Body:          io.NopCloser(bytes.NewReader(obj.body)),
+		ContentLength: aws.Int64(int64(len(obj.body))),
+		ContentType:   obj.contentType,
+		ETag:          aws.String(obj.etag),
+		LastModified:  aws.Time(obj.lastModified),
+		Metadata:      obj.metadata,
+	}, nil
+}
+
+// PutObject implements s3.PutObject, conditional writes are defined by
+// If-Match and If-None-Match headers
+func (service *S3) PutObject(ctx context.Context, req *s3.PutObjectInput, opts ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	service.Lock()
+	defer service.Unlock()
+
+	b, err := service.bucket(req.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	key := aws.ToString(req.Key)
+	if key == "" {
+		return nil, errHTTP(http.StatusBadRequest, &smithy.GenericAPIError{Code: "InvalidArgument", Message: "object key cannot be empty"})
+	}
+
+	obj, has := b.objects[key]
+	header := headersOf(opts)
+
+	if h := header.Get("If-None-Match"); h != "" && has && isMatch(h, obj) {
+		return nil, errPreconditionFailed()
+	}
+
+	if h := header.Get("If-Match"); h != "" && (!has || !isMatch(h, obj)) {
+		if !has {
+			return nil, errHTTP(http.StatusNotFound, &types.NoSuchKey{
+				Message: aws.String("The specified key does not exist."),
+			})
+		}
+		return nil, errPreconditionFailed()
+	}
+
+	var body []byte
+	if req.Body != nil {
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hash := md5.Sum(body)
+	val := &object{
+		body:         body,
+		etag:         "\"" + hex.EncodeToString(hash[:]) + "\"",
+		contentType:  req.ContentType,
+		metadata:     req.Metadata,
+		lastModified: service.clock().UTC(),
+	}
+	b.put(key, val)
+
+	return &s3.PutObjectOutput{ETag: aws.String(val.etag)}, nil
+}
+
+// DeleteObject implements s3.DeleteObject, the deletion is conditional if
+// If-Match header is defined
+func (service *S3) DeleteObject(ctx context.Context, req *s3.DeleteObjectInput, opts ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	service.Lock()
+	defer service.Unlock()
+
+	b, err := service.bucket(req.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	key := aws.ToString(req.Key)
+	obj, has := b.objects[key]
+
+	if h := headersOf(opts).Get("If-Match"); h != "" {
+		if !has {
+			return nil, errHTTP(http.StatusNotFound, &types.NoSuchKey{
+				Message: aws.String("The specified key does not exist."),
+			})
+		}
+		if !isMatch(h, obj) {
+			return nil, errPreconditionFailed()
+		}
+	}
+
+	b.remove(key)
+
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+// ListObjectsV2 implements s3.ListObjectsV2. Keys are listed in
+// lexicographical order, the continuation token is opaque.
+func (service *S3) ListObjectsV2(ctx context.Context, req *s3.ListObjectsV2Input, opts ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	service.Lock()
+	defer service.Unlock()
+
+	b, err := service.bucket(req.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	maxKeys := int32(1000)
+	if req.MaxKeys != nil && *req.MaxKeys >= 0 && *req.MaxKeys < maxKeys {
+		maxKeys = *req.MaxKeys
+	}
+
+	prefix := aws.ToString(req.Prefix)
+	delimiter := aws.ToString(req.Delimiter)
+
+	after := aws.ToString(req.StartAfter)
+	if req.ContinuationToken != nil {
+		token, err := base64.RawURLEncoding.DecodeString(*req.ContinuationToken)
+		if err != nil {
+			return nil, errHTTP(http.StatusBadRequest, &smithy.GenericAPIError{Code: "InvalidArgument", Message: "The continuation token provided is incorrect"})
+		}
+		after = string(token)
+	}
+
+	out := &s3.ListObjectsV2Output{
+		Name:              req.Bucket,
+		Prefix:            req.Prefix,
+		Delimiter:         req.Delimiter,
+		StartAfter:        req.StartAfter,
+		ContinuationToken: req.ContinuationToken,
+		MaxKeys:           aws.Int32(maxKeys),
+		IsTruncated:       aws.Bool(false),
+	}
+
+	count := int32(0)
+	last := ""
+	i := sort.Search(len(b.keys), func(i int) bool { return b.keys[i] > after && b.keys[i] >= prefix })
+
+	for ; i < len(b.keys) && strings.HasPrefix(b.keys[i], prefix); i++ {
+		key := b.keys[i]
+
+		if delimiter != "" {
+			if at := strings.Index(key[len(prefix):], delimiter); at != -1 {
+				common := key[:len(prefix)+at+len(delimiter)]
+				if common <= after {
+					continue
+				}
+				if count == maxKeys {
+					out.IsTruncated = aws.Bool(true)
+					break
+				}
+				out.CommonPrefixes = append(out.CommonPrefixes, types.CommonPrefix{Prefix: aws.String(common)})
+				count++
+				last = common
+				// skip all keys of the common prefix
+				for i+1 < len(b.keys) && strings.HasPrefix(b.keys[i+1], common) {
+					i++
+				}
+				continue
+			}
+		}
+
+		if count == maxKeys {
+			out.IsTruncated = aws.Bool(true)
+			break
+		}
+
+		obj := b.objects[key]
+		out.Contents = append(out.Contents, types.Object{
+			Key:          aws.String(key),
+			ETag:         aws.String(obj.etag),
+			Size:         aws.Int64(int64(len(obj.body))),
+			LastModified: aws.Time(obj.lastModified),
+			StorageClass: types.ObjectStorageClassStandard,
+		})
+		count++
+		last = key
+	}
+
+	out.KeyCount = aws.Int32(count)
+	if aws.ToBool(out.IsTruncated) {
+		out.NextContinuationToken = aws.String(base64.RawURLEncoding.EncodeToString([]byte(last)))
+	}
+
+	return out, nil
+}
+
+// isMatch evaluates ETag condition, it is either list of ETags or "*"
+func isMatch(cond string, obj *object) bool {
+	for _, etag := range strings.Split(cond, ",") {
+		etag = strings.TrimSpace(etag)
+		if etag == "*" || strings.Trim(etag, "\"") == strings.Trim(obj.etag, "\"") {
+			return true
+		}
+	}
+	return false
+}
+
+// headersOf evaluates request options and returns HTTP headers defined by them
+func headersOf(opts []func(*s3.Options)) http.Header {
+	var conf s3.Options
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	header := http.Header{}
+	if len(conf.APIOptions) == 0 {
+		return header
+	}
+
+	stack := middleware.NewStack("s3fake", smithyhttp.NewStackRequest)
+	for _, fn := range conf.APIOptions {
+		if err := fn(stack); err != nil {
+			return header
+		}
+	}
+
+	handler := middleware.HandlerFunc(
+		func(ctx context.Context, input interface{}) (interface{}, middleware.Metadata, error) {
+			if req, ok := input.(*smithyhttp.Request); ok {
+				header = req.Header
+			}
+			return nil, middleware.Metadata{}, nil
+		},
+	)
+
+	middleware.DecorateHandler(handler, stack).Handle(context.Background(), nil)
+	return header
+}
@@ -0,0 +1,300 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package s3fake_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/fogfish/curie"
+	"github.com/fogfish/dynamo/v3"
+	s3api "github.com/fogfish/dynamo/v3/service/s3"
+	"github.com/fogfish/dynamo/v3/service/s3/s3fake"
+	"github.com/fogfish/it"
+)
+
+func put(service *s3fake.S3, keys ...string) {
+	for _, key := range keys {
+		service.PutObject(context.Background(), &s3.PutObjectInput{
+			Bucket: aws.String("test"),
+			Key:    aws.String(key),
+			Body:   strings.NewReader(key),
+		})
+	}
+}
+
+func keysOf(val *s3.ListObjectsV2Output) []string {
+	seq := make([]string, 0, len(val.Contents))
+	for _, obj := range val.Contents {
+		seq = append(seq, aws.ToString(obj.Key))
+	}
+	return seq
+}
+
+func header(h, v string) func(*s3.Options) {
+	return func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, smithyhttp.SetHeaderValue(h, v))
+	}
+}
+
+func errorCode(err error) string {
+	var e interface{ ErrorCode() string }
+	if errors.As(err, &e) {
+		return e.ErrorCode()
+	}
+	return ""
+}
+
+func TestListObjectsV2(t *testing.T) {
+	service := s3fake.New("test")
+	put(service, "c/1", "a/2", "b/1", "a/1", "a/3", "b/2")
+
+	t.Run("Ordered", func(t *testing.T) {
+		val, err := service.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
+			Bucket: aws.String("test"),
+		})
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(keysOf(val)).Should().Equal([]string{"a/1", "a/2", "a/3", "b/1", "b/2", "c/1"}).
+			If(aws.ToBool(val.IsTruncated)).Should().Equal(false)
+	})
+
+	t.Run("Prefix", func(t *testing.T) {
+		val, err := service.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
+			Bucket: aws.String("test"),
+			Prefix: aws.String("b/"),
+		})
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(keysOf(val)).Should().Equal([]string{"b/1", "b/2"})
+	})
+
+	t.Run("StartAfter", func(t *testing.T) {
+		val, err := service.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
+			Bucket:     aws.String("test"),
+			Prefix:     aws.String("a/"),
+			StartAfter: aws.String("a/1"),
+		})
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(keysOf(val)).Should().Equal([]string{"a/2", "a/3"})
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		req := &s3.ListObjectsV2Input{
+			Bucket:  aws.String("test"),
+			MaxKeys: aws.Int32(4),
+		}
+		val, err := service.ListObjectsV2(context.Background(), req)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(keysOf(val)).Should().Equal([]string{"a/1", "a/2", "a/3", "b/1"}).
+			If(aws.ToInt32(val.KeyCount)).Should().Equal(int32(4)).
+			If(aws.ToBool(val.IsTruncated)).Should().Equal(true)
+
+		req.ContinuationToken = val.NextContinuationToken
+		val, err = service.ListObjectsV2(context.Background(), req)
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(keysOf(val)).Should().Equal([]string{"b/2", "c/1"}).
+			If(aws.ToBool(val.IsTruncated)).Should().Equal(false).
+			If(val.NextContinuationToken).Should().Equal((*string)(nil))
+	})
+
+	t.Run("Delimiter", func(t *testing.T) {
+		val, err := service.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
+			Bucket:    aws.String("test"),
+			Delimiter: aws.String("/"),
+		})
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(len(val.Contents)).Should().Equal(0).
+			If(val.CommonPrefixes).Should().Equal([]types.CommonPrefix{
+			{Prefix: aws.String("a/")},
+			{Prefix: aws.String("b/")},
+			{Prefix: aws.String("c/")},
+		})
+	})
+
+	t.Run("NoSuchBucket", func(t *testing.T) {
+		_, err := service.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
+			Bucket: aws.String("unknown"),
+		})
+		it.Ok(t).If(errorCode(err)).Should().Equal("NoSuchBucket")
+	})
+}
+
+func TestPreconditions(t *testing.T) {
+	service := s3fake.New("test")
+	put(service, "a")
+
+	val, err := service.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String("test"),
+		Key:    aws.String("a"),
+	})
+	it.Ok(t).If(err).Should().Equal(nil)
+
+	etag := aws.ToString(val.ETag)
+	body, _ := io.ReadAll(val.Body)
+	it.Ok(t).If(string(body)).Should().Equal("a")
+
+	t.Run("GetIfMatch", func(t *testing.T) {
+		_, err := service.GetObject(context.Background(),
+			&s3.GetObjectInput{Bucket: aws.String("test"), Key: aws.String("a")},
+			header("If-Match", etag),
+		)
+		it.Ok(t).If(err).Should().Equal(nil)
+
+		_, err = service.GetObject(context.Background(),
+			&s3.GetObjectInput{Bucket: aws.String("test"), Key: aws.String("a"), IfMatch: aws.String("\"abc\"")},
+		)
+		it.Ok(t).If(errorCode(err)).Should().Equal("PreconditionFailed")
+	})
+
+	t.Run("GetIfNoneMatch", func(t *testing.T) {
+		_, err := service.GetObject(context.Background(),
+			&s3.GetObjectInput{Bucket: aws.String("test"), Key: aws.String("a"), IfNoneMatch: aws.String(etag)},
+		)
+		it.Ok(t).If(errorCode(err)).Should().Equal("NotModified")
+	})
+
+	t.Run("PutIfNoneMatch", func(t *testing.T) {
+		_, err := service.PutObject(context.Background(),
+			&s3.PutObjectInput{Bucket: aws.String("test"), Key: aws.String("a"), Body: strings.NewReader("b")},
+			header("If-None-Match", "*"),
+		)
+		it.Ok(t).If(errorCode(err)).Should().Equal("PreconditionFailed")
+
+		_, err = service.PutObject(context.Background(),
+			&s3.PutObjectInput{Bucket: aws.String("test"), Key: aws.String("b"), Body: strings.NewReader("b")},
+			header("If-None-Match", "*"),
+		)
+		it.Ok(t).If(err).Should().Equal(nil)
+	})
+
+	t.Run("PutIfMatch", func(t *testing.T) {
+		_, err := service.PutObject(context.Background(),
+			&s3.PutObjectInput{Bucket: aws.String("test"), Key: aws.String("a"), Body: strings.NewReader("c")},
+			header("If-Match", etag),
+		)
+		it.Ok(t).If(err).Should().Equal(nil)
+
+		_, err = service.PutObject(context.Background(),
+			&s3.PutObjectInput{Bucket: aws.String("test"), Key: aws.String("a"), Body: strings.NewReader("d")},
+			header("If-Match", etag),
+		)
+		it.Ok(t).If(errorCode(err)).Should().Equal("PreconditionFailed")
+	})
+
+	t.Run("DeleteIfMatch", func(t *testing.T) {
+		_, err := service.DeleteObject(context.Background(),
+			&s3.DeleteObjectInput{Bucket: aws.String("test"), Key: aws.String("a")},
+			header("If-Match", etag),
+		)
+		it.Ok(t).If(errorCode(err)).Should().Equal("PreconditionFailed")
+
+		_, err = service.DeleteObject(context.Background(),
+			&s3.DeleteObjectInput{Bucket: aws.String("test"), Key: aws.String("a")},
+		)
+		it.Ok(t).If(err).Should().Equal(nil)
+
+		_, err = service.GetObject(context.Background(),
+			&s3.GetObjectInput{Bucket: aws.String("test"), Key: aws.String("a")},
+		)
+		it.Ok(t).If(errorCode(err)).Should().Equal("NoSuchKey")
+	})
+}
+
+//-----------------------------------------------------------------------------
+//
+// s3.Storage over fake
+//
+//-----------------------------------------------------------------------------
+
+type note struct {
+	Topic curie.IRI `json:"topic,omitempty"`
+	ID    curie.IRI `json:"id,omitempty"`
+	Text  string    `json:"text,omitempty"`
+}
+
+func (n note) HashKey() curie.IRI { return n.Topic }
+func (n note) SortKey() curie.IRI { return n.ID }
+
+func TestStorage(t *testing.T) {
+	db := s3api.Must(
+		s3api.New[note](
+			s3api.WithBucket("test"),
+			s3api.WithService(s3fake.New("test")),
+		),
+	)
+
+	for _, id := range []curie.IRI{"3", "1", "2", "4", "5"} {
+		err := db.Put(context.Background(), note{Topic: "a", ID: id, Text: "x"})
+		it.Ok(t).If(err).Should().Equal(nil)
+	}
+
+	t.Run("Get", func(t *testing.T) {
+		val, err := db.Get(context.Background(), note{Topic: "a", ID: "1"})
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val).Should().Equal(note{Topic: "a", ID: "1", Text: "x"})
+
+		_, err = db.Get(context.Background(), note{Topic: "a", ID: "9"})
+		_, ok := err.(interface{ NotFound() string })
+		it.Ok(t).IfTrue(ok)
+	})
+
+	t.Run("Match", func(t *testing.T) {
+		seq := []curie.IRI{}
+		var cursor interface{ MatcherOpt(note) }
+		for {
+			opts := []interface{ MatcherOpt(note) }{dynamo.Limit[note](2)}
+			if cursor != nil {
+				opts = append(opts, cursor)
+			}
+
+			val, next, err := db.Match(context.Background(), note{Topic: "a"}, opts...)
+			it.Ok(t).If(err).Should().Equal(nil)
+
+			for _, x := range val {
+				seq = append(seq, x.ID)
+			}
+
+			if next == nil {
+				break
+			}
+			cursor = next
+		}
+
+		it.Ok(t).If(seq).Should().Equal([]curie.IRI{"1", "2", "3", "4", "5"})
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		val, err := db.Update(context.Background(), note{Topic: "a", ID: "1", Text: "y"})
+		it.Ok(t).
+			If(err).Should().Equal(nil).
+			If(val.Text).Should().Equal("y")
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		_, err := db.Remove(context.Background(), note{Topic: "a", ID: "1"})
+		it.Ok(t).If(err).Should().Equal(nil)
+
+		_, err = db.Get(context.Background(), note{Topic: "a", ID: "1"})
+		_, ok := err.(interface{ NotFound() string })
+		it.Ok(t).IfTrue(ok)
+	})
+}
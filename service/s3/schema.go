@@ -12,6 +12,7 @@ import (
 	"reflect"
 
 	"github.com/fogfish/dynamo/v3"
+	"github.com/fogfish/dynamo/v3/internal/partial"
 	"github.com/fogfish/golem/hseq"
 )
 
@@ -24,7 +25,23 @@ func newSchema[T dynamo.Thing]() *schema[T] {
 	return &schema[T]{hseq.New[T]()}
 }
 
+// Merge takes non-zero fields of a, other fields are taken from b
 func (schema schema[T]) Merge(a, b T) (c T) {
+	return schema.merge(a, b, func(v reflect.Value) partial.Action {
+		if v.IsZero() {
+			return partial.Skip
+		}
+		return partial.Set
+	})
+}
+
+// Patch takes set fields of a, nil and zero fields are taken from b,
+// Null sentinel and empty collections remove the field.
+func (schema schema[T]) Patch(a, b T) (c T) {
+	return schema.merge(a, b, partial.ActionOf)
+}
+
+func (schema schema[T]) merge(a, b T, actionOf func(reflect.Value) partial.Action) (c T) {
 	va := reflect.ValueOf(a)
 	if va.Kind() == reflect.Pointer {
 		va = va.Elem()
@@ -52,10 +69,10 @@ func (schema schema[T]) Merge(a, b T) (c T) {
 		fb := vb.FieldByName(f.Name)
 		fc := vc.FieldByName(f.Name)
 
-		switch {
-		case !fa.IsZero():
+		switch actionOf(fa) {
+		case partial.Set:
 			fc.Set(fa)
-		case !fb.IsZero():
+		case partial.Skip:
 			fc.Set(fb)
 		}
 	}
@@ -12,6 +12,7 @@ import (
 	"testing"
 
 	"github.com/fogfish/curie"
+	"github.com/fogfish/dynamo/v3"
 	"github.com/fogfish/dynamo/v3/internal/dynamotest"
 	"github.com/fogfish/it"
 )
@@ -48,3 +49,47 @@ func TestMerge(t *testing.T) {
 			If(schema.Merge(&a, &b)).Should().Equal(&c)
 	})
 }
+
+type account struct {
+	Prefix curie.IRI `dynamodbav:"prefix,omitempty"`
+	Suffix curie.IRI `dynamodbav:"suffix,omitempty"`
+	Name   *string   `dynamodbav:"name,omitempty"`
+	Age    *int      `dynamodbav:"age,omitempty"`
+	Email  *string   `dynamodbav:"email,omitempty"`
+	Tags   []string  `dynamodbav:"tags,omitempty"`
+}
+
+func (p account) HashKey() curie.IRI { return p.Prefix }
+func (p account) SortKey() curie.IRI { return p.Suffix }
+
+func ptr[A any](v A) *A { return &v }
+
+func TestPatch(t *testing.T) {
+	a := account{
+		Prefix: curie.New("dead:beef"),
+		Suffix: curie.New("1"),
+		Name:   ptr("Eduard"),
+		Age:    ptr(0),
+		Email:  dynamo.Null[string](),
+		Tags:   []string{},
+	}
+
+	b := account{
+		Prefix: curie.New("dead:beef"),
+		Suffix: curie.New("1"),
+		Name:   ptr("Verner Pleishner"),
+		Age:    ptr(64),
+		Email:  ptr("verner@example.com"),
+		Tags:   []string{"spy"},
+	}
+
+	schema := newSchema[account]()
+	it.Ok(t).
+		If(schema.Patch(a, b)).Should().Equal(account{
+		Prefix: curie.New("dead:beef"),
+		Suffix: curie.New("1"),
+		Name:   ptr("Eduard"),
+		Age:    ptr(0),
+	}).
+		If(schema.Patch(account{Prefix: a.Prefix, Suffix: a.Suffix}, b)).Should().Equal(b)
+}
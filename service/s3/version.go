@@ -0,0 +1,89 @@
+//
+// Copyright (C) 2022 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/dynamo
+//
+
+package s3
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/fogfish/dynamo/v3"
+	"github.com/fogfish/golem/hseq"
+)
+
+/*
+Version is utility that reads and increments numeric version field of struct
+*/
+type version[T dynamo.Thing] struct{ field string }
+
+func newVersion[T dynamo.Thing](field string) (*version[T], error) {
+	if field == "" {
+		return nil, nil
+	}
+
+	for _, f := range hseq.New[T]() {
+		if f.Name != field {
+			continue
+		}
+
+		switch f.PureType.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return &version[T]{field: field}, nil
+		default:
+			return nil, errInvalidEntity.New(fmt.Errorf("version field %s of %T is not a number", field, *new(T)))
+		}
+	}
+
+	return nil, errInvalidEntity.New(fmt.Errorf("version field %s is not defined by %T", field, *new(T)))
+}
+
+// Get version of entity
+func (v version[T]) Get(entity T) int64 {
+	val := reflect.ValueOf(entity)
+	if val.Kind() == reflect.Pointer {
+		if val.IsNil() {
+			return 0
+		}
+		val = val.Elem()
+	}
+
+	f := val.FieldByName(v.field)
+	if f.CanInt() {
+		return f.Int()
+	}
+	return int64(f.Uint())
+}
+
+// With returns copy of entity with given version
+func (v version[T]) With(entity T, n int64) (c T) {
+	// pointer to c makes reflect.ValueOf settable
+	vc := reflect.ValueOf(&c).Elem()
+	if vc.Kind() == reflect.Pointer {
+		// T is a pointer type, the copy of struct protects the origin
+		ve := reflect.ValueOf(entity)
+		if ve.IsNil() {
+			return entity
+		}
+
+		vc.Set(reflect.New(vc.Type().Elem()))
+		vc = vc.Elem()
+		vc.Set(ve.Elem())
+	} else {
+		vc.Set(reflect.ValueOf(entity))
+	}
+
+	f := vc.FieldByName(v.field)
+	if f.CanInt() {
+		f.SetInt(n)
+	} else {
+		f.SetUint(uint64(n))
+	}
+
+	return
+}
@@ -16,6 +16,7 @@ import (
 	"context"
 
 	"github.com/fogfish/curie"
+	"github.com/fogfish/dynamo/v3/internal/partial"
 )
 
 //-----------------------------------------------------------------------------
@@ -115,3 +116,94 @@ func Cursor[T Thing](c Thing) interface{ MatcherOpt(T) } { return cursor[T]{c} }
 type cursor[T Thing] struct{ Thing }
 
 func (cursor[T]) MatcherOpt(T) {}
+
+// Unwrap returns the position the cursor is built from (e.g. element of sequence)
+func (c cursor[T]) Unwrap() Thing { return c.Thing }
+
+// Attributes of the cursor, storages use them to carry extra attributes
+// of the position (e.g. keys of global secondary index)
+func (c cursor[T]) Attributes() map[string]string {
+	if v, ok := c.Thing.(interface{ Attributes() map[string]string }); ok {
+		return v.Attributes()
+	}
+	return nil
+}
+
+// Descending option for Match, returns elements in descending order of sort key
+func Descending[T Thing]() interface{ MatcherOpt(T) } { return descending[T]{} }
+
+type descending[T Thing] struct{}
+
+func (descending[T]) MatcherOpt(T) {}
+
+func (descending[T]) Descending() bool { return true }
+
+// ConsistentRead option for Get and Match, demands strongly consistent reads
+func ConsistentRead[T Thing]() interface {
+	GetterOpt(T)
+	MatcherOpt(T)
+} {
+	return consistentRead[T]{}
+}
+
+type consistentRead[T Thing] struct{}
+
+func (consistentRead[T]) GetterOpt(T) {}
+
+func (consistentRead[T]) MatcherOpt(T) {}
+
+func (consistentRead[T]) ConsistentRead() bool { return true }
+
+// PartialUpdate option for Update, builds the update from the diff of set
+// fields: nil pointers, slices and maps are left untouched; Null sentinel,
+// empty slices and maps remove the attribute. Pointers to zero values write
+// zero, use Null to remove the attribute.
+func PartialUpdate[T Thing]() interface{ WriterOpt(T) } { return partialUpdate[T]{} }
+
+type partialUpdate[T Thing] struct{}
+
+func (partialUpdate[T]) WriterOpt(T) {}
+
+func (partialUpdate[T]) PartialUpdate() bool { return true }
+
+// Null returns sentinel pointer, the field assigned to it is removed by
+// the PartialUpdate.
+//
+//	db.Update(ctx, Person{ID: "x", Address: dynamo.Null[string]()}, dynamo.PartialUpdate[Person]())
+func Null[A any]() *A { return partial.Null[A]() }
+
+// SortKeyBetween option for Match, matches elements with sort key in the range
+//
+//	a <= SortKey <= b
+func SortKeyBetween[T Thing](a, b curie.IRI) interface{ MatcherOpt(T) } {
+	return sortKeyCondition[T]{op: "BETWEEN", val: []curie.IRI{a, b}}
+}
+
+// SortKeyLt option for Match, matches elements with sort key less than value
+func SortKeyLt[T Thing](val curie.IRI) interface{ MatcherOpt(T) } {
+	return sortKeyCondition[T]{op: "<", val: []curie.IRI{val}}
+}
+
+// SortKeyLe option for Match, matches elements with sort key less or equal to value
+func SortKeyLe[T Thing](val curie.IRI) interface{ MatcherOpt(T) } {
+	return sortKeyCondition[T]{op: "<=", val: []curie.IRI{val}}
+}
+
+// SortKeyGt option for Match, matches elements with sort key greater than value
+func SortKeyGt[T Thing](val curie.IRI) interface{ MatcherOpt(T) } {
+	return sortKeyCondition[T]{op: ">", val: []curie.IRI{val}}
+}
+
+// SortKeyGe option for Match, matches elements with sort key greater or equal to value
+func SortKeyGe[T Thing](val curie.IRI) interface{ MatcherOpt(T) } {
+	return sortKeyCondition[T]{op: ">=", val: []curie.IRI{val}}
+}
+
+type sortKeyCondition[T Thing] struct {
+	op  string
+	val []curie.IRI
+}
+
+func (sortKeyCondition[T]) MatcherOpt(T) {}
+
+func (c sortKeyCondition[T]) SortKeyCondition() (string, []curie.IRI) { return c.op, c.val }